@@ -15,6 +15,12 @@
 package cmd
 
 import (
+	"os"
+	"time"
+
+	"github.com/fanux/sealos/pkg/logger"
+	"github.com/fanux/sealos/pkg/utils"
+
 	"github.com/fanux/sealos/pkg/install"
 	"github.com/spf13/cobra"
 )
@@ -27,23 +33,58 @@ type Flag struct {
 	DNSDomain    string
 	CertPath     string
 	CertEtcdPath string
+	CAValidity   time.Duration
+	CertValidity time.Duration
+	KeyAlgorithm string
 }
 
 var config *Flag
 
+var exampleCertCmd = `
+	# generate certs on this host, used by sealos init and join
+	sealos cert --node-ip 192.168.0.2 --node-name master0 --service-cidr 10.96.0.0/12
+
+	# generate certs with 10 years CAs, 1 year leaf certs and ECDSA P-256 keys
+	sealos cert --node-ip 192.168.0.2 --node-name master0 --ca-validity 87600h --cert-validity 8760h --key-algorithm ecdsa
+
+	# check the certs and kubeconfigs expiration on every master
+	sealos cert check
+
+	# renew the leaf certs by the existing CAs and restart control-plane one master at a time
+	sealos cert renew
+
+	# add or remove the apiserver cert SANs and restart apiserver one master at a time
+	sealos cert add-san lb.sealyun.com 47.0.0.22
+	sealos cert remove-san 47.0.0.22
+
+	# rotate the kubernetes, front-proxy and etcd CAs, run it again for every phase: trust, reissue, drop
+	sealos cert rotate-ca
+`
+
 // certCmd represents the cert command
 var certCmd = &cobra.Command{
-	Use:   "cert",
-	Short: "generate certs",
-	Long:  `you can specify expire time`,
+	Use:     "cert",
+	Short:   "generate certs",
+	Long:    `you can specify expire time`,
+	Example: exampleCertCmd,
 	Run: func(cmd *cobra.Command, args []string) {
-		install.GenerateCert(config.CertPath, config.CertEtcdPath, config.AltNames, config.NodeIP, config.NodeName, config.ServiceCIDR, config.DNSDomain)
+		options, err := install.NewCertOptions(config.CAValidity, config.CertValidity, config.KeyAlgorithm)
+		if err != nil {
+			logger.Error("invalid cert options %s", err)
+			os.Exit(install.ErrorExitOSCase)
+		}
+		install.GenerateCert(config.CertPath, config.CertEtcdPath, config.AltNames, config.NodeIP, config.NodeName, config.ServiceCIDR, config.DNSDomain, options)
 	},
 }
 
 func init() {
 	config = &Flag{}
 	rootCmd.AddCommand(certCmd)
+	certCmd.AddCommand(NewCertCheckCmd())
+	certCmd.AddCommand(NewCertRenewCmd())
+	certCmd.AddCommand(NewCertAddSANCmd())
+	certCmd.AddCommand(NewCertRemoveSANCmd())
+	certCmd.AddCommand(NewCertRotateCACmd())
 
 	certCmd.Flags().StringSliceVar(&config.AltNames, "alt-names", []string{}, "like sealyun.com or 10.103.97.2")
 	certCmd.Flags().StringVar(&config.NodeName, "node-name", "", "like master0")
@@ -52,6 +93,9 @@ func init() {
 	certCmd.Flags().StringVar(&config.DNSDomain, "dns-domain", "cluster.local", "cluster dns domain")
 	certCmd.Flags().StringVar(&config.CertPath, "cert-path", "/etc/kubernetes/pki", "kubernetes cert file path")
 	certCmd.Flags().StringVar(&config.CertEtcdPath, "cert-etcd-path", "/etc/kubernetes/pki/etcd", "kubernetes etcd cert file path")
+	certCmd.Flags().DurationVar(&config.CAValidity, "ca-validity", 0, "validity of the CAs, ex. 87600h, default 100 years")
+	certCmd.Flags().DurationVar(&config.CertValidity, "cert-validity", 0, "validity of the leaf certs, ex. 8760h, default 100 years")
+	certCmd.Flags().StringVar(&config.KeyAlgorithm, "key-algorithm", "rsa", "private key algorithm of the certs, rsa|ecdsa")
 
 	// Here you will define your flags and configuration settings.
 
@@ -63,3 +107,117 @@ func init() {
 	// is called directly, e.g.:
 	// certCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
+
+func NewCertCheckCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "check",
+		Short: "check certs subject, SANs and expiration on every master",
+		Run:   CertCheckCmdFunc,
+	}
+}
+
+func NewCertRenewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "renew",
+		Short: "renew leaf certs by the existing CAs and restart control-plane one master at a time",
+		Run:   CertRenewCmdFunc,
+	}
+	cmd.Flags().DurationVar(&install.CertRenewTimeout, "timeout", install.CertRenewTimeout, "timeout for waiting every static pod restart")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "renew need interactive to confirm")
+	return cmd
+}
+
+func NewCertAddSANCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add-san [SAN...]",
+		Short: "add SANs to the apiserver cert and restart apiserver one master at a time",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			c := install.GetCertFlags(cfgFile)
+			if err := c.AddSANs(cfgFile, args); err != nil {
+				logger.Error(err)
+				os.Exit(install.ErrorExitOSCase)
+			}
+		},
+	}
+	cmd.Flags().DurationVar(&install.CertRenewTimeout, "timeout", install.CertRenewTimeout, "timeout for waiting every apiserver restart")
+	return cmd
+}
+
+func NewCertRemoveSANCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "remove-san [SAN...]",
+		Short: "remove SANs from the apiserver cert and restart apiserver one master at a time",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			c := install.GetCertFlags(cfgFile)
+			if err := c.RemoveSANs(cfgFile, args); err != nil {
+				logger.Error(err)
+				os.Exit(install.ErrorExitOSCase)
+			}
+		},
+	}
+	cmd.Flags().DurationVar(&install.CertRenewTimeout, "timeout", install.CertRenewTimeout, "timeout for waiting every apiserver restart")
+	return cmd
+}
+
+func NewCertRotateCACmd() *cobra.Command {
+	var all bool
+	cmd := &cobra.Command{
+		Use:   "rotate-ca",
+		Short: "rotate the CAs by phases: trust the old and new CAs, reissue the certs by the new CAs, drop the old CAs",
+		Long: `rotate-ca run the next phase of the CA rotation, the progress is saved in ~/.sealos/pki-rotate,
+rerun it to resume from the failed phase or to run the next phase.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			c := install.GetCertFlags(cfgFile)
+			if !force {
+				prompt := "Are you sure to rotate CAs, it will restart control-plane static pods and kubelet on every host  (y/n)?"
+				cancel := "You have canceled to rotate CAs!"
+				result, err := utils.Confirm(prompt, cancel)
+				if err != nil {
+					logger.Fatal(err)
+				}
+				if !result {
+					logger.Info("rotate CAs is skip, Exit")
+					os.Exit(-1)
+				}
+			}
+			if err := c.RotateCA(all); err != nil {
+				logger.Error(err)
+				os.Exit(install.ErrorExitOSCase)
+			}
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "run all the remaining phases without stopping between them")
+	cmd.Flags().DurationVar(&install.CertRenewTimeout, "timeout", install.CertRenewTimeout, "timeout for waiting every static pod and node restart")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "rotate-ca need interactive to confirm")
+	return cmd
+}
+
+func CertCheckCmdFunc(cmd *cobra.Command, args []string) {
+	c := install.GetCertFlags(cfgFile)
+	if err := install.PrintCertInfos(os.Stdout, c.Check()); err != nil {
+		logger.Error(err)
+		os.Exit(install.ErrorExitOSCase)
+	}
+}
+
+func CertRenewCmdFunc(cmd *cobra.Command, args []string) {
+	c := install.GetCertFlags(cfgFile)
+	if !force {
+		prompt := "Are you sure to renew certs, it will restart control-plane static pods on every master  (y/n)?"
+		cancel := "You have canceled to renew certs!"
+		result, err := utils.Confirm(prompt, cancel)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		if !result {
+			logger.Info("renew certs is skip, Exit")
+			os.Exit(-1)
+		}
+	}
+	if err := c.Renew(); err != nil {
+		logger.Error(err)
+		os.Exit(install.ErrorExitOSCase)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+
+	"github.com/fanux/sealos/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+var exampleCNICmd = `
+	# show the diff of calico v3.19.1 against the live objects without applying it
+	sealos cni upgrade --version v3.19.1 --dry-run
+
+	# upgrade calico to v3.19.1 by the options in sealos config, and wait for calico-node rollout
+	sealos cni upgrade --version v3.19.1
+
+	# upgrade with the patched manifests
+	sealos cni upgrade --version v3.19.1 --cni-manifest /root/calico/ --cni-values /root/calico-values.yaml
+`
+
+type cniUpgradeOptions struct {
+	version string
+	dryRun  bool
+}
+
+func init() {
+	rootCmd.AddCommand(NewCNICommand())
+}
+
+func NewCNICommand() *cobra.Command {
+	o := &cniUpgradeOptions{}
+	cmd := &cobra.Command{
+		Use:     "cni <subcommand>",
+		Short:   "Manage the cni plugin of your kubernetes cluster",
+		Example: exampleCNICmd,
+	}
+	upgradeCmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "render the cni manifests of the new version by the options in sealos config, show the diff, apply it and wait for the rollout",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(install.GetCNIFlags(cfgFile).Upgrade(cfgFile, o.version, o.dryRun))
+		},
+	}
+	upgradeCmd.Flags().StringVar(&o.version, "version", "", "cni version to upgrade to, ex. v3.19.1")
+	upgradeCmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "show the diff against the live objects only")
+	upgradeCmd.Flags().StringVar(&v1.CNIManifest, "cni-manifest", "", "cni manifest file or dir rendered as go template, like --cni-manifest of sealos init")
+	upgradeCmd.Flags().StringVar(&v1.CNIValues, "cni-values", "", "yaml file of the user variables in --cni-manifest")
+	_ = upgradeCmd.MarkFlagRequired("version")
+	cmd.AddCommand(upgradeCmd)
+	return cmd
+}
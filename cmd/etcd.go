@@ -17,7 +17,6 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"time"
 
 	"github.com/fanux/sealos/pkg/logger"
 
@@ -142,26 +141,10 @@ func EtcdRestoreCmdFunc(cmd *cobra.Command, args []string) {
 			os.Exit(-1)
 		}
 	}
-	e.RestoreAll()
-	//logger.Info("Restore Success! Check Your Restore Dir: %s", e.RestoreDir)
-	time.Sleep(time.Second * 10)
-	// stop etcd kube-apiserver
-	tmpdir, _ := e.StopPod()
-	time.Sleep(time.Second * 10)
-	logger.Info("send restore file to etcd master node and start etcd")
-	// send restore file to etcd master node to  start etcd
-	if err := e.AfterRestore(); err != nil {
+	if err := e.RestoreCluster(); err != nil {
 		logger.Error(err)
-		logger.Info("Start RecoveryKuBeCluster")
-		e.RecoveryKuBeCluster(tmpdir)
 		os.Exit(1)
 	}
-	logger.Info("Start kube-apiserver kube-controller-manager kube-scheduler")
-	// start kube-apiserver
-	e.StartPod(tmpdir)
-	logger.Info("Wait 60s to health check for etcd")
-	time.Sleep(time.Second * 60)
-	e.HealthCheck()
 	logger.Info("restore kubernetes yourself glad~")
 }
 
@@ -0,0 +1,142 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/fanux/sealos/pkg/logger"
+
+	"github.com/fanux/sealos/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+var exampleImagesCmd = `
+	# list the images on all nodes, and the required kubernetes images missing on the nodes
+	sealos images list
+
+	# pull images on the master nodes
+	sealos images pull nginx:1.19 busybox:1.32 --label node-role.kubernetes.io/master=
+
+	# load image tarballs on the nodes
+	sealos images load /root/app-images.tar --node 192.168.0.5 --node dev-k8s-node1
+
+	# push the images in the tarball to the local registry, the nodes pull them by the registry mirrors
+	sealos images push /root/app-images.tar
+
+	# remove the images not used by any container on all nodes, except the images required by kubernetes and nginx:1.19
+	sealos images prune nginx:1.19
+`
+
+type imagesOptions struct {
+	label string
+	nodes []string
+	// local run the operation on this host, it is used by sealos on the nodes.
+	local bool
+	cri   string
+}
+
+func init() {
+	rootCmd.AddCommand(NewImagesCommand())
+}
+
+func NewImagesCommand() *cobra.Command {
+	o := &imagesOptions{}
+	cmd := &cobra.Command{
+		Use:     "images <subcommand>",
+		Short:   "Manage the images of the container runtime on your kubernetes nodes",
+		Example: exampleImagesCmd,
+	}
+	cmd.PersistentFlags().StringVar(&o.label, "label", "", "kubernetes labels like node-role.kubernetes.io/master=")
+	cmd.PersistentFlags().StringSliceVar(&o.nodes, "node", []string{}, "node ip or hostname in kubernetes, default all nodes")
+	cmd.PersistentFlags().BoolVar(&o.local, "local", false, "run on this host only")
+	cmd.PersistentFlags().StringVar(&o.cri, "cri", "", "container runtime of this host with --local, docker|containerd|cri-o")
+	_ = cmd.PersistentFlags().MarkHidden("local")
+	_ = cmd.PersistentFlags().MarkHidden("cri")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "list the images on the nodes",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if o.local {
+				o.runLocal(install.ImagesList, args)
+				return
+			}
+			infos, listErr := install.GetImagesFlags(cfgFile, o.label, o.nodes).List()
+			if err := install.PrintImageInfos(os.Stdout, infos); err != nil {
+				logger.Error(err)
+				os.Exit(install.ErrorExitOSCase)
+			}
+			exitOnError(listErr)
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "pull <image>...",
+		Short: "pull the images on the nodes",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if o.local {
+				o.runLocal(install.ImagesPull, args)
+				return
+			}
+			exitOnError(install.GetImagesFlags(cfgFile, o.label, o.nodes).Pull(args))
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "load <file>...",
+		Short: "load the image tarballs on the nodes",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if o.local {
+				o.runLocal(install.ImagesLoad, args)
+				return
+			}
+			exitOnError(install.GetImagesFlags(cfgFile, o.label, o.nodes).LoadImages(args))
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "push <file>...",
+		Short: "push the images in the tarballs to the local registry",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(install.GetImagesFlags(cfgFile, "", nil).Push(cfgFile, args))
+		},
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "prune [<keep image>...]",
+		Short: "remove the images not used by any container and not required by kubernetes on the nodes",
+		Args:  cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if o.local {
+				o.runLocal(install.ImagesPrune, args)
+				return
+			}
+			exitOnError(install.GetImagesFlags(cfgFile, o.label, o.nodes).Prune(args))
+		},
+	})
+	return cmd
+}
+
+func (o *imagesOptions) runLocal(op string, args []string) {
+	exitOnError(install.LocalImages(os.Stdout, o.cri, op, args))
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		logger.Error(err)
+		os.Exit(install.ErrorExitOSCase)
+	}
+}
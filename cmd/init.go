@@ -17,6 +17,7 @@ package cmd
 import (
 	"os"
 
+	"github.com/fanux/sealos/pkg/lb"
 	"github.com/fanux/sealos/pkg/logger"
 
 	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
@@ -68,6 +69,43 @@ var exampleInit = `
 	--master 192.168.0.2 --master 192.168.0.3 --master 192.168.0.4 \
 	--node 192.168.0.5 --user root --passwd your-server-password \
 	--version v1.18.0 --pkg-url=/root/kube1.18.0.tar.gz 
+
+	# use calico vxlan on the cloud which drops the ipip packets, like tencent cloud
+	sealos init --calico-encapsulation vxlan \
+	--master 192.168.0.2 --node 192.168.0.5 --user root --passwd your-server-password \
+	--version v1.18.0 --pkg-url=/root/kube1.18.0.tar.gz
+
+	# peer calico with the top of rack routers
+	sealos init --bgp --calico-as-number 64512 --calico-bgp-peer 192.168.0.1=64513 \
+	--master 192.168.0.2 --node 192.168.0.5 --user root --passwd your-server-password \
+	--version v1.18.0 --pkg-url=/root/kube1.18.0.tar.gz
+
+	# apply the patched calico manifests, {{ .CIDR }} and the variables of values.yaml like {{ .Values.image }} are rendered
+	sealos init --cni-manifest /root/calico/ --cni-values /root/calico-values.yaml \
+	--master 192.168.0.2 --node 192.168.0.5 --user root --passwd your-server-password \
+	--version v1.18.0 --pkg-url=/root/kube1.18.0.tar.gz
+
+	# serve the apiserver on 7443 when 6443 is reserved on the hosts
+	sealos init --apiserver-port 7443 \
+	--master 192.168.0.2 --node 192.168.0.5 --user root --passwd your-server-password \
+	--version v1.18.0 --pkg-url=/root/kube1.18.0.tar.gz
+
+	# float the vip on the masters by keepalived, haproxy on the masters listens on 8443, no ipvs on the nodes
+	sealos init --lb-mode keepalived --vip 192.168.0.100 \
+	--master 192.168.0.2 --master 192.168.0.3 --master 192.168.0.4 \
+	--node 192.168.0.5 --user root --passwd your-server-password \
+	--version v1.18.0 --pkg-url=/root/kube1.18.0.tar.gz
+
+	# use the F5 or cloud SLB forwarding 6443 to the masters
+	sealos init --lb-mode external --vip 192.168.0.200 \
+	--master 192.168.0.2 --master 192.168.0.3 --master 192.168.0.4 \
+	--node 192.168.0.5 --user root --passwd your-server-password \
+	--version v1.18.0 --pkg-url=/root/kube1.18.0.tar.gz
+
+	# use cilium without kube-proxy, the kernel of the hosts must be >= 5.4
+	sealos init --network cilium \
+	--master 192.168.0.2 --node 192.168.0.5 --user root --passwd your-server-password \
+	--version v1.20.0 --pkg-url=/root/kube1.20.0.tar.gz
 `
 
 // initCmd represents the init command
@@ -118,23 +156,59 @@ func init() {
 	initCmd.Flags().StringVar(&v1.KubeadmFile, "kubeadm-config", "", "kubeadm-config.yaml template file")
 
 	initCmd.Flags().StringVar(&v1.APIServer, "apiserver", v1.DefaultAPIServerDomain, "apiserver domain name")
-	initCmd.Flags().StringVar(&v1.VIP, "vip", "10.103.97.2", "virtual ip")
+	initCmd.Flags().IntVar(&v1.APIServerPort, "apiserver-port", v1.DefaultAPIServerPort, "apiserver port")
+	initCmd.Flags().StringVar(&v1.VIP, "vip", "10.103.97.2", "virtual ip, the ip of the external load balancer in external mode, an unused ip in the subnet of the masters in keepalived and kube-vip mode")
+	initCmd.Flags().StringVar(&v1.LoadBalancer.Mode, "lb-mode", v1.LBModeIPVS, "load balancer of the vip, ipvs|external|keepalived|kube-vip")
+	initCmd.Flags().IntVar(&v1.LoadBalancer.Port, "lb-port", 0, "port of the vip, must be the apiserver port except in keepalived mode, default 8443 in keepalived mode")
+	initCmd.Flags().StringVar(&v1.LoadBalancer.Interface, "lb-interface", "", "NIC of the masters holding the vip in keepalived and kube-vip mode, default the NIC of the master ip")
+	initCmd.Flags().StringVar(&v1.LoadBalancer.KeepalivedImage, "keepalived-image", "", "keepalived image, default "+lb.DefaultKeepalivedImage)
+	initCmd.Flags().StringVar(&v1.LoadBalancer.HAProxyImage, "haproxy-image", "", "haproxy image, default "+lb.DefaultHAProxyImage)
+	initCmd.Flags().StringVar(&v1.LoadBalancer.KubeVIPImage, "kube-vip-image", "", "kube-vip image, default "+lb.DefaultKubeVIPImage)
 	initCmd.Flags().StringSliceVar(&v1.MasterIPs, "master", []string{}, "kubernetes multi-masters ex. 192.168.0.2-192.168.0.4")
 	initCmd.Flags().StringSliceVar(&v1.NodeIPs, "node", []string{}, "kubernetes multi-nodes ex. 192.168.0.5-192.168.0.5")
 	initCmd.Flags().StringSliceVar(&v1.CertSANS, "cert-sans", []string{}, "kubernetes apiServerCertSANs ex. 47.0.0.22 sealyun.com ")
+	initCmd.Flags().DurationVar(&v1.CAValidity, "ca-validity", 0, "validity of the CAs, ex. 87600h, default 100 years")
+	initCmd.Flags().DurationVar(&v1.CertValidity, "cert-validity", 0, "validity of the leaf certs and the kubelet client certs, ex. 8760h, default 100 years")
+	initCmd.Flags().StringVar(&v1.KeyAlgorithm, "key-algorithm", "rsa", "private key algorithm of the certs, rsa|ecdsa")
+	initCmd.Flags().StringVar(&v1.RootCACert, "root-ca-cert", "", "root or intermediate ca cert file to sign the cluster CAs, may contain its issuers")
+	initCmd.Flags().StringVar(&v1.RootCAKey, "root-ca-key", "", "root or intermediate ca key file")
+	initCmd.Flags().StringVar(&v1.CADir, "ca-dir", "", "dir of the pre-created CAs: ca.crt/key, front-proxy-ca.crt/key and etcd/ca.crt/key")
 
 	initCmd.Flags().StringVar(&v1.PkgURL, "pkg-url", "", "http://store.lameleg.com/kube1.14.1.tar.gz download offline package url, or file location ex. /root/kube1.14.1.tar.gz")
 	initCmd.Flags().StringVar(&v1.Version, "version", "", "version is kubernetes version")
 	initCmd.Flags().StringVar(&v1.Repo, "repo", "k8s.gcr.io", "choose a container registry to pull control plane images from")
+	initCmd.Flags().StringSliceVar(&v1.RegistryMirrors, "registry-mirror", []string{}, "mirror of the registry, ex. docker.io=https://mirror.example.com, can be repeated")
+	initCmd.Flags().StringSliceVar(&v1.Registry.Insecure, "insecure-registry", []string{}, "registry to skip tls verify or fall back to plain http, ex. harbor.example.com")
+	initCmd.Flags().StringToStringVar(&v1.Registry.CAFiles, "registry-ca", map[string]string{}, "ca file of the registry, ex. harbor.example.com=/root/harbor-ca.crt")
+	initCmd.Flags().StringSliceVar(&v1.RegistryAuths, "registry-auth", []string{}, "pull credential of the registry, ex. harbor.example.com=user:password")
+	initCmd.Flags().BoolVar(&v1.LocalRegistryEnabled, "local-registry", false, "start a registry seeded from the package images, the other hosts pull images from it instead of loading the whole images, only for containerd")
+	initCmd.Flags().StringVar(&v1.LocalRegistryHost, "local-registry-host", "", "host of the local registry, default master0")
+	initCmd.Flags().StringVar(&v1.LocalRegistryImage, "local-registry-image", "docker.io/library/registry:2.7.1", "image of the local registry, it must be in the package or --local-registry-image-file")
+	initCmd.Flags().StringVar(&v1.LocalRegistryImageFile, "local-registry-image-file", "", "image tarball of the local registry, ex. ctr image export registry.tar docker.io/library/registry:2.7.1")
+	initCmd.Flags().StringVar(&v1.CRI, "cri", "", "container runtime, docker|containerd|cri-o, default docker below 1.20 and containerd from 1.20 on")
 	initCmd.Flags().StringVar(&v1.PodCIDR, "podcidr", "100.64.0.0/10", "Specify range of IP addresses for the pod network")
 	initCmd.Flags().StringVar(&v1.SvcCIDR, "svccidr", "10.96.0.0/12", "Use alternative range of IP address for service VIPs")
 	initCmd.Flags().StringVar(&v1.Interface, "interface", "eth.*|en.*|em.*", "name of network interface, when use calico IP_AUTODETECTION_METHOD, set your ipv4 with can-reach=192.168.0.1")
 
 	initCmd.Flags().BoolVar(&v1.WithoutCNI, "without-cni", false, "If true we not install cni plugin")
-	initCmd.Flags().BoolVar(&v1.BGP, "bgp", false, "bgp mode enable, calico..")
-	initCmd.Flags().StringVar(&v1.MTU, "mtu", "1440", "mtu of the ipip mode , calico..")
+	initCmd.Flags().StringVar(&v1.Network, "network", "calico", "cni plugin, calico|flannel|cilium, cilium replaces kube-proxy")
+	initCmd.Flags().StringVar(&v1.FlannelBackend, "flannel-backend", "vxlan", "backend of the flannel, vxlan|host-gw")
+	initCmd.Flags().StringVar(&v1.CiliumTunnel, "cilium-tunnel", "vxlan", "tunnel of the cilium, vxlan|geneve|disabled")
+	initCmd.Flags().StringVar(&v1.CNIManifest, "cni-manifest", "", "cni manifest file or dir rendered as go template by the options of --network, like {{ .CIDR }} and {{ .Values.key }}")
+	initCmd.Flags().StringVar(&v1.CNIValues, "cni-values", "", "yaml file of the user variables in --cni-manifest, referenced as {{ .Values.key }}")
+	initCmd.Flags().BoolVar(&v1.BGP, "bgp", false, "bgp mode enable, calico.., same as --calico-encapsulation none")
+	initCmd.Flags().StringVar(&v1.CalicoEncap, "calico-encapsulation", "", "encapsulation of the calico, ipip|vxlan|ipip-crosssubnet|vxlan-crosssubnet|none, default ipip, none with --bgp")
+	initCmd.Flags().StringVar(&v1.CalicoASNumber, "calico-as-number", "", "AS number of the calico nodes, default 64512")
+	initCmd.Flags().StringToStringVar(&v1.CalicoBGPPeers, "calico-bgp-peer", map[string]string{}, "bgp peer of the calico like the top of rack router, ex. 192.168.0.1=64513")
+	initCmd.Flags().StringVar(&v1.MTU, "mtu", "", "mtu of the calico veth, derived from the NIC mtu and the encapsulation if empty")
 	initCmd.Flags().StringVar(&v1.LvscareImage.Image, "lvscare-image", "fanux/lvscare", "lvscare image name")
 	initCmd.Flags().StringVar(&v1.LvscareImage.Tag, "lvscare-tag", "latest", "lvscare image tag name")
+	initCmd.Flags().StringVar(&v1.Lvscare.HealthPath, "lvscare-health-path", "", "health check path of the apiserver, default /healthz")
+	initCmd.Flags().StringVar(&v1.Lvscare.HealthSchem, "lvscare-health-schem", "", "health check schem of the apiserver, http|https, default https")
+	initCmd.Flags().Int32Var(&v1.Lvscare.Interval, "lvscare-interval", 0, "health check interval of lvscare in seconds, default 5")
+	initCmd.Flags().StringVar(&v1.Lvscare.PullPolicy, "lvscare-pull-policy", "", "image pull policy of lvscare, Always|IfNotPresent|Never, default IfNotPresent")
+	initCmd.Flags().StringToStringVar(&v1.Lvscare.Requests, "lvscare-requests", nil, "resource requests of lvscare, ex. cpu=50m,memory=32Mi")
+	initCmd.Flags().StringToStringVar(&v1.Lvscare.Limits, "lvscare-limits", nil, "resource limits of lvscare, ex. cpu=200m,memory=128Mi")
 
 	initCmd.Flags().IntVar(&v1.Vlog, "vlog", 0, "kubeadm log level")
 }
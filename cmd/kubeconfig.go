@@ -0,0 +1,106 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/fanux/sealos/pkg/logger"
+
+	"github.com/fanux/sealos/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+var exampleKubeconfigCmd = `
+	# create a kubeconfig for user alice in group dev, signed by the cluster ca and expired in 30 days
+	sealos kubeconfig create --user alice --group dev --ttl 720h --server https://10.103.97.2:6443
+
+	# create a kubeconfig for user alice and bind the view ClusterRole
+	sealos kubeconfig create --user alice --cluster-role view
+
+	# create a kubeconfig with a ServiceAccount token
+	sealos kubeconfig create --user ci --token --namespace default --cluster-role edit
+
+	# merge the admin kubeconfig into ~/.kube/config under the context prod,
+	# the server is master0 in ipvs mode and the vip in the other load balancer modes
+	sealos kubeconfig get --context prod
+
+	# use the eip in the infra status or a custom lb as the server
+	sealos kubeconfig get --context prod --endpoint eip --infra infra.yaml
+	sealos kubeconfig get --context prod --endpoint lb.sealyun.com:6443
+`
+
+func init() {
+	rootCmd.AddCommand(NewKubeconfigCommand())
+}
+
+func NewKubeconfigCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "kubeconfig <subcommand>",
+		Short:   "Manage the kubeconfigs of your kubernetes cluster",
+		Example: exampleKubeconfigCmd,
+	}
+	cmd.AddCommand(NewKubeconfigCreateCommand())
+	cmd.AddCommand(NewKubeconfigGetCommand())
+	return cmd
+}
+
+func NewKubeconfigCreateCommand() *cobra.Command {
+	k := &install.KubeconfigFlags{}
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "create a kubeconfig signed by the cluster ca or with a ServiceAccount token",
+		Run: func(cmd *cobra.Command, args []string) {
+			flags := install.GetKubeconfigFlags(cfgFile)
+			flags.User, flags.Groups, flags.TTL, flags.Server = k.User, k.Groups, k.TTL, k.Server
+			flags.ClusterRole, flags.Token, flags.Namespace, flags.Output = k.ClusterRole, k.Token, k.Namespace, k.Output
+			if err := flags.Create(); err != nil {
+				logger.Error(err)
+				os.Exit(install.ErrorExitOSCase)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&k.User, "user", "", "user name, the common name of the client cert or the ServiceAccount name")
+	cmd.Flags().StringSliceVar(&k.Groups, "group", []string{}, "groups of the user, the organizations of the client cert")
+	cmd.Flags().DurationVar(&k.TTL, "ttl", install.DefaultKubeconfigTTL, "validity of the client cert or token, ex. 8760h, the token may be capped by the apiserver")
+	cmd.Flags().StringVar(&k.Server, "server", "", "apiserver address in the kubeconfig, default https://master0:<apiserver port>, the apiserver port is --apiserver-port of init")
+	cmd.Flags().StringVar(&k.ClusterRole, "cluster-role", "", "bind the ClusterRole to the user, ex. view, edit")
+	cmd.Flags().BoolVar(&k.Token, "token", false, "create a ServiceAccount token instead of a client cert")
+	cmd.Flags().StringVarP(&k.Namespace, "namespace", "n", "default", "namespace of the ServiceAccount")
+	cmd.Flags().StringVarP(&k.Output, "output", "o", "", "kubeconfig file to write, default <user>.kubeconfig")
+	_ = cmd.MarkFlagRequired("user")
+	return cmd
+}
+
+func NewKubeconfigGetCommand() *cobra.Command {
+	k := &install.KubeconfigFlags{}
+	cmd := &cobra.Command{
+		Use:   "get",
+		Short: "fetch the admin kubeconfig from master0 and merge it into the local kubeconfig",
+		Run: func(cmd *cobra.Command, args []string) {
+			flags := install.GetKubeconfigFlags(cfgFile)
+			flags.Endpoint, flags.InfraFile, flags.Context, flags.Output = k.Endpoint, k.InfraFile, k.Context, k.Output
+			if err := flags.Get(); err != nil {
+				logger.Error(err)
+				os.Exit(install.ErrorExitOSCase)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&k.Endpoint, "endpoint", "", "apiserver endpoint of the kubeconfig, vip, eip, master0 or a custom lb address, default master0 in ipvs mode and vip in the other modes")
+	cmd.Flags().StringVar(&k.InfraFile, "infra", "", "infra file with the eip in status, required by the eip endpoint")
+	cmd.Flags().StringVar(&k.Context, "context", install.DefaultKubeconfigContext, "context name of the cluster in the local kubeconfig")
+	cmd.Flags().StringVarP(&k.Output, "output", "o", install.DefaultLocalKubeconfig, "local kubeconfig file to merge into")
+	return cmd
+}
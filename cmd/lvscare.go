@@ -0,0 +1,53 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/fanux/sealos/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+var exampleLvscareCmd = `
+	# edit the lvscare section of ~/.sealos/config.yaml, like
+	#   lvscare:
+	#     interval: 3
+	#     pullpolicy: Always
+	#     limits:
+	#       cpu: 200m
+	#       memory: 128Mi
+	# then regenerate the lvscare static pod of every node by the masters in the config
+	sealos lvscare sync
+`
+
+func init() {
+	rootCmd.AddCommand(NewLvscareCommand())
+}
+
+func NewLvscareCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "lvscare <subcommand>",
+		Short:   "Manage the lvscare static pod of the nodes",
+		Example: exampleLvscareCmd,
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "sync",
+		Short: "regenerate the lvscare static pod by sealos config, push it to every node and report the nodes whose ipvs rules do not match the masters",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(install.GetLvscareFlags(cfgFile).Sync())
+		},
+	})
+	return cmd
+}
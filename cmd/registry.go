@@ -0,0 +1,46 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/fanux/sealos/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+var exampleRegistryCmd = `
+	# edit the registry of ~/.sealos/config.yaml, then render it to containerd or docker on every host
+	sealos registry sync
+`
+
+func init() {
+	rootCmd.AddCommand(NewRegistryCommand())
+}
+
+func NewRegistryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "registry <subcommand>",
+		Short:   "Manage the registry config of the container runtime on your kubernetes nodes",
+		Example: exampleRegistryCmd,
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "sync",
+		Short: "render the registry mirrors, insecure registries, CAs and auths in sealos config to every host, and restart the container runtime one host at a time",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			exitOnError(install.GetRegistryFlags(cfgFile).Sync())
+		},
+	})
+	return cmd
+}
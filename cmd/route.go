@@ -20,8 +20,9 @@ import (
 )
 
 var (
-	host      string
-	gatewayIP string
+	host         string
+	gatewayIP    string
+	persistRoute bool
 )
 
 func NewRouteCmd() *cobra.Command {
@@ -35,6 +36,7 @@ func NewRouteCmd() *cobra.Command {
 	cmd.Flags().StringVar(&host, "host", "", "route host ip address for iFace")
 	cmd.AddCommand(NewDelRouteCmd())
 	cmd.AddCommand(NewAddRouteCmd())
+	cmd.AddCommand(NewListRouteCmd())
 	return cmd
 }
 
@@ -51,6 +53,7 @@ func NewAddRouteCmd() *cobra.Command {
 	// manually to set host via gateway
 	cmd.Flags().StringVar(&host, "host", "", "route host ,ex ip route add host via gateway")
 	cmd.Flags().StringVar(&gatewayIP, "gateway", "", "route gateway ,ex ip route add host via gateway")
+	cmd.Flags().BoolVar(&persistRoute, "persist", false, "install a systemd unit to add the route again at boot")
 	return cmd
 }
 
@@ -66,6 +69,15 @@ func NewDelRouteCmd() *cobra.Command {
 	return cmd
 }
 
+func NewListRouteCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "list",
+		Short: "list the routes managed by sealos on this host",
+		Run:   RouteListCmdFunc,
+	}
+	return cmd
+}
+
 func RouteCmdFunc(cmd *cobra.Command, args []string) {
 	r := install.GetRouteFlag(host, gatewayIP)
 	r.CheckRoute()
@@ -73,6 +85,7 @@ func RouteCmdFunc(cmd *cobra.Command, args []string) {
 
 func RouteAddCmdFunc(cmd *cobra.Command, args []string) {
 	r := install.GetRouteFlag(host, gatewayIP)
+	r.Persist = persistRoute
 	r.SetRoute()
 }
 
@@ -80,3 +93,8 @@ func RouteDelCmdFunc(cmd *cobra.Command, args []string) {
 	r := install.GetRouteFlag(host, gatewayIP)
 	r.DelRoute()
 }
+
+func RouteListCmdFunc(cmd *cobra.Command, args []string) {
+	r := install.GetRouteFlag(host, gatewayIP)
+	exitOnError(r.ListRoute())
+}
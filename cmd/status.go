@@ -0,0 +1,65 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/fanux/sealos/pkg/logger"
+
+	"github.com/fanux/sealos/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+var exampleStatusCmd = `
+	# show masters and nodes, control-plane, etcd and certs status
+	sealos status
+
+	# show cluster status in json or yaml
+	sealos status -o json
+`
+
+var statusOutput string
+
+func init() {
+	rootCmd.AddCommand(NewStatusCmd())
+}
+
+func NewStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "status",
+		Short:   "show the cluster overview of sealos config and kubernetes",
+		Example: exampleStatusCmd,
+		Run:     StatusCmdFunc,
+	}
+	cmd.Flags().StringVarP(&statusOutput, "output", "o", install.StatusOutputTable, "output format, support table|json|yaml")
+	return cmd
+}
+
+func StatusCmdFunc(cmd *cobra.Command, args []string) {
+	if statusOutput == install.StatusOutputJSON || statusOutput == install.StatusOutputYAML {
+		// the ssh commands and health checks log to the console, keep stdout for the document only.
+		logger.SetConsoleStderr()
+	}
+	status, err := install.GetClusterStatus(cfgFile)
+	if err != nil {
+		logger.Error("get cluster status err: %s", err)
+		os.Exit(install.ErrorExitOSCase)
+	}
+	if err := status.Print(os.Stdout, statusOutput); err != nil {
+		logger.Error(err)
+		os.Exit(install.ErrorExitOSCase)
+	}
+}
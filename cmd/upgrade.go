@@ -15,13 +15,16 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/fanux/sealos/pkg/logger"
 
 	"github.com/fanux/sealos/pkg/utils"
 
 	install "github.com/fanux/sealos/pkg/install"
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
 	"github.com/spf13/cobra"
 )
 
@@ -30,8 +33,17 @@ func init() {
 }
 
 var (
-	newVersion string
-	newPkgURL  string
+	newVersion       string
+	newPkgURL        string
+	upgradePkgDir    string
+	ignorePlanErrors bool
+	// upgradeHops is the packages to upgrade one minor version at a time, resolved by PreRun.
+	upgradeHops []install.UpgradeHop
+
+	upgradeMaxUnavailable int
+	upgradeDrainWorkers   bool
+	upgradeDrainTimeout   time.Duration
+	upgradeReadyTimeout   time.Duration
 )
 
 func NewUpgradeCmd() *cobra.Command {
@@ -41,13 +53,90 @@ func NewUpgradeCmd() *cobra.Command {
 		Run:    UpgradeCmdFunc,
 		PreRun: PreRunUpgradeCmdFunc,
 	}
-	cmd.Flags().StringVar(&newVersion, "version", "", "upgrade version for kubernetes version")
-	cmd.Flags().StringVar(&newPkgURL, "pkg-url", "", "http://store.lameleg.com/kube1.14.1.tar.gz download offline package url, or file location ex. /root/kube1.14.1.tar.gz")
+	cmd.PersistentFlags().StringVar(&newVersion, "version", "", "upgrade version for kubernetes version")
+	cmd.PersistentFlags().StringVar(&newPkgURL, "pkg-url", "", "http://store.lameleg.com/kube1.14.1.tar.gz download offline package url, or file location ex. /root/kube1.14.1.tar.gz, comma separated for multi-hop upgrade")
+	cmd.PersistentFlags().StringVar(&upgradePkgDir, "pkg-dir", "", "dir of the offline packages for multi-hop upgrade, one package for every minor version to the target version")
+	cmd.PersistentFlags().IntVar(&upgradeMaxUnavailable, "max-unavailable", 1, "number of worker nodes upgraded at the same time, masters are upgraded one at a time")
+	cmd.PersistentFlags().BoolVar(&upgradeDrainWorkers, "drain-workers", false, "drain the worker nodes before upgrade, evictions honour PodDisruptionBudgets")
+	cmd.PersistentFlags().DurationVar(&upgradeDrainTimeout, "drain-timeout", install.DefaultDrainTimeout, "timeout to drain a node")
+	cmd.PersistentFlags().DurationVar(&upgradeReadyTimeout, "ready-timeout", install.DefaultReadyTimeout, "timeout to wait for a node Ready after upgrade, the node is uncordoned after Ready")
 	cmd.Flags().BoolVarP(&force, "force", "f", false, "upgrade need interactive to confirm")
+	cmd.Flags().BoolVar(&ignorePlanErrors, "ignore-plan-errors", false, "force to upgrade even if the upgrade plan is failed")
+	cmd.AddCommand(NewUpgradePlanCmd())
+	cmd.AddCommand(NewUpgradeRollbackCmd())
 	return cmd
 }
 
+func NewUpgradeRollbackCmd() *cobra.Command {
+	var restoreEtcd bool
+	var toVersion string
+	cmd := &cobra.Command{
+		Use:   "rollback",
+		Short: "rollback the upgrade by the backup taken before it, the last upgrade by default",
+		Run: func(cmd *cobra.Command, args []string) {
+			u := install.GetRollbackUpgrade(cfgFile)
+			b, err := install.LoadUpgradeBackup(toVersion)
+			if err != nil {
+				logger.Error(err)
+				os.Exit(install.ErrorExitOSCase)
+			}
+			if !force {
+				prompt := fmt.Sprintf("Are you sure to rollback your kubernetes cluster to %s backed up at %s, etcd restore: %t (y/n)?",
+					b.Version, b.Time.Format(time.RFC3339), restoreEtcd)
+				result, err := utils.Confirm(prompt, "You have canceled to exec rollback cmd !")
+				if err != nil {
+					logger.Fatal(err)
+				}
+				if !result {
+					logger.Info("rollback is skip, Exit")
+					os.Exit(-1)
+				}
+			}
+			if err = u.Rollback(b, restoreEtcd); err != nil {
+				logger.Error(err)
+				os.Exit(install.ErrorExitOSCase)
+			}
+			u.Dump(cfgFile)
+			logger.Info("rollback to %s success", b.Version)
+		},
+	}
+	cmd.Flags().BoolVar(&restoreEtcd, "etcd", false, "restore the etcd snapshot taken before upgrade, the control plane is stopped during restore")
+	cmd.Flags().StringVar(&toVersion, "to", "", "the version to rollback to, a version upgraded from, ex. the first hop of a multi-hop upgrade, default the version before the last upgrade")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "rollback need interactive to confirm")
+	return cmd
+}
+
+func NewUpgradePlanCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    "plan",
+		Short:  "check the cluster and print the upgrade steps without upgrading",
+		PreRun: PreRunUpgradeCmdFunc,
+		Run: func(cmd *cobra.Command, args []string) {
+			printUpgradeHops()
+			plan := newUpgrade().Plan()
+			if err := plan.Print(os.Stdout); err != nil {
+				logger.Error(err)
+				os.Exit(install.ErrorExitOSCase)
+			}
+			if plan.Failed() {
+				os.Exit(install.ErrorExitOSCase)
+			}
+		},
+	}
+}
+
 func UpgradeCmdFunc(cmd *cobra.Command, args []string) {
+	u := newUpgrade()
+	plan := u.Plan()
+	if plan.Failed() {
+		_ = plan.Print(os.Stdout)
+		if !ignorePlanErrors {
+			logger.Error("upgrade plan is failed, fix it or upgrade with --ignore-plan-errors, Exit")
+			os.Exit(install.ErrorExitOSCase)
+		}
+		logger.Warn("upgrade plan is failed, upgrade is forced by --ignore-plan-errors")
+	}
+	printUpgradeHops()
 	if !force {
 		prompt := "Are you exec upgrade cmd will upgrade your kubernetes cluster immediately \n" +
 			"Are you sure you want to proceed with the upgrade?  (y/n)?"
@@ -61,13 +150,51 @@ func UpgradeCmdFunc(cmd *cobra.Command, args []string) {
 			os.Exit(-1)
 		}
 	}
-	u := install.NewUpgrade(newVersion, newPkgURL)
+	if len(upgradeHops) > 1 {
+		if err := u.UpgradeHops(cfgFile, newVersion, upgradeHops, ignorePlanErrors); err != nil {
+			logger.Error(err)
+			os.Exit(install.ErrorExitOSCase)
+		}
+		return
+	}
 	u.SetUP()
 	u.Dump(cfgFile)
 }
 
+func printUpgradeHops() {
+	if len(upgradeHops) <= 1 {
+		return
+	}
+	logger.Info("upgrade from %s to %s in %d hops:", v1.Version, newVersion, len(upgradeHops))
+	for i, hop := range upgradeHops {
+		logger.Info("  %d. %s by %s", i+1, hop.Version, hop.PkgURL)
+	}
+}
+
+func newUpgrade() *install.SealosUpgrade {
+	u := install.NewUpgrade(upgradeHops[0].Version, upgradeHops[0].PkgURL)
+	u.MaxUnavailable = upgradeMaxUnavailable
+	u.DrainWorkers = upgradeDrainWorkers
+	u.DrainTimeout = upgradeDrainTimeout
+	u.ReadyTimeout = upgradeReadyTimeout
+	return u
+}
+
 func PreRunUpgradeCmdFunc(cmd *cobra.Command, args []string) {
-	if err := install.ExitUpgradeCase(newVersion, newPkgURL, cfgFile); err != nil {
+	if upgradeMaxUnavailable < 1 {
+		logger.Error("PreRun error: --max-unavailable must be at least 1, got %d", upgradeMaxUnavailable)
+		os.Exit(1)
+	}
+	pkgs, err := install.UpgradePackages(newPkgURL, upgradePkgDir)
+	if err != nil {
+		logger.Error("PreRun error: ", err)
+		os.Exit(1)
+	}
+	if err = install.ExitUpgradeCase(newVersion, pkgs, cfgFile); err != nil {
+		logger.Error("PreRun error: ", err)
+		os.Exit(1)
+	}
+	if upgradeHops, err = install.ResolveUpgradeHops(v1.Version, newVersion, pkgs); err != nil {
 		logger.Error("PreRun error: ", err)
 		os.Exit(1)
 	}
@@ -14,11 +14,34 @@
 
 package cni
 
+import (
+	"strings"
+
+	"github.com/fanux/sealos/pkg/logger"
+)
+
+const (
+	CalicoEncapsulationIPIP             = "ipip"
+	CalicoEncapsulationVXLAN            = "vxlan"
+	CalicoEncapsulationIPIPCrossSubnet  = "ipip-crosssubnet"
+	CalicoEncapsulationVXLANCrossSubnet = "vxlan-crosssubnet"
+	CalicoEncapsulationNone             = "none"
+)
+
+// CalicoEncapsulations is the supported encapsulation of the calico.
+var CalicoEncapsulations = []string{
+	CalicoEncapsulationIPIP,
+	CalicoEncapsulationVXLAN,
+	CalicoEncapsulationIPIPCrossSubnet,
+	CalicoEncapsulationVXLANCrossSubnet,
+	CalicoEncapsulationNone,
+}
+
 type Calico struct {
 	metadata MetaData
 }
 
-func (c Calico) Manifests(template string) string {
+func (c Calico) Manifests(template string) (string, error) {
 	if template == "" {
 		template = c.Template()
 	}
@@ -32,9 +55,34 @@ func (c Calico) Manifests(template string) string {
 		c.metadata.CniRepo = "calico"
 	}
 	if c.metadata.Version == "" {
-		c.metadata.Version = "v3.8.2"
+		c.metadata.Version = defaultCalicoVersion
+	}
+	if c.metadata.MTU == "" {
+		c.metadata.MTU = defaultMTU
+	}
+	if c.metadata.Calico.Encapsulation == "" {
+		c.metadata.Calico.Encapsulation = CalicoEncapsulationNone
+		if c.metadata.IPIP {
+			c.metadata.Calico.Encapsulation = CalicoEncapsulationIPIP
+		}
+	}
+	// vxlan cross subnet is supported since calico v3.17.
+	if c.metadata.Version == defaultCalicoVersion && c.metadata.Calico.Encapsulation == CalicoEncapsulationVXLANCrossSubnet {
+		logger.Warn("calico %s does not support %s, use %s", c.metadata.Version, CalicoEncapsulationVXLANCrossSubnet, CalicoEncapsulationVXLAN)
+		c.metadata.Calico.Encapsulation = CalicoEncapsulationVXLAN
 	}
-	return render(c.metadata, template)
+	manifests, err := render(c.metadata, template)
+	if err != nil {
+		return "", err
+	}
+	if c.metadata.Calico.ASNumber != "" || len(c.metadata.Calico.BGPPeers) != 0 {
+		bgp, err := render(c.metadata, CalicoBGPManifests)
+		if err != nil {
+			return "", err
+		}
+		manifests += bgp
+	}
+	return manifests, nil
 }
 
 func (c Calico) Template() string {
@@ -47,3 +95,57 @@ func (c Calico) Template() string {
 		return CalicoManifests
 	}
 }
+
+// IPIPMode is the CALICO_IPV4POOL_IPIP of the encapsulation.
+func (o CalicoOptions) IPIPMode() string {
+	switch o.Encapsulation {
+	case CalicoEncapsulationIPIP:
+		return "Always"
+	case CalicoEncapsulationIPIPCrossSubnet:
+		return "CrossSubnet"
+	default:
+		return "Never"
+	}
+}
+
+// VXLANMode is the CALICO_IPV4POOL_VXLAN of the encapsulation.
+func (o CalicoOptions) VXLANMode() string {
+	switch o.Encapsulation {
+	case CalicoEncapsulationVXLAN:
+		return "Always"
+	case CalicoEncapsulationVXLANCrossSubnet:
+		return "CrossSubnet"
+	default:
+		return "Never"
+	}
+}
+
+// MTUOverhead is the bytes of the encapsulation header, the calico MTU is the NIC MTU minus it.
+func (o CalicoOptions) MTUOverhead() int {
+	switch o.Encapsulation {
+	case CalicoEncapsulationIPIP, CalicoEncapsulationIPIPCrossSubnet:
+		return 20
+	case CalicoEncapsulationVXLAN, CalicoEncapsulationVXLANCrossSubnet:
+		return 50
+	default:
+		return 0
+	}
+}
+
+// BIRD return true if the bgp daemon bird is needed, vxlan always does not need it.
+func (o CalicoOptions) BIRD() bool {
+	return o.Encapsulation != CalicoEncapsulationVXLAN
+}
+
+// Backend is the calico_backend, bird or vxlan.
+func (o CalicoOptions) Backend() string {
+	if o.BIRD() {
+		return "bird"
+	}
+	return "vxlan"
+}
+
+// Name is the name of the BGPPeer resource.
+func (p BGPPeer) Name() string {
+	return "sealos-peer-" + strings.NewReplacer(".", "-", ":", "-").Replace(p.IP)
+}
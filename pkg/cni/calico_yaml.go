@@ -27,7 +27,7 @@ data:
   # You must set a non-zero value for Typha replicas below.
   typha_service_name: "none"
   # Configure the backend to use.
-  calico_backend: "bird"
+  calico_backend: "{{ .Calico.Backend }}"
 
   # Configure the MTU to use for workload interfaces and tunnels.
   # By default, MTU is auto-detected, and explicitly setting this field should not be required.
@@ -3663,10 +3663,10 @@ spec:
               value: "{{ .Interface }}"
             # Enable IPIP
             - name: CALICO_IPV4POOL_IPIP
-              value: "{{if not .IPIP }}Off{{else}}Always{{end}}"
+              value: "{{ .Calico.IPIPMode }}"
             # Enable or Disable VXLAN on the default IP pool.
             - name: CALICO_IPV4POOL_VXLAN
-              value: "Never"
+              value: "{{ .Calico.VXLANMode }}"
             # Set MTU for tunnel device used if ipip is enabled
             - name: FELIX_IPINIPMTU
               valueFrom:
@@ -3711,7 +3711,9 @@ spec:
               command:
               - /bin/calico-node
               - -felix-live
+{{- if .Calico.BIRD }}
               - -bird-live
+{{- end }}
             periodSeconds: 10
             initialDelaySeconds: 10
             failureThreshold: 6
@@ -3720,7 +3722,9 @@ spec:
               command:
               - /bin/calico-node
               - -felix-ready
+{{- if .Calico.BIRD }}
               - -bird-ready
+{{- end }}
             periodSeconds: 10
           volumeMounts:
             - mountPath: /lib/modules
@@ -3899,7 +3903,7 @@ data:
   # Typha is disabled.
   typha_service_name: "none"
   # Configure the backend to use.
-  calico_backend: "bird"
+  calico_backend: "{{ .Calico.Backend }}"
 
   # Configure the MTU to use
   veth_mtu: "{{ .MTU }}"
@@ -4494,13 +4498,22 @@ spec:
               value: "{{ .Interface }}"
             # Enable IPIP
             - name: CALICO_IPV4POOL_IPIP
-              value: "{{if not .IPIP }}Off{{else}}Always{{end}}"
+              value: "{{ .Calico.IPIPMode }}"
+            # Enable or Disable VXLAN on the default IP pool.
+            - name: CALICO_IPV4POOL_VXLAN
+              value: "{{ .Calico.VXLANMode }}"
             # Set MTU for tunnel device used if ipip is enabled
             - name: FELIX_IPINIPMTU
               valueFrom:
                 configMapKeyRef:
                   name: calico-config
                   key: veth_mtu
+            # Set MTU for the VXLAN tunnel device.
+            - name: FELIX_VXLANMTU
+              valueFrom:
+                configMapKeyRef:
+                  name: calico-config
+                  key: veth_mtu
             # The default IPv4 pool to create on startup if none exists. Pod IPs will be
             # chosen from this range. Changing this value after installation will have
             - name: CALICO_IPV4POOL_CIDR
@@ -4535,7 +4548,9 @@ spec:
             exec:
               command:
               - /bin/calico-node
+{{- if .Calico.BIRD }}
               - -bird-ready
+{{- end }}
               - -felix-ready
             periodSeconds: 10
           volumeMounts:
@@ -4659,3 +4674,28 @@ metadata:
   name: calico-kube-controllers
   namespace: kube-system
 `
+
+// CalicoBGPManifests is the AS number of the nodes and the global bgp peers, it is applied after the calico crds.
+const CalicoBGPManifests = `
+{{- if .Calico.ASNumber }}
+---
+apiVersion: crd.projectcalico.org/v1
+kind: BGPConfiguration
+metadata:
+  name: default
+spec:
+  logSeverityScreen: Info
+  nodeToNodeMeshEnabled: true
+  asNumber: {{ .Calico.ASNumber }}
+{{- end }}
+{{- range .Calico.BGPPeers }}
+---
+apiVersion: crd.projectcalico.org/v1
+kind: BGPPeer
+metadata:
+  name: {{ .Name }}
+spec:
+  peerIP: {{ .IP }}
+  asNumber: {{ .ASNumber }}
+{{- end }}
+`
@@ -0,0 +1,58 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cni
+
+const (
+	CiliumTunnelVXLAN    = "vxlan"
+	CiliumTunnelGeneve   = "geneve"
+	CiliumTunnelDisabled = "disabled"
+
+	defaultAPIServerHost = "apiserver.cluster.local"
+	defaultAPIServerPort = "6443"
+)
+
+// Cilium replaces kube-proxy, the cluster must be inited without the addon/kube-proxy phase.
+type Cilium struct {
+	metadata MetaData
+}
+
+func (c Cilium) Manifests(template string) (string, error) {
+	if template == "" {
+		template = c.Template()
+	}
+	if c.metadata.CIDR == "" {
+		c.metadata.CIDR = defaultCIDR
+	}
+	if c.metadata.CniRepo == "" || c.metadata.CniRepo == defaultCNIRepo {
+		c.metadata.CniRepo = "quay.io/cilium"
+	}
+	if c.metadata.Version == "" {
+		c.metadata.Version = defaultCiliumVersion
+	}
+	if c.metadata.Cilium.Tunnel == "" {
+		c.metadata.Cilium.Tunnel = CiliumTunnelVXLAN
+	}
+	if c.metadata.Cilium.APIServerHost == "" {
+		c.metadata.Cilium.APIServerHost = defaultAPIServerHost
+	}
+	if c.metadata.Cilium.APIServerPort == "" {
+		c.metadata.Cilium.APIServerPort = defaultAPIServerPort
+	}
+	return render(c.metadata, template)
+}
+
+func (c Cilium) Template() string {
+	return CiliumManifests
+}
@@ -0,0 +1,58 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cni
+
+import "strings"
+
+const (
+	FlannelBackendVXLAN  = "vxlan"
+	FlannelBackendHostGW = "host-gw"
+)
+
+type Flannel struct {
+	metadata MetaData
+}
+
+func (f Flannel) Manifests(template string) (string, error) {
+	if template == "" {
+		template = f.Template()
+	}
+	// flannel selects the interface by regex, can-reach of calico is not supported so the default route interface is used.
+	switch {
+	case f.metadata.Interface == "":
+		f.metadata.Interface = defaultInterface
+	case strings.HasPrefix(f.metadata.Interface, "interface="):
+		f.metadata.Interface = strings.TrimPrefix(f.metadata.Interface, "interface=")
+	case strings.HasPrefix(f.metadata.Interface, "can-reach="):
+		f.metadata.Interface = ""
+	}
+	if f.metadata.CIDR == "" {
+		f.metadata.CIDR = defaultCIDR
+	}
+	if f.metadata.CniRepo == "" || f.metadata.CniRepo == defaultCNIRepo {
+		f.metadata.CniRepo = "quay.io/coreos"
+	}
+	if f.metadata.Version == "" {
+		f.metadata.Version = defaultFlannelVersion
+	}
+	if f.metadata.Flannel.Backend == "" {
+		f.metadata.Flannel.Backend = FlannelBackendVXLAN
+	}
+	return render(f.metadata, template)
+}
+
+func (f Flannel) Template() string {
+	return FlannelManifests
+}
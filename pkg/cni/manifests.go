@@ -0,0 +1,62 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cni
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// CheckTemplate return the parse error of the custom template, it is checked before init.
+func CheckTemplate(temp string) error {
+	_, err := template.New("net").Parse(temp)
+	return err
+}
+
+// ValidateManifests check the manifests are kubernetes objects with apiVersion, kind and name.
+func ValidateManifests(manifests string) error {
+	d := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+	objects := 0
+	for i := 1; ; i++ {
+		var obj map[string]interface{}
+		if err := d.Decode(&obj); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("document %d is not valid yaml: %w", i, err)
+		}
+		// empty document, like the comments between ---
+		if obj == nil {
+			continue
+		}
+		apiVersion, _ := obj["apiVersion"].(string)
+		kind, _ := obj["kind"].(string)
+		if apiVersion == "" || kind == "" {
+			return fmt.Errorf("document %d has no apiVersion or kind", i)
+		}
+		metadata, _ := obj["metadata"].(map[string]interface{})
+		if name, _ := metadata["name"].(string); name == "" {
+			return fmt.Errorf("document %d %s has no metadata.name", i, kind)
+		}
+		objects++
+	}
+	if objects == 0 {
+		return fmt.Errorf("no kubernetes object is found")
+	}
+	return nil
+}
@@ -16,15 +16,34 @@ package cni
 
 import (
 	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
 	"text/template"
 )
 
+const (
+	CALICO  = "calico"
+	FLANNEL = "flannel"
+	CILIUM  = "cilium"
+)
+
 const (
 	defaultInterface = "eth.*|en.*"
 	defaultCIDR      = "100.64.0.0/10"
 	defaultCNIRepo   = "k8s.gcr.io"
+	defaultMTU       = "1440"
+
+	defaultCalicoVersion  = "v3.8.2"
+	defaultFlannelVersion = "v0.14.0"
+	defaultCiliumVersion  = "v1.9.8"
 )
 
+// CalicoVersions is the calico versions of the bundled manifests, flannel and cilium manifests use the version as image tag.
+var CalicoVersions = []string{defaultCalicoVersion, "v3.19.1"}
+
+var versionRegexp = regexp.MustCompile(`^v\d+\.\d+\.\d+$`)
+
 type MetaData struct {
 	Interface string
 	CIDR      string
@@ -34,11 +53,44 @@ type MetaData struct {
 	MTU     string
 	CniRepo string
 	Version string
+	// options of the calico, flannel and cilium
+	Calico  CalicoOptions
+	Flannel FlannelOptions
+	Cilium  CiliumOptions
+	// Values is the user variables of the custom template, ex. {{ .Values.image }}
+	Values map[string]interface{}
+}
+
+type CalicoOptions struct {
+	// Encapsulation is ipip, vxlan, ipip-crosssubnet, vxlan-crosssubnet or none, it is ipip or none by IPIP if empty
+	Encapsulation string
+	// ASNumber is the AS number of the nodes, calico uses 64512 if empty
+	ASNumber string
+	// BGPPeers is the global bgp peers like the top of rack routers
+	BGPPeers []BGPPeer
+}
+
+type BGPPeer struct {
+	IP       string
+	ASNumber string
+}
+
+type FlannelOptions struct {
+	// Backend is the flannel backend, vxlan or host-gw
+	Backend string
+}
+
+type CiliumOptions struct {
+	// Tunnel is the encapsulation mode, vxlan, geneve or disabled
+	Tunnel string
+	// APIServerHost and APIServerPort is the apiserver cilium connects to, kube-proxy is not installed.
+	APIServerHost string
+	APIServerPort string
 }
 
 // Net is CNI interface
 type Net interface {
-	Manifests(template string) string
+	Manifests(template string) (string, error)
 	Template() string
 }
 
@@ -46,9 +98,77 @@ func NewCalico(metadata MetaData) Net {
 	return &Calico{metadata: metadata}
 }
 
-func render(data MetaData, temp string) string {
+func NewFlannel(metadata MetaData) Net {
+	return &Flannel{metadata: metadata}
+}
+
+func NewCilium(metadata MetaData) Net {
+	return &Cilium{metadata: metadata}
+}
+
+// NewNetwork return the cni by name, nil if not supported.
+func NewNetwork(name string, metadata MetaData) Net {
+	switch name {
+	case CALICO, "":
+		return NewCalico(metadata)
+	case FLANNEL:
+		return NewFlannel(metadata)
+	case CILIUM:
+		return NewCilium(metadata)
+	default:
+		return nil
+	}
+}
+
+// DefaultVersion return the version of the cni if the package does not specify it.
+func DefaultVersion(name string) string {
+	switch name {
+	case FLANNEL:
+		return defaultFlannelVersion
+	case CILIUM:
+		return defaultCiliumVersion
+	default:
+		return defaultCalicoVersion
+	}
+}
+
+// CheckVersion return error if the bundled manifests of the cni do not support the version.
+func CheckVersion(name, version string) error {
+	if name == CALICO || name == "" {
+		for _, v := range CalicoVersions {
+			if v == version {
+				return nil
+			}
+		}
+		return fmt.Errorf("calico %s is not supported, only support %s, or use a custom manifest", version, strings.Join(CalicoVersions, "|"))
+	}
+	if !versionRegexp.MatchString(version) {
+		return fmt.Errorf("%s version %s is invalid, must be like v1.2.3", name, version)
+	}
+	return nil
+}
+
+// DaemonSetName return the name of the DaemonSet in kube-system running the cni agent.
+func DaemonSetName(name string) string {
+	switch name {
+	case FLANNEL:
+		return "kube-flannel-ds"
+	case CILIUM:
+		return "cilium"
+	default:
+		return "calico-node"
+	}
+}
+
+// render the template, a missing key of the user variables is an error, or it is rendered as <no value>.
+func render(data MetaData, temp string) (string, error) {
 	var b bytes.Buffer
-	t := template.Must(template.New("net").Parse(temp))
-	_ = t.Execute(&b, &data)
-	return b.String()
+	t, err := template.New("net").Option("missingkey=error").Parse(temp)
+	if err != nil {
+		return "", err
+	}
+	if err = t.Execute(&b, &data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
 }
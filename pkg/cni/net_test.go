@@ -16,27 +16,220 @@ package cni
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
+// manifests render the default template of the network.
+func manifests(t *testing.T, net Net) string {
+	t.Helper()
+	netyaml, err := net.Manifests("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return netyaml
+}
+
 func TestNewNetworkCalico(t *testing.T) {
-	netyaml := NewCalico(MetaData{
+	netyaml := manifests(t, NewCalico(MetaData{
 		Interface: "interface=en.*|eth.*",
 		CIDR:      "10.1.1.1/24",
 		IPIP:      true,
 		MTU:       "1440",
 		CniRepo:   "",
 		Version:   "v3.8.2",
-	}).Manifests("")
+	}))
 	fmt.Println(netyaml)
 
-	netyaml = NewCalico(MetaData{
+	netyaml = manifests(t, NewCalico(MetaData{
 		Interface: "interface=en.*|eth.*",
 		CIDR:      "10.1.1.1/24",
 		IPIP:      true,
 		MTU:       "1440",
 		CniRepo:   "",
 		Version:   "",
-	}).Manifests("")
+	}))
 	fmt.Println(netyaml)
 }
+
+func TestNewNetwork(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata MetaData
+		want     []string
+	}{
+		{
+			name:     FLANNEL,
+			metadata: MetaData{Interface: "interface=eth0", CIDR: "10.244.0.0/16", Flannel: FlannelOptions{Backend: FlannelBackendHostGW}},
+			want:     []string{`"Network": "10.244.0.0/16"`, `"Type": "host-gw"`, "--iface-regex=eth0", "quay.io/coreos/flannel:v0.14.0"},
+		},
+		{
+			name:     FLANNEL,
+			metadata: MetaData{Interface: "can-reach=192.168.0.1"},
+			want:     []string{`"Network": "100.64.0.0/10"`, `"Type": "vxlan"`},
+		},
+		{
+			name:     CILIUM,
+			metadata: MetaData{CIDR: "10.244.0.0/16", CniRepo: "registry.example.com/cilium", Version: "v1.9.10"},
+			want:     []string{"tunnel: vxlan", "kube-proxy-replacement: strict", `value: "apiserver.cluster.local"`, `value: "6443"`, "registry.example.com/cilium/cilium:v1.9.10"},
+		},
+		{
+			name:     CILIUM,
+			metadata: MetaData{CIDR: "10.244.0.0/16", Cilium: CiliumOptions{Tunnel: CiliumTunnelDisabled, APIServerHost: "10.0.0.1", APIServerPort: "8443"}},
+			want:     []string{"tunnel: disabled", "native-routing-cidr: 10.244.0.0/16", `value: "10.0.0.1"`, `value: "8443"`},
+		},
+	}
+	for _, tt := range tests {
+		netyaml := manifests(t, NewNetwork(tt.name, tt.metadata))
+		for _, w := range tt.want {
+			if !strings.Contains(netyaml, w) {
+				t.Errorf("%s manifests missing %q", tt.name, w)
+			}
+		}
+	}
+	if strings.Contains(manifests(t, NewNetwork(FLANNEL, MetaData{Interface: "can-reach=192.168.0.1"})), "--iface-regex") {
+		t.Errorf("flannel manifests should not set iface regex with can-reach")
+	}
+	if NewNetwork("weave", MetaData{}) != nil {
+		t.Errorf("unsupported network should be nil")
+	}
+}
+
+func TestCalicoEncapsulation(t *testing.T) {
+	tests := []struct {
+		version string
+		options CalicoOptions
+		ipip    bool
+		want    []string
+		notWant []string
+	}{
+		{
+			version: "v3.19.1",
+			ipip:    true,
+			want:    []string{`calico_backend: "bird"`, "CALICO_IPV4POOL_IPIP\n              value: \"Always\"", "CALICO_IPV4POOL_VXLAN\n              value: \"Never\"", "-bird-ready"},
+			notWant: []string{"nodeToNodeMeshEnabled: true", "name: sealos-peer"},
+		},
+		{
+			version: "v3.19.1",
+			options: CalicoOptions{Encapsulation: CalicoEncapsulationVXLAN},
+			want:    []string{`calico_backend: "vxlan"`, "CALICO_IPV4POOL_IPIP\n              value: \"Never\"", "CALICO_IPV4POOL_VXLAN\n              value: \"Always\""},
+			notWant: []string{"-bird-ready", "-bird-live"},
+		},
+		{
+			version: "v3.19.1",
+			options: CalicoOptions{Encapsulation: CalicoEncapsulationVXLANCrossSubnet},
+			want:    []string{`calico_backend: "bird"`, "CALICO_IPV4POOL_VXLAN\n              value: \"CrossSubnet\"", "-bird-ready"},
+		},
+		{
+			version: "v3.8.2",
+			options: CalicoOptions{Encapsulation: CalicoEncapsulationIPIPCrossSubnet},
+			want:    []string{`calico_backend: "bird"`, "CALICO_IPV4POOL_IPIP\n              value: \"CrossSubnet\"", "-bird-ready"},
+		},
+		{
+			version: "v3.8.2",
+			options: CalicoOptions{Encapsulation: CalicoEncapsulationVXLANCrossSubnet},
+			want:    []string{`calico_backend: "vxlan"`, "CALICO_IPV4POOL_VXLAN\n              value: \"Always\""},
+			notWant: []string{"-bird-ready"},
+		},
+		{
+			version: "v3.8.2",
+			options: CalicoOptions{Encapsulation: CalicoEncapsulationVXLAN},
+			want:    []string{`calico_backend: "vxlan"`, "name: FELIX_VXLANMTU"},
+		},
+		{
+			version: "v3.19.1",
+			options: CalicoOptions{Encapsulation: CalicoEncapsulationVXLAN},
+			want:    []string{"name: FELIX_VXLANMTU"},
+		},
+		{
+			version: "v3.8.2",
+			options: CalicoOptions{
+				Encapsulation: CalicoEncapsulationNone,
+				ASNumber:      "64512",
+				BGPPeers:      []BGPPeer{{IP: "192.168.0.1", ASNumber: "64513"}},
+			},
+			want: []string{"CALICO_IPV4POOL_IPIP\n              value: \"Never\"", "kind: BGPConfiguration", "asNumber: 64512", "name: sealos-peer-192-168-0-1", "peerIP: 192.168.0.1"},
+		},
+	}
+	for _, tt := range tests {
+		netyaml := manifests(t, NewCalico(MetaData{IPIP: tt.ipip, Version: tt.version, Calico: tt.options}))
+		for _, w := range tt.want {
+			if !strings.Contains(netyaml, w) {
+				t.Errorf("calico %s %s manifests missing %q", tt.version, tt.options.Encapsulation, w)
+			}
+		}
+		for _, w := range tt.notWant {
+			if strings.Contains(netyaml, w) {
+				t.Errorf("calico %s %s manifests should not contain %q", tt.version, tt.options.Encapsulation, w)
+			}
+		}
+	}
+}
+
+func TestValidateManifests(t *testing.T) {
+	for _, name := range []string{CALICO, FLANNEL, CILIUM} {
+		if err := ValidateManifests(manifests(t, NewNetwork(name, MetaData{}))); err != nil {
+			t.Errorf("%s manifests is invalid: %v", name, err)
+		}
+	}
+	if err := ValidateManifests(manifests(t, NewCalico(MetaData{Version: "v3.19.1"}))); err != nil {
+		t.Errorf("calico v3.19.1 manifests is invalid: %v", err)
+	}
+	tests := []struct {
+		manifests string
+		wantErr   string
+	}{
+		{"---\n# comment\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n", ""},
+		{"", "no kubernetes object"},
+		{"---\n# comment only\n", "no kubernetes object"},
+		{"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n---\nkind: ConfigMap\nmetadata:\n  name: b\n", "document 2 has no apiVersion or kind"},
+		{"apiVersion: v1\nkind: ConfigMap\n", "document 1 ConfigMap has no metadata.name"},
+		{"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: [a\n", "document 1 is not valid yaml"},
+	}
+	for _, tt := range tests {
+		err := ValidateManifests(tt.manifests)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("ValidateManifests(%q) unexpected error: %v", tt.manifests, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("ValidateManifests(%q) error = %v, want %q", tt.manifests, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	if err := CheckTemplate("cidr: {{ .CIDR }}"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckTemplate("cidr: {{ .CIDR "); err == nil {
+		t.Errorf("want error of the unclosed action")
+	}
+}
+
+func TestManifestsMissingValue(t *testing.T) {
+	temp := "image: {{ .Values.image }}"
+	if got, err := NewFlannel(MetaData{Values: map[string]interface{}{"image": "flannel:v0.14.0"}}).Manifests(temp); err != nil || got != "image: flannel:v0.14.0" {
+		t.Errorf("Manifests() = %q, %v", got, err)
+	}
+	if got, err := NewFlannel(MetaData{Values: map[string]interface{}{"imgae": "flannel:v0.14.0"}}).Manifests(temp); err == nil {
+		t.Errorf("Manifests() of the missing value = %q, want error", got)
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	tests := []struct {
+		name, version string
+		wantErr       bool
+	}{
+		{CALICO, "v3.19.1", false},
+		{CALICO, "v3.8.2", false},
+		{CALICO, "v3.20.0", true},
+		{FLANNEL, "v0.15.1", false},
+		{CILIUM, "1.10.0", true},
+	}
+	for _, tt := range tests {
+		if err := CheckVersion(tt.name, tt.version); (err != nil) != tt.wantErr {
+			t.Errorf("CheckVersion(%s, %s) error = %v, wantErr %v", tt.name, tt.version, err, tt.wantErr)
+		}
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package util
 
 import (
+	"encoding/json"
 	"path/filepath"
 	goruntime "runtime"
 	"strings"
@@ -32,12 +33,34 @@ import (
 const (
 	DefaultDockerCRISocket     = "/var/run/dockershim.sock"
 	DefaultContainerdCRISocket = containerdSocket
+	DefaultCRIOCRISocket       = crioSocket
 	DefaultCgroupDriver        = "cgroupfs"
 	DefaultSystemdCgroupDriver = "systemd"
 	// PullImageRetry specifies how many times ContainerRuntime retries when pulling image failed
 	PullImageRetry = 5
 )
 
+// Container runtime names, the values of --cri.
+const (
+	Docker     = "docker"
+	Containerd = "containerd"
+	CRIO       = "cri-o"
+)
+
+// CRISocket return the CRI socket of the container runtime name, empty if the name is unknown.
+func CRISocket(name string) string {
+	switch name {
+	case Docker:
+		return DefaultDockerCRISocket
+	case Containerd:
+		return DefaultContainerdCRISocket
+	case CRIO:
+		return DefaultCRIOCRISocket
+	default:
+		return ""
+	}
+}
+
 // ContainerRuntime is an interface for working with container runtimes
 type ContainerRuntime interface {
 	IsDocker() bool
@@ -46,6 +69,9 @@ type ContainerRuntime interface {
 	RemoveContainers(containers []string) error
 	PullImage(image string) error
 	ImageExists(image string) (bool, error)
+	ListImages() ([]string, error)
+	LoadImage(file string) error
+	PruneImages(keep func(image string) bool) error
 	CGroupDriver() (string, error)
 }
 
@@ -60,6 +86,11 @@ type DockerRuntime struct {
 	exec utilsexec.Interface
 }
 
+// CRIORuntime is the CRI-O runtime, the cgroup driver is read from the crio config instead of crictl info.
+type CRIORuntime struct {
+	CRIRuntime
+}
+
 // NewContainerRuntime sets up and returns a ContainerRuntime struct
 func NewContainerRuntime(execer utilsexec.Interface, criSocket string) (ContainerRuntime, error) {
 	var toolName string
@@ -73,7 +104,11 @@ func NewContainerRuntime(execer utilsexec.Interface, criSocket string) (Containe
 		if filepath.IsAbs(criSocket) && goruntime.GOOS != "windows" {
 			criSocket = "unix://" + criSocket
 		}
-		runtime = &CRIRuntime{execer, criSocket}
+		if strings.HasSuffix(criSocket, crioSocket) {
+			runtime = &CRIORuntime{CRIRuntime{execer, criSocket}}
+		} else {
+			runtime = &CRIRuntime{execer, criSocket}
+		}
 	} else {
 		toolName = "docker"
 		runtime = &DockerRuntime{execer}
@@ -139,6 +174,25 @@ func (runtime *CRIRuntime) CGroupDriver() (string, error) {
 	return DefaultSystemdCgroupDriver, nil
 }
 
+// CGroupDriver read cgroup_manager of the CRI-O config.
+func (runtime *CRIORuntime) CGroupDriver() (string, error) {
+	if err := runtime.IsRunning(); err != nil {
+		return "", err
+	}
+	out, err := runtime.exec.Command("crio", "config").CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get crio config: output: %s, error", string(out))
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) == 2 && strings.TrimSpace(kv[0]) == "cgroup_manager" {
+			return strings.Trim(strings.TrimSpace(kv[1]), `"`), nil
+		}
+	}
+	// systemd is the default cgroup manager of CRI-O.
+	return DefaultSystemdCgroupDriver, nil
+}
+
 // ListKubeContainers lists running k8s CRI pods
 func (runtime *CRIRuntime) ListKubeContainers() ([]string, error) {
 	out, err := runtime.exec.Command("crictl", "-r", runtime.criSocket, "pods", "-q").CombinedOutput()
@@ -230,12 +284,189 @@ func (runtime *DockerRuntime) ImageExists(image string) (bool, error) {
 	return err == nil, nil
 }
 
+// criImage is the image listed by crictl images
+type criImage struct {
+	ID          string   `json:"id"`
+	RepoTags    []string `json:"repoTags"`
+	RepoDigests []string `json:"repoDigests"`
+}
+
+// listImages lists the images by crictl
+func (runtime *CRIRuntime) listImages() ([]criImage, error) {
+	out, err := runtime.exec.Command("crictl", "-r", runtime.criSocket, "images", "-o", "json").Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list images")
+	}
+	var list struct {
+		Images []criImage `json:"images"`
+	}
+	if err = json.Unmarshal(out, &list); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse images: %s", string(out))
+	}
+	return list.Images, nil
+}
+
+// ListImages lists the image tags, the untagged images are skipped
+func (runtime *CRIRuntime) ListImages() ([]string, error) {
+	list, err := runtime.listImages()
+	if err != nil {
+		return nil, err
+	}
+	images := []string{}
+	for _, image := range list {
+		images = append(images, image.RepoTags...)
+	}
+	return images, nil
+}
+
+// ListImages lists the image tags, the untagged images are skipped
+func (runtime *DockerRuntime) ListImages() ([]string, error) {
+	out, err := runtime.exec.Command("docker", "images", "--format", "{{.Repository}}:{{.Tag}}").CombinedOutput()
+	if err != nil {
+		return nil, errors.Wrapf(err, "output: %s, error", string(out))
+	}
+	images := []string{}
+	for _, image := range strings.Fields(string(out)) {
+		if !strings.HasSuffix(image, ":<none>") {
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
+
+// LoadImage imports the image tarball, crictl can not load images, so ctr is used in the k8s.io namespace of containerd
+func (runtime *CRIRuntime) LoadImage(file string) error {
+	if out, err := runtime.exec.Command("ctr", "-n=k8s.io", "image", "import", file).CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "failed to load image %s: output: %s, error", file, string(out))
+	}
+	return nil
+}
+
+// LoadImage loads the image tarball by podman, CRI-O shares the containers storage with podman
+func (runtime *CRIORuntime) LoadImage(file string) error {
+	if out, err := runtime.exec.Command("podman", "load", "-i", file).CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "failed to load image %s: output: %s, error", file, string(out))
+	}
+	return nil
+}
+
+// LoadImage loads the image tarball
+func (runtime *DockerRuntime) LoadImage(file string) error {
+	if out, err := runtime.exec.Command("docker", "load", "-i", file).CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "failed to load image %s: output: %s, error", file, string(out))
+	}
+	return nil
+}
+
+// PruneImages removes the images not used by any container one by one, the image is kept if any of its tags is kept
+func (runtime *CRIRuntime) PruneImages(keep func(image string) bool) error {
+	out, err := runtime.exec.Command("crictl", "-r", runtime.criSocket, "ps", "-a", "-o", "json").Output()
+	if err != nil {
+		return errors.Wrapf(err, "failed to list containers")
+	}
+	var containers struct {
+		Containers []struct {
+			Image struct {
+				Image string `json:"image"`
+			} `json:"image"`
+			ImageRef string `json:"imageRef"`
+		} `json:"containers"`
+	}
+	if err = json.Unmarshal(out, &containers); err != nil {
+		return errors.Wrapf(err, "failed to parse containers: %s", string(out))
+	}
+	used := make(map[string]bool)
+	for _, c := range containers.Containers {
+		used[c.Image.Image] = true
+		used[c.ImageRef] = true
+	}
+	images, err := runtime.listImages()
+	if err != nil {
+		return err
+	}
+	errs := []error{}
+	for _, image := range images {
+		if used[image.ID] || anyOf(image.RepoTags, func(tag string) bool { return used[tag] || keep(tag) }) || anyOf(image.RepoDigests, func(digest string) bool { return used[digest] }) {
+			continue
+		}
+		if out, err := runtime.exec.Command("crictl", "-r", runtime.criSocket, "rmi", image.ID).CombinedOutput(); err != nil {
+			// don't stop on errors, try to remove as many images as possible
+			errs = append(errs, errors.Wrapf(err, "failed to remove image %s: output: %s, error", image.ID, string(out)))
+		}
+	}
+	return errorsutil.NewAggregate(errs)
+}
+
+// PruneImages removes the images not used by any container one by one, the image is kept if any of its tags is kept
+func (runtime *DockerRuntime) PruneImages(keep func(image string) bool) error {
+	out, err := runtime.exec.Command("docker", "ps", "-a", "-q").CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "failed to list containers: output: %s, error", string(out))
+	}
+	used := make(map[string]bool)
+	if containers := strings.Fields(string(out)); len(containers) != 0 {
+		args := append([]string{"inspect", "--format", "{{.Image}}"}, containers...)
+		out, err = runtime.exec.Command("docker", args...).CombinedOutput()
+		if err != nil {
+			return errors.Wrapf(err, "failed to inspect containers: output: %s, error", string(out))
+		}
+		for _, id := range strings.Fields(string(out)) {
+			used[id] = true
+		}
+	}
+	out, err = runtime.exec.Command("docker", "images", "--no-trunc", "--format", "{{.ID}} {{.Repository}}:{{.Tag}}").CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "failed to list images: output: %s, error", string(out))
+	}
+	ids := []string{}
+	tags := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		if _, ok := tags[fields[0]]; !ok {
+			ids = append(ids, fields[0])
+			tags[fields[0]] = []string{}
+		}
+		if !strings.HasSuffix(fields[1], ":<none>") {
+			tags[fields[0]] = append(tags[fields[0]], fields[1])
+		}
+	}
+	errs := []error{}
+	for _, id := range ids {
+		if used[id] || anyOf(tags[id], keep) {
+			continue
+		}
+		// the image in several repositories can not be removed by id without force, remove all its tags instead
+		refs := tags[id]
+		if len(refs) == 0 {
+			refs = []string{id}
+		}
+		if out, err := runtime.exec.Command("docker", append([]string{"rmi"}, refs...)...).CombinedOutput(); err != nil {
+			// don't stop on errors, try to remove as many images as possible
+			errs = append(errs, errors.Wrapf(err, "failed to remove image %s: output: %s, error", id, string(out)))
+		}
+	}
+	return errorsutil.NewAggregate(errs)
+}
+
+// anyOf return true if f is true for any of the items
+func anyOf(items []string, f func(string) bool) bool {
+	for _, item := range items {
+		if f(item) {
+			return true
+		}
+	}
+	return false
+}
+
 // detectCRISocketImpl is separated out only for test purposes, DON'T call it directly, use DetectCRISocket instead
 func detectCRISocketImpl(isSocket func(string) bool) (string, error) {
 	foundCRISockets := []string{}
 	knownCRISockets := []string{
 		// Docker and containerd sockets are special cased below, hence not to be included here
-		"/var/run/crio/crio.sock",
+		crioSocket,
 	}
 
 	if isSocket(dockerSocket) {
@@ -323,6 +323,106 @@ func TestImageExists(t *testing.T) {
 	}
 }
 
+func TestListImages(t *testing.T) {
+	fcmd := fakeexec.FakeCmd{
+		OutputScript: []fakeexec.FakeAction{
+			func() ([]byte, []byte, error) {
+				return []byte(`{"images":[{"id":"sha256:1","repoTags":["k8s.gcr.io/pause:3.2"]},{"id":"sha256:2","repoTags":[]}]}`), nil, nil
+			},
+		},
+		CombinedOutputScript: []fakeexec.FakeAction{
+			func() ([]byte, []byte, error) { return []byte("k8s.gcr.io/pause:3.2\n<none>:<none>\n"), nil, nil },
+		},
+	}
+	execer := fakeexec.FakeExec{
+		CommandScript: genFakeActions(&fcmd, 2),
+		LookPathFunc:  func(cmd string) (string, error) { return crlctlBin, nil },
+	}
+	for _, criSocket := range []string{DefaultContainerdCRISocket, DefaultDockerCRISocket} {
+		runtime, err := NewContainerRuntime(&execer, criSocket)
+		if err != nil {
+			t.Fatalf("unexpected NewContainerRuntime error: %v", err)
+		}
+		images, err := runtime.ListImages()
+		if err != nil {
+			t.Fatalf("unexpected ListImages error: %v", err)
+		}
+		if !reflect.DeepEqual(images, []string{"k8s.gcr.io/pause:3.2"}) {
+			t.Errorf("unexpected ListImages output of %s: %v", criSocket, images)
+		}
+	}
+}
+
+func TestPruneImages(t *testing.T) {
+	keep := func(image string) bool { return image == "k8s.gcr.io/pause:3.2" }
+
+	fcmd := fakeexec.FakeCmd{
+		OutputScript: []fakeexec.FakeAction{
+			func() ([]byte, []byte, error) {
+				return []byte(`{"containers":[{"image":{"image":"sha256:2"},"imageRef":"sha256:2"}]}`), nil, nil
+			},
+			func() ([]byte, []byte, error) {
+				return []byte(`{"images":[{"id":"sha256:1","repoTags":["k8s.gcr.io/pause:3.2"]},{"id":"sha256:2","repoTags":["nginx:1.19"]},{"id":"sha256:3","repoTags":["busybox:1.32"]},{"id":"sha256:4","repoTags":[]}]}`), nil, nil
+			},
+		},
+		CombinedOutputScript: []fakeexec.FakeAction{
+			func() ([]byte, []byte, error) { return nil, nil, nil },
+			func() ([]byte, []byte, error) { return nil, nil, nil },
+		},
+	}
+	execer := fakeexec.FakeExec{
+		CommandScript: genFakeActions(&fcmd, 4),
+		LookPathFunc:  func(cmd string) (string, error) { return crlctlBin, nil },
+	}
+	runtime, err := NewContainerRuntime(&execer, DefaultContainerdCRISocket)
+	if err != nil {
+		t.Fatalf("unexpected NewContainerRuntime error: %v", err)
+	}
+	if err = runtime.PruneImages(keep); err != nil {
+		t.Fatalf("unexpected PruneImages error: %v", err)
+	}
+	var removed []string
+	for _, argv := range fcmd.CombinedOutputLog {
+		removed = append(removed, argv[len(argv)-1])
+	}
+	if !reflect.DeepEqual(removed, []string{"sha256:3", "sha256:4"}) {
+		t.Errorf("unexpected crictl rmi: %v", fcmd.CombinedOutputLog)
+	}
+
+	fcmd = fakeexec.FakeCmd{
+		CombinedOutputScript: []fakeexec.FakeAction{
+			func() ([]byte, []byte, error) { return []byte("c1\n"), nil, nil },
+			func() ([]byte, []byte, error) { return []byte("sha256:2\n"), nil, nil },
+			func() ([]byte, []byte, error) {
+				return []byte("sha256:1 k8s.gcr.io/pause:3.2\nsha256:2 nginx:1.19\nsha256:3 busybox:1.32\nsha256:3 busybox:latest\nsha256:4 <none>:<none>\n"), nil, nil
+			},
+			func() ([]byte, []byte, error) { return nil, nil, nil },
+			func() ([]byte, []byte, error) { return nil, nil, nil },
+		},
+	}
+	execer = fakeexec.FakeExec{
+		CommandScript: genFakeActions(&fcmd, 5),
+		LookPathFunc:  func(cmd string) (string, error) { return "/usr/bin/docker", nil },
+	}
+	runtime, err = NewContainerRuntime(&execer, DefaultDockerCRISocket)
+	if err != nil {
+		t.Fatalf("unexpected NewContainerRuntime error: %v", err)
+	}
+	if err = runtime.PruneImages(keep); err != nil {
+		t.Fatalf("unexpected PruneImages error: %v", err)
+	}
+	want := [][]string{
+		{"docker", "ps", "-a", "-q"},
+		{"docker", "inspect", "--format", "{{.Image}}", "c1"},
+		{"docker", "images", "--no-trunc", "--format", "{{.ID}} {{.Repository}}:{{.Tag}}"},
+		{"docker", "rmi", "busybox:1.32", "busybox:latest"},
+		{"docker", "rmi", "sha256:4"},
+	}
+	if !reflect.DeepEqual(fcmd.CombinedOutputLog, want) {
+		t.Errorf("unexpected docker commands: %v, want %v", fcmd.CombinedOutputLog, want)
+	}
+}
+
 func TestIsExistingSocket(t *testing.T) {
 	// this test is not expected to work on Windows
 	if runtime.GOOS == "windows" {
@@ -461,3 +561,37 @@ func TestDetectCRISocketImpl(t *testing.T) {
 		})
 	}
 }
+
+func TestCRIOCGroupDriver(t *testing.T) {
+	fcmd := fakeexec.FakeCmd{
+		CombinedOutputScript: []fakeexec.FakeAction{
+			// crictl info, crio config
+			func() ([]byte, []byte, error) { return nil, nil, nil },
+			func() ([]byte, []byte, error) {
+				return []byte("[crio.runtime]\n# comment\ncgroup_manager = \"cgroupfs\"\n"), nil, nil
+			},
+			func() ([]byte, []byte, error) { return nil, nil, nil },
+			func() ([]byte, []byte, error) { return []byte("[crio.runtime]\n"), nil, nil },
+		},
+	}
+	execer := fakeexec.FakeExec{
+		CommandScript: genFakeActions(&fcmd, len(fcmd.CombinedOutputScript)),
+		LookPathFunc:  func(cmd string) (string, error) { return crlctlBin, nil },
+	}
+	for _, want := range []string{DefaultCgroupDriver, DefaultSystemdCgroupDriver} {
+		runtime, err := NewContainerRuntime(&execer, CRISocket(CRIO))
+		if err != nil {
+			t.Fatalf("unexpected NewContainerRuntime error: %v", err)
+		}
+		if _, ok := runtime.(*CRIORuntime); !ok {
+			t.Fatalf("runtime of %s is %T, want *CRIORuntime", CRISocket(CRIO), runtime)
+		}
+		driver, err := runtime.CGroupDriver()
+		if err != nil {
+			t.Fatalf("unexpected CGroupDriver error: %v", err)
+		}
+		if driver != want {
+			t.Errorf("CGroupDriver() = %s, want %s", driver, want)
+		}
+	}
+}
@@ -25,6 +25,7 @@ import (
 const (
 	dockerSocket     = "/var/run/docker.sock" // The Docker socket is not CRI compatible
 	containerdSocket = "/run/containerd/containerd.sock"
+	crioSocket       = "/var/run/crio/crio.sock"
 )
 
 // isExistingSocket checks if path exists and is domain socket
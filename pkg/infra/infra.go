@@ -45,10 +45,10 @@ func loadConfig(infra *v2.Infra) {
 	}
 }
 
-func newAliProvider(infra *v2.Infra) (Interface, error) {
+func newAliProvider(infra *v2.Infra, apiServerPort int) (Interface, error) {
 	aliProvider := new(aliyun.AliProvider)
 	aliProvider.Infra = infra
-	if err := v2.Default(aliProvider.Infra, aliyun.DefaultInfra); err != nil {
+	if err := v2.Default(aliProvider.Infra, apiServerPort, aliyun.DefaultInfra); err != nil {
 		return nil, err
 	}
 	if err := validation.ValidateInfra(aliProvider.Infra, aliyun.DefaultValidate); len(err) != 0 {
@@ -60,10 +60,10 @@ func newAliProvider(infra *v2.Infra) (Interface, error) {
 	return aliProvider, nil
 }
 
-func newHwProvider(infra *v2.Infra) (Interface, error) {
+func newHwProvider(infra *v2.Infra, apiServerPort int) (Interface, error) {
 	hwProvider := new(huawei.HwProvider)
 	hwProvider.Infra = infra
-	if err := v2.Default(hwProvider.Infra, huawei.DefaultInfra); err != nil {
+	if err := v2.Default(hwProvider.Infra, apiServerPort, huawei.DefaultInfra); err != nil {
 		return nil, err
 	}
 	if err := validation.ValidateInfra(hwProvider.Infra, huawei.DefaultValidate); len(err) != 0 {
@@ -75,13 +75,14 @@ func newHwProvider(infra *v2.Infra) (Interface, error) {
 	return hwProvider, nil
 }
 
-func NewDefaultProvider(infra *v2.Infra) (Interface, error) {
+// NewDefaultProvider return the provider of the infra, the security group exports apiServerPort, the apiserverPort of the sealos config.
+func NewDefaultProvider(infra *v2.Infra, apiServerPort int) (Interface, error) {
 	loadConfig(infra)
 	switch infra.Spec.Provider {
 	case aliyun.AliyunProvider:
-		return newAliProvider(infra)
+		return newAliProvider(infra, apiServerPort)
 	case huawei.HuaweiProvider:
-		return newHwProvider(infra)
+		return newHwProvider(infra, apiServerPort)
 	default:
 		return nil, fmt.Errorf("the provider is invalid, please set the provider correctly")
 	}
@@ -69,7 +69,7 @@ func TestAliApply(t *testing.T) {
 		},
 	}
 
-	aliProvider, err := NewDefaultProvider(&infra)
+	aliProvider, err := NewDefaultProvider(&infra, v2.DefaultAPIServerPort)
 	if err != nil {
 		fmt.Printf("%v", err)
 	} else {
@@ -167,7 +167,7 @@ func TestHuaweiApply(t *testing.T) {
 		},
 	}
 
-	hwProvider, err := NewDefaultProvider(&infra)
+	hwProvider, err := NewDefaultProvider(&infra, v2.DefaultAPIServerPort)
 	if err != nil {
 		fmt.Printf("%v", err)
 		return
@@ -17,14 +17,17 @@ package install
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/fanux/sealos/pkg/logger"
 
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+
 	"github.com/fanux/sealos/pkg/kubernetes/cert"
 )
 
 // CMD return sealos cert command
-func CMD(altNames []string, hostIP, hostName, serviceCIRD, DNSDomain string) string {
+func CMD(altNames []string, hostIP, hostName, serviceCIRD, DNSDomain string, caValidity, certValidity time.Duration, keyAlgorithm string) string {
 	cmd := "sealos cert "
 	if hostIP != "" {
 		cmd += fmt.Sprintf(" --node-ip %s", hostIP)
@@ -48,12 +51,59 @@ func CMD(altNames []string, hostIP, hostName, serviceCIRD, DNSDomain string) str
 		}
 	}
 
+	if caValidity != 0 {
+		cmd += fmt.Sprintf(" --ca-validity %s", caValidity)
+	}
+
+	if certValidity != 0 {
+		cmd += fmt.Sprintf(" --cert-validity %s", certValidity)
+	}
+
+	if keyAlgorithm != "" {
+		cmd += fmt.Sprintf(" --key-algorithm %s", keyAlgorithm)
+	}
+
 	return cmd
 }
 
+// NewCertOptions return the cert options, keyAlgorithm is rsa or ecdsa.
+func NewCertOptions(caValidity, certValidity time.Duration, keyAlgorithm string) (cert.CertOptions, error) {
+	algorithm, err := cert.ParseKeyAlgorithm(keyAlgorithm)
+	if err != nil {
+		return cert.CertOptions{}, err
+	}
+	options := cert.CertOptions{
+		CAValidity:   caValidity,
+		CertValidity: certValidity,
+		KeyAlgorithm: algorithm,
+	}
+	return options, options.Validate()
+}
+
+// CheckCA validate the root CA or the pre-created CAs specified by user, it must be called before touching any host.
+func CheckCA() error {
+	if v1.CADir != "" && (v1.RootCACert != "" || v1.RootCAKey != "") {
+		return fmt.Errorf("--ca-dir and --root-ca-cert/--root-ca-key can not be used together")
+	}
+	if (v1.RootCACert == "") != (v1.RootCAKey == "") {
+		return fmt.Errorf("--root-ca-cert and --root-ca-key must be specified together")
+	}
+	if v1.CADir != "" {
+		return cert.ValidateCADir(v1.CADir)
+	}
+	if v1.RootCACert != "" {
+		rootCA, err := cert.LoadRootCA(v1.RootCACert, v1.RootCAKey)
+		if err != nil {
+			return err
+		}
+		return rootCA.CheckExistingCAs(v1.CertPath, v1.CertEtcdPath)
+	}
+	return nil
+}
+
 // GenerateCert generate all cert.
-func GenerateCert(certPATH, certEtcdPATH string, altNames []string, hostIP, hostName, serviceCIRD, DNSDomain string) {
-	certConfig, err := cert.NewSealosCertMetaData(certPATH, certEtcdPATH, altNames, serviceCIRD, hostName, hostIP, DNSDomain)
+func GenerateCert(certPATH, certEtcdPATH string, altNames []string, hostIP, hostName, serviceCIRD, DNSDomain string, options cert.CertOptions) {
+	certConfig, err := cert.NewSealosCertMetaData(certPATH, certEtcdPATH, altNames, serviceCIRD, hostName, hostIP, DNSDomain, options)
 	if err != nil {
 		logger.Error("generator cert config failed %s", err)
 		os.Exit(-1)
@@ -0,0 +1,256 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fanux/sealos/pkg/logger"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+	"github.com/fanux/sealos/pkg/utils/ssh"
+
+	"github.com/fanux/sealos/pkg/kubernetes/apiclient"
+	"github.com/fanux/sealos/pkg/kubernetes/cert"
+	"github.com/fanux/sealos/pkg/kubernetes/nodeclient"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+const (
+	KubeletClientCertFile = "/var/lib/kubelet/pki/kubelet-client-current.pem"
+	KubeEtcdComponent     = "etcd"
+	// StaticPodRestartLabel is changed to make kubelet recreate the static pod.
+	StaticPodRestartLabel = "sealos.io/restarted-at"
+)
+
+var (
+	CertRenewTimeout = 5 * time.Minute
+
+	// kubeConfigFiles is the kubeconfig files renew with the leaf certs, kubelet.conf is rotated by kubelet.
+	kubeConfigFiles = []string{"admin.conf", "controller-manager.conf", "scheduler.conf"}
+)
+
+// CertInfo is the cert info on the master.
+type CertInfo struct {
+	Host     string
+	Name     string
+	Subject  string
+	SANs     []string
+	NotAfter time.Time
+	Error    string
+}
+
+type CertFlags struct {
+	Client *kubernetes.Clientset
+	v1.SealConfig
+}
+
+// GetCertFlags load the sealos config, exit if failed.
+func GetCertFlags(cfgFile string) *CertFlags {
+	c := &CertFlags{}
+	if err := c.Load(cfgFile); err != nil {
+		logger.Error(err)
+		c.ShowDefaultConfig()
+		os.Exit(0)
+	}
+	return c
+}
+
+// Check read the certs and kubeconfigs on every master.
+func (c *CertFlags) Check() []CertInfo {
+	var infos []CertInfo
+	for _, master := range c.Masters {
+		infos = append(infos, remoteCertInfos(master)...)
+	}
+	return infos
+}
+
+func remoteCertInfos(host string) []CertInfo {
+	var infos []CertInfo
+	files := v1.SSHConfig.CmdToString(host, fmt.Sprintf("find %s -name '*.crt' | sort", cert.KubeDefaultCertPath), "\n")
+	for _, f := range strings.Fields(files) {
+		name, _ := filepath.Rel(cert.KubeDefaultCertPath, f)
+		infos = append(infos, parseCertInfos(host, name, []byte(v1.SSHConfig.CmdToString(host, "cat "+f, "\n")))...)
+	}
+	for _, kubeConfigFile := range append(kubeConfigFiles, "kubelet.conf") {
+		f := filepath.Join(cert.KubernetesDir, kubeConfigFile)
+		config, err := clientcmd.Load([]byte(v1.SSHConfig.CmdToString(host, "cat "+f, "\n")))
+		if err != nil {
+			infos = append(infos, CertInfo{Host: host, Name: kubeConfigFile, Error: err.Error()})
+			continue
+		}
+		for _, authInfo := range config.AuthInfos {
+			if len(authInfo.ClientCertificateData) != 0 {
+				infos = append(infos, parseCertInfos(host, kubeConfigFile, authInfo.ClientCertificateData)...)
+			}
+		}
+	}
+	if v1.SSHConfig.IsFileExist(host, KubeletClientCertFile) {
+		infos = append(infos, parseCertInfos(host, filepath.Base(KubeletClientCertFile), []byte(v1.SSHConfig.CmdToString(host, "cat "+KubeletClientCertFile, "\n")))...)
+	}
+	return infos
+}
+
+func parseCertInfos(host, name string, data []byte) []CertInfo {
+	certs, err := certutil.ParseCertsPEM(data)
+	if err != nil {
+		return []CertInfo{{Host: host, Name: name, Error: err.Error()}}
+	}
+	// only show the first cert of the bundle, it is the cert itself.
+	return []CertInfo{newCertInfo(host, name, certs[0])}
+}
+
+func newCertInfo(host, name string, c *x509.Certificate) CertInfo {
+	info := CertInfo{
+		Host:     host,
+		Name:     name,
+		Subject:  c.Subject.String(),
+		NotAfter: c.NotAfter,
+	}
+	info.SANs = append(info.SANs, c.DNSNames...)
+	for _, ip := range c.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+	sort.Strings(info.SANs)
+	return info
+}
+
+// PrintCertInfos print cert infos as table.
+func PrintCertInfos(w io.Writer, infos []CertInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tCERT\tSUBJECT\tEXPIRES\tRESIDUAL\tSANS\tERROR")
+	for _, i := range infos {
+		var expires, residual string
+		if !i.NotAfter.IsZero() {
+			expires = i.NotAfter.Format(time.RFC3339)
+			residual = fmt.Sprintf("%dd", int(time.Until(i.NotAfter).Hours()/24))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", i.Host, i.Name, i.Subject, expires, residual, strings.Join(i.SANs, ","), i.Error)
+	}
+	return tw.Flush()
+}
+
+// Renew regenerate the leaf certs and kubeconfigs by the CAs in sealos pki dir,
+// and restart the control-plane static pods one master at a time.
+func (c *CertFlags) Renew() error {
+	if err := c.initClient(); err != nil {
+		return err
+	}
+	for _, master := range c.Masters {
+		if err := c.renewMaster(master); err != nil {
+			return fmt.Errorf("[%s] renew certs failed: %w", master, err)
+		}
+		logger.Info("[%s] renew certs success", master)
+	}
+	return nil
+}
+
+func (c *CertFlags) initClient() error {
+	if !utils.FileExist(nodeclient.KubeDefaultConfigPath) {
+		return fmt.Errorf("KubeDefaultConfigPath %s is not exist", nodeclient.KubeDefaultConfigPath)
+	}
+	var err error
+	c.Client, err = nodeclient.NewClient(nodeclient.KubeDefaultConfigPath, nil)
+	if err != nil {
+		return fmt.Errorf("get k8s client err: %w", err)
+	}
+	return nil
+}
+
+func (c *CertFlags) renewMaster(master string) error {
+	ip := utils.IPFormat(master)
+	hostname := ssh.RemoteHostName(v1.SSHConfig, master)
+	tmpDir, err := ioutil.TempDir("", "sealos-cert-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	options, err := NewCertOptions(v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm)
+	if err != nil {
+		return err
+	}
+	pki := filepath.Join(tmpDir, "pki")
+	meta, err := cert.NewSealosCertMetaData(pki, filepath.Join(pki, "etcd"), c.APIServerCertSANs, c.SvcCIDR, hostname, ip, c.DNSDomain, options)
+	if err != nil {
+		return err
+	}
+	if err = meta.RenewAll(v1.CertPath, v1.CertEtcdPath); err != nil {
+		return err
+	}
+	certConfig := cert.Config{
+		Path:         v1.CertPath,
+		BaseName:     "ca",
+		Validity:     options.CertValidity,
+		KeyAlgorithm: options.KeyAlgorithm,
+	}
+	controlPlaneEndpoint := fmt.Sprintf("https://%s:%d", c.APIServerDomain, c.LoadBalancer.Port)
+	for _, kubeConfigFile := range kubeConfigFiles {
+		if err = cert.CreateKubeConfigFile(kubeConfigFile, tmpDir, certConfig, hostname, controlPlaneEndpoint, "kubernetes"); err != nil {
+			return err
+		}
+	}
+
+	v1.SSHConfig.CopyLocalToRemote(master, pki, cert.KubeDefaultCertPath)
+	for _, kubeConfigFile := range kubeConfigFiles {
+		v1.SSHConfig.CopyLocalToRemote(master, filepath.Join(tmpDir, kubeConfigFile), filepath.Join(cert.KubernetesDir, kubeConfigFile))
+	}
+	to11911192([]string{master})
+	_ = v1.SSHConfig.CmdAsync(master, "cp -f /etc/kubernetes/admin.conf /root/.kube/config && chmod 600 /root/.kube/config")
+
+	return restartStaticPods(c.Client, master, hostname, append([]string{KubeEtcdComponent}, apiclient.ControlPlaneComponents...))
+}
+
+// restartStaticPods restart the static pods on host by component and wait for the mirror pods updated.
+func restartStaticPods(client *kubernetes.Clientset, host, nodeName string, components []string) error {
+	waiter := apiclient.NewKubeWaiter(client, CertRenewTimeout, os.Stdout)
+	for _, component := range components {
+		hash, err := waiter.WaitForStaticPodSingleHash(nodeName, component)
+		if err != nil {
+			return fmt.Errorf("get %s static pod hash err: %w", component, err)
+		}
+		logger.Info("[%s] restart static pod %s", host, component)
+		if err = v1.SSHConfig.CmdAsync(host, restartStaticPodCmd(component, time.Now().Unix())); err != nil {
+			return fmt.Errorf("restart %s static pod err: %w", component, err)
+		}
+		if err = waiter.WaitForStaticPodHashChange(nodeName, component, hash); err != nil {
+			return fmt.Errorf("wait for %s static pod restart err: %w", component, err)
+		}
+		if err = waiter.WaitForPodsWithLabel("component=" + component); err != nil {
+			return fmt.Errorf("wait for %s static pod ready err: %w", component, err)
+		}
+	}
+	return nil
+}
+
+// restartStaticPodCmd update the restart label of the static pod manifest, kubelet will recreate it.
+func restartStaticPodCmd(component string, timestamp int64) string {
+	manifest := fmt.Sprintf("/etc/kubernetes/manifests/%s.yaml", component)
+	return fmt.Sprintf(`sed -i '\|%s|d' %s && sed -i 's|^\(\s*\)tier: control-plane$|&\n\1%s: "%d"|' %s`,
+		StaticPodRestartLabel, manifest, StaticPodRestartLabel, timestamp, manifest)
+}
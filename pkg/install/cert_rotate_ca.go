@@ -0,0 +1,590 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fanux/sealos/pkg/logger"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+	"github.com/fanux/sealos/pkg/utils/ssh"
+
+	"github.com/fanux/sealos/pkg/kubernetes/apiclient"
+	"github.com/fanux/sealos/pkg/kubernetes/cert"
+	"github.com/fanux/sealos/pkg/kubernetes/nodeclient"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/keyutil"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// RotateCAPhaseTrust distribute the CA bundles trusting the old and new CAs, the old CAs still sign.
+	RotateCAPhaseTrust = "trust"
+	// RotateCAPhaseReissue reissue the leaf certs, kubeconfigs and kubelet client certs by the new CAs.
+	RotateCAPhaseReissue = "reissue"
+	// RotateCAPhaseDrop drop the old CAs from the bundles.
+	RotateCAPhaseDrop = "drop"
+
+	KubeletClientCertDir = "/var/lib/kubelet/pki"
+	kubeletConfFile      = "kubelet.conf"
+)
+
+var (
+	RotateCAPhases = []string{RotateCAPhaseTrust, RotateCAPhaseReissue, RotateCAPhaseDrop}
+	// CertRotateCADir keep the old and new CAs and the progress during rotation.
+	CertRotateCADir = v1.DefaultConfigPath + "/pki-rotate"
+
+	// rotateCAFiles is the CA files relative to the pki dir.
+	rotateCAFiles = []string{"ca", "front-proxy-ca", filepath.Join("etcd", "ca")}
+)
+
+// RotateCAState is the finished phases of the CA rotation.
+type RotateCAState struct {
+	Done []string `json:"done"`
+}
+
+func rotateCAStateFile() string {
+	return filepath.Join(CertRotateCADir, "state.yaml")
+}
+
+func loadRotateCAState() (*RotateCAState, error) {
+	state := &RotateCAState{}
+	data, err := ioutil.ReadFile(rotateCAStateFile())
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, yaml.Unmarshal(data, state)
+}
+
+func (s *RotateCAState) save() error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(rotateCAStateFile(), data, 0600)
+}
+
+// next return the next phase to run, empty if all phases are done.
+func (s *RotateCAState) next() string {
+	for _, phase := range RotateCAPhases {
+		if utils.NotIn(phase, s.Done) {
+			return phase
+		}
+	}
+	return ""
+}
+
+// RotateCA run the next phase of the CA rotation, or all the remaining phases if all is true.
+// The progress is saved after every phase, rerun it to resume from the failed phase.
+func (c *CertFlags) RotateCA(all bool) error {
+	state, err := loadRotateCAState()
+	if err != nil {
+		return fmt.Errorf("load rotate ca state err: %w", err)
+	}
+	if len(state.Done) == 0 {
+		if err = c.prepareRotateCA(); err != nil {
+			return fmt.Errorf("prepare new CAs err: %w", err)
+		}
+	}
+	for phase := state.next(); phase != ""; phase = state.next() {
+		logger.Info("rotate ca phase %s start", phase)
+		if err = c.rotateCAPhase(phase); err != nil {
+			return fmt.Errorf("rotate ca phase %s failed, rerun to resume: %w", phase, err)
+		}
+		state.Done = append(state.Done, phase)
+		if err = state.save(); err != nil {
+			return err
+		}
+		logger.Info("rotate ca phase %s success", phase)
+		if phase == RotateCAPhaseTrust {
+			logger.Warn("restart the pods using the service account ca.crt before the %s phase", RotateCAPhaseDrop)
+		}
+		if !all && state.next() != "" {
+			logger.Info("run sealos cert rotate-ca again for the next phase %s", state.next())
+			return nil
+		}
+	}
+	archive := fmt.Sprintf("%s-%d", CertRotateCADir, time.Now().Unix())
+	logger.Info("rotate ca finished, the old CAs are kept in %s", archive)
+	return os.Rename(CertRotateCADir, archive)
+}
+
+// prepareRotateCA backup the old CAs and generate the new CAs, signed by the root CA if configured.
+func (c *CertFlags) prepareRotateCA() error {
+	oldDir, newDir := filepath.Join(CertRotateCADir, "old"), filepath.Join(CertRotateCADir, "new")
+	if err := os.MkdirAll(CertRotateCADir, 0700); err != nil {
+		return err
+	}
+	if err := cert.CopyCAs(v1.CertPath, v1.CertEtcdPath, oldDir, filepath.Join(oldDir, "etcd")); err != nil {
+		return err
+	}
+	options, err := NewCertOptions(v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm)
+	if err != nil {
+		return err
+	}
+	if v1.RootCACert != "" {
+		if options.RootCA, err = cert.LoadRootCA(v1.RootCACert, v1.RootCAKey); err != nil {
+			return err
+		}
+	}
+	meta, err := cert.NewSealosCertMetaData(newDir, filepath.Join(newDir, "etcd"), c.APIServerCertSANs, c.SvcCIDR, "", "", c.DNSDomain, options)
+	if err != nil {
+		return err
+	}
+	// the new CAs on disk are kept, so it is safe to prepare again.
+	return meta.GenerateCAs()
+}
+
+// caBundle return the CA bundle of the phase, the first cert of the bundle is the signing CA.
+func caBundle(phase, name string) ([]byte, error) {
+	oldCA, err := ioutil.ReadFile(filepath.Join(CertRotateCADir, "old", name+".crt"))
+	if err != nil {
+		return nil, err
+	}
+	newCA, err := ioutil.ReadFile(filepath.Join(CertRotateCADir, "new", name+".crt"))
+	if err != nil {
+		return nil, err
+	}
+	switch phase {
+	case RotateCAPhaseTrust:
+		return append(oldCA, newCA...), nil
+	case RotateCAPhaseReissue:
+		return append(newCA, oldCA...), nil
+	default:
+		return newCA, nil
+	}
+}
+
+func (c *CertFlags) rotateCAPhase(phase string) error {
+	caData, err := caBundle(phase, "ca")
+	if err != nil {
+		return err
+	}
+	// sealos must trust the apiserver cert signed by both CAs during rotation.
+	if phase == RotateCAPhaseTrust {
+		if err = updateKubeConfigFile(nodeclient.KubeDefaultConfigPath, kubeConfigCA(caData)); err != nil {
+			return err
+		}
+	}
+	if err = c.initClient(); err != nil {
+		return err
+	}
+	for _, master := range c.Masters {
+		if err = c.rotateCAMaster(phase, master, caData); err != nil {
+			return fmt.Errorf("[%s] %w", master, err)
+		}
+	}
+	for _, node := range c.Nodes {
+		if err = c.rotateCANode(phase, node, caData); err != nil {
+			return fmt.Errorf("[%s] %w", node, err)
+		}
+	}
+	if err = updateServiceAccountCA(c.Client, caData); err != nil {
+		return err
+	}
+	if err = updateClusterInfoCA(c.Client, caData); err != nil {
+		return err
+	}
+	switch phase {
+	case RotateCAPhaseReissue:
+		return c.reissueLocalKubeConfigs(caData)
+	case RotateCAPhaseDrop:
+		return c.dropLocalCAs(caData)
+	}
+	return nil
+}
+
+// stageCABundles write the CA bundles of the phase to pki dir, and the new CA keys for the reissue phase.
+func stageCABundles(phase, pki string) error {
+	for _, name := range rotateCAFiles {
+		data, err := caBundle(phase, name)
+		if err != nil {
+			return err
+		}
+		if err = os.MkdirAll(filepath.Dir(filepath.Join(pki, name)), 0755); err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(filepath.Join(pki, name+".crt"), data, 0644); err != nil {
+			return err
+		}
+		if phase != RotateCAPhaseReissue {
+			continue
+		}
+		key, err := ioutil.ReadFile(filepath.Join(CertRotateCADir, "new", name+".key"))
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(filepath.Join(pki, name+".key"), key, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *CertFlags) rotateCAMaster(phase, master string, caData []byte) error {
+	ip := utils.IPFormat(master)
+	hostname := ssh.RemoteHostName(v1.SSHConfig, master)
+	tmpDir, err := ioutil.TempDir("", "sealos-rotate-ca-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pki := filepath.Join(tmpDir, "pki")
+	if err = stageCABundles(phase, pki); err != nil {
+		return err
+	}
+	newDir := filepath.Join(CertRotateCADir, "new")
+	if phase == RotateCAPhaseReissue {
+		options, err := NewCertOptions(v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm)
+		if err != nil {
+			return err
+		}
+		meta, err := cert.NewSealosCertMetaData(pki, filepath.Join(pki, "etcd"), c.APIServerCertSANs, c.SvcCIDR, hostname, ip, c.DNSDomain, options)
+		if err != nil {
+			return err
+		}
+		if err = meta.RenewAll(newDir, filepath.Join(newDir, "etcd")); err != nil {
+			return err
+		}
+		certConfig := cert.Config{
+			Path:         newDir,
+			BaseName:     "ca",
+			Validity:     options.CertValidity,
+			KeyAlgorithm: options.KeyAlgorithm,
+		}
+		controlPlaneEndpoint := fmt.Sprintf("https://%s:%d", c.APIServerDomain, c.LoadBalancer.Port)
+		for _, kubeConfigFile := range kubeConfigFiles {
+			if err = cert.CreateKubeConfigFile(kubeConfigFile, tmpDir, certConfig, hostname, controlPlaneEndpoint, "kubernetes"); err != nil {
+				return err
+			}
+			if err = updateKubeConfigFile(filepath.Join(tmpDir, kubeConfigFile), kubeConfigCA(caData)); err != nil {
+				return err
+			}
+		}
+	}
+
+	v1.SSHConfig.CopyLocalToRemote(master, pki, cert.KubeDefaultCertPath)
+	for _, kubeConfigFile := range kubeConfigFiles {
+		remote := filepath.Join(cert.KubernetesDir, kubeConfigFile)
+		if phase == RotateCAPhaseReissue {
+			v1.SSHConfig.CopyLocalToRemote(master, filepath.Join(tmpDir, kubeConfigFile), remote)
+			continue
+		}
+		if err = updateRemoteKubeConfig(master, remote, kubeConfigCA(caData)); err != nil {
+			return err
+		}
+	}
+	if phase == RotateCAPhaseReissue {
+		to11911192([]string{master})
+	}
+	_ = v1.SSHConfig.CmdAsync(master, "cp -f /etc/kubernetes/admin.conf /root/.kube/config && chmod 600 /root/.kube/config")
+
+	if err = restartStaticPods(c.Client, master, hostname, append([]string{KubeEtcdComponent}, apiclient.ControlPlaneComponents...)); err != nil {
+		return err
+	}
+	return c.rotateCAKubelet(phase, master, hostname, caData)
+}
+
+func (c *CertFlags) rotateCANode(phase, node string, caData []byte) error {
+	hostname := ssh.RemoteHostName(v1.SSHConfig, node)
+	tmpDir, err := ioutil.TempDir("", "sealos-rotate-ca-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	caFile := filepath.Join(tmpDir, "ca.crt")
+	if err = ioutil.WriteFile(caFile, caData, 0644); err != nil {
+		return err
+	}
+	v1.SSHConfig.CopyLocalToRemote(node, caFile, filepath.Join(cert.KubeDefaultCertPath, "ca.crt"))
+	return c.rotateCAKubelet(phase, node, hostname, caData)
+}
+
+// rotateCAKubelet update the CA of kubelet.conf, issue the kubelet client cert by the new CA in the reissue phase,
+// then restart kubelet and wait for the node ready.
+func (c *CertFlags) rotateCAKubelet(phase, host, nodeName string, caData []byte) error {
+	current := filepath.Join(KubeletClientCertDir, "kubelet-client-current.pem")
+	if phase == RotateCAPhaseReissue {
+		pem, err := kubeletClientPEM(nodeName)
+		if err != nil {
+			return err
+		}
+		tmpFile, err := ioutil.TempFile("", "kubelet-client-")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmpFile.Name())
+		if _, err = tmpFile.Write(pem); err != nil {
+			return err
+		}
+		_ = tmpFile.Close()
+		remote := filepath.Join(KubeletClientCertDir, fmt.Sprintf("kubelet-client-sealos-%d.pem", time.Now().Unix()))
+		v1.SSHConfig.CopyLocalToRemote(host, tmpFile.Name(), remote)
+		if err = v1.SSHConfig.CmdAsync(host, fmt.Sprintf("chmod 600 %s && ln -sf %s %s", remote, remote, current)); err != nil {
+			return err
+		}
+	}
+	err := updateRemoteKubeConfig(host, filepath.Join(cert.KubernetesDir, kubeletConfFile), func(config *clientcmdapi.Config) {
+		kubeConfigCA(caData)(config)
+		// kubelet.conf generated by sealos embeds the client cert, use the rotated cert file like kubeadm.
+		for _, authInfo := range config.AuthInfos {
+			authInfo.ClientCertificateData, authInfo.ClientKeyData = nil, nil
+			authInfo.ClientCertificate, authInfo.ClientKey = current, current
+		}
+	})
+	if err != nil {
+		return err
+	}
+	since, err := remoteTime(host)
+	if err != nil {
+		return err
+	}
+	if err = v1.SSHConfig.CmdAsync(host, "systemctl restart kubelet"); err != nil {
+		return err
+	}
+	return waitNodeReady(c.Client, nodeName, since, CertRenewTimeout)
+}
+
+// kubeletClientPEM issue the kubelet client cert and key of the node by the new CA.
+func kubeletClientPEM(nodeName string) ([]byte, error) {
+	options, err := NewCertOptions(v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	caCert, caKey, err := cert.LoadCaCertAndKeyFromDisk(cert.Config{Path: filepath.Join(CertRotateCADir, "new"), BaseName: "ca"})
+	if err != nil {
+		return nil, err
+	}
+	clientCert, clientKey, err := cert.NewCaCertAndKeyFromRoot(cert.Config{
+		CommonName:   "system:node:" + nodeName,
+		Organization: []string{"system:nodes"},
+		Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		Validity:     options.CertValidity,
+		KeyAlgorithm: options.KeyAlgorithm,
+	}, caCert, caKey)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := keyutil.MarshalPrivateKeyToPEM(clientKey)
+	if err != nil {
+		return nil, err
+	}
+	return append(cert.EncodeCertPEM(clientCert), keyPEM...), nil
+}
+
+// reissueLocalKubeConfigs regenerate the sealos kubeconfigs by the new CA.
+func (c *CertFlags) reissueLocalKubeConfigs(caData []byte) error {
+	tmpDir, err := ioutil.TempDir("", "sealos-rotate-ca-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	options, err := NewCertOptions(v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm)
+	if err != nil {
+		return err
+	}
+	certConfig := cert.Config{
+		Path:         filepath.Join(CertRotateCADir, "new"),
+		BaseName:     "ca",
+		Validity:     options.CertValidity,
+		KeyAlgorithm: options.KeyAlgorithm,
+	}
+	hostname := ssh.RemoteHostName(v1.SSHConfig, c.Masters[0])
+	controlPlaneEndpoint := fmt.Sprintf("https://%s:%d", c.APIServerDomain, c.LoadBalancer.Port)
+	if err = cert.CreateJoinControlPlaneKubeConfigFiles(tmpDir, certConfig, hostname, controlPlaneEndpoint, "kubernetes"); err != nil {
+		return err
+	}
+	for _, kubeConfigFile := range append(kubeConfigFiles, kubeletConfFile) {
+		f := filepath.Join(tmpDir, kubeConfigFile)
+		if err = updateKubeConfigFile(f, kubeConfigCA(caData)); err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		if err = ioutil.WriteFile(filepath.Join(v1.DefaultConfigPath, kubeConfigFile), data, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropLocalCAs replace the CAs in sealos pki dir with the new CAs, and reissue the local leaf certs.
+func (c *CertFlags) dropLocalCAs(caData []byte) error {
+	newDir := filepath.Join(CertRotateCADir, "new")
+	if err := cert.CopyCAs(newDir, filepath.Join(newDir, "etcd"), v1.CertPath, v1.CertEtcdPath); err != nil {
+		return err
+	}
+	options, err := NewCertOptions(v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm)
+	if err != nil {
+		return err
+	}
+	hostname := ssh.RemoteHostName(v1.SSHConfig, c.Masters[0])
+	meta, err := cert.NewSealosCertMetaData(v1.CertPath, v1.CertEtcdPath, c.APIServerCertSANs, c.SvcCIDR, hostname, utils.IPFormat(c.Masters[0]), c.DNSDomain, options)
+	if err != nil {
+		return err
+	}
+	if err = meta.RenewAll(v1.CertPath, v1.CertEtcdPath); err != nil {
+		return err
+	}
+	for _, kubeConfigFile := range append(kubeConfigFiles, kubeletConfFile) {
+		if err = updateKubeConfigFile(filepath.Join(v1.DefaultConfigPath, kubeConfigFile), kubeConfigCA(caData)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kubeConfigCA set the CA data of all clusters in kubeconfig.
+func kubeConfigCA(caData []byte) func(*clientcmdapi.Config) {
+	return func(config *clientcmdapi.Config) {
+		for _, cluster := range config.Clusters {
+			cluster.CertificateAuthority = ""
+			cluster.CertificateAuthorityData = caData
+		}
+	}
+}
+
+func updateKubeConfigFile(file string, fn func(*clientcmdapi.Config)) error {
+	config, err := clientcmd.LoadFromFile(file)
+	if err != nil {
+		return err
+	}
+	fn(config)
+	return clientcmd.WriteToFile(*config, file)
+}
+
+func updateRemoteKubeConfig(host, file string, fn func(*clientcmdapi.Config)) error {
+	config, err := clientcmd.Load([]byte(v1.SSHConfig.CmdToString(host, "cat "+file, "\n")))
+	if err != nil {
+		return fmt.Errorf("load %s err: %w", file, err)
+	}
+	fn(config)
+	tmpFile, err := ioutil.TempFile("", "kubeconfig-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	_ = tmpFile.Close()
+	if err = clientcmd.WriteToFile(*config, tmpFile.Name()); err != nil {
+		return err
+	}
+	v1.SSHConfig.CopyLocalToRemote(host, tmpFile.Name(), file)
+	return nil
+}
+
+// updateServiceAccountCA update the ca.crt of the service account token secrets,
+// the kube-root-ca.crt ConfigMaps are published by controller-manager from its ca.crt.
+func updateServiceAccountCA(client *kubernetes.Clientset, caData []byte) error {
+	secrets, err := client.CoreV1().Secrets(metav1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: "type=" + string(corev1.SecretTypeServiceAccountToken),
+	})
+	if err != nil {
+		return fmt.Errorf("list service account token secrets err: %w", err)
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if bytes.Equal(secret.Data[corev1.ServiceAccountRootCAKey], caData) {
+			continue
+		}
+		secret.Data[corev1.ServiceAccountRootCAKey] = caData
+		if _, err = client.CoreV1().Secrets(secret.Namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("update secret %s/%s err: %w", secret.Namespace, secret.Name, err)
+		}
+	}
+	return nil
+}
+
+// updateClusterInfoCA update the CA of the cluster-info ConfigMap, which the joining nodes discover the cluster by,
+// kubeadm token create hash the ca.crt on disk, so the discovery must serve the same CA.
+func updateClusterInfoCA(client kubernetes.Interface, caData []byte) error {
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespacePublic).Get(context.TODO(), bootstrapapi.ConfigMapClusterInfo, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get configmap %s/%s err: %w", metav1.NamespacePublic, bootstrapapi.ConfigMapClusterInfo, err)
+	}
+	config, err := clientcmd.Load([]byte(cm.Data[bootstrapapi.KubeConfigKey]))
+	if err != nil {
+		return fmt.Errorf("load the kubeconfig of %s err: %w", bootstrapapi.ConfigMapClusterInfo, err)
+	}
+	kubeConfigCA(caData)(config)
+	data, err := clientcmd.Write(*config)
+	if err != nil {
+		return err
+	}
+	if cm.Data[bootstrapapi.KubeConfigKey] == string(data) {
+		return nil
+	}
+	// the bootstrap signer of kube-controller-manager re-sign the jws of the tokens.
+	cm.Data[bootstrapapi.KubeConfigKey] = string(data)
+	if _, err = client.CoreV1().ConfigMaps(metav1.NamespacePublic).Update(context.TODO(), cm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("update configmap %s/%s err: %w", metav1.NamespacePublic, bootstrapapi.ConfigMapClusterInfo, err)
+	}
+	return nil
+}
+
+// remoteTime return the current time of the host, the node status is stamped by the clock of the host.
+func remoteTime(host string) (time.Time, error) {
+	out, err := v1.SSHConfig.CmdOutput(host, "date +%s")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("get the time of %s err: %w", host, err)
+	}
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse the time of %s err: %w", host, err)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// waitNodeReady wait for the node Ready reported by kubelet after since, the time kubelet restarted,
+// so the Ready status before the restart is not taken.
+func waitNodeReady(client kubernetes.Interface, nodeName string, since time.Time, timeout time.Duration) error {
+	return wait.PollImmediate(apiclient.APICallRetryInterval, timeout, func() (bool, error) {
+		node, err := nodeclient.GetNodeByName(client, nodeName)
+		if err != nil {
+			return false, nil
+		}
+		for _, c := range node.Status.Conditions {
+			if c.Type == corev1.NodeReady {
+				return c.LastHeartbeatTime.After(since) && c.Status == corev1.ConditionTrue, nil
+			}
+		}
+		return false, nil
+	})
+}
@@ -0,0 +1,154 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	bootstrapapi "k8s.io/cluster-bootstrap/token/api"
+)
+
+func TestRotateCAState(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "sealos-rotate-ca-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	defer func(dir string) { CertRotateCADir = dir }(CertRotateCADir)
+	CertRotateCADir = tmpDir
+
+	state, err := loadRotateCAState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state.next(); got != RotateCAPhaseTrust {
+		t.Errorf("next() = %s, want %s", got, RotateCAPhaseTrust)
+	}
+	state.Done = append(state.Done, RotateCAPhaseTrust)
+	if err = state.save(); err != nil {
+		t.Fatal(err)
+	}
+	if state, err = loadRotateCAState(); err != nil {
+		t.Fatal(err)
+	}
+	if got := state.next(); got != RotateCAPhaseReissue {
+		t.Errorf("next() after resume = %s, want %s", got, RotateCAPhaseReissue)
+	}
+}
+
+func TestCABundle(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "sealos-rotate-ca-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	defer func(dir string) { CertRotateCADir = dir }(CertRotateCADir)
+	CertRotateCADir = tmpDir
+	for _, dir := range []string{"old", "new"} {
+		if err = os.MkdirAll(filepath.Join(tmpDir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filepath.Join(tmpDir, dir, "ca.crt"), []byte(dir+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		phase string
+		want  string
+	}{
+		{RotateCAPhaseTrust, "old\nnew\n"},
+		{RotateCAPhaseReissue, "new\nold\n"},
+		{RotateCAPhaseDrop, "new\n"},
+	}
+	for _, tt := range tests {
+		got, err := caBundle(tt.phase, "ca")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("caBundle(%s) = %q, want %q", tt.phase, got, tt.want)
+		}
+	}
+}
+
+func TestWaitNodeReady(t *testing.T) {
+	restarted := time.Now().Truncate(time.Second)
+	node := func(heartbeat time.Time) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue, LastHeartbeatTime: metav1.NewTime(heartbeat)},
+			}},
+		}
+	}
+	// the Ready status before the restart is stale.
+	client := fake.NewSimpleClientset(node(restarted.Add(-time.Minute)))
+	if err := waitNodeReady(client, "node1", restarted, time.Second); err == nil {
+		t.Errorf("waitNodeReady() should time out on the Ready heartbeated before %s", restarted)
+	}
+	client = fake.NewSimpleClientset(node(restarted.Add(time.Second)))
+	if err := waitNodeReady(client, "node1", restarted, time.Second); err != nil {
+		t.Errorf("waitNodeReady() err: %v", err)
+	}
+}
+
+func TestUpdateClusterInfoCA(t *testing.T) {
+	kubeConfig := `apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority-data: b2xkCg==
+    server: https://apiserver.cluster.local:6443
+  name: ""
+contexts: null
+current-context: ""
+preferences: {}
+users: null
+`
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: bootstrapapi.ConfigMapClusterInfo, Namespace: metav1.NamespacePublic},
+		Data:       map[string]string{bootstrapapi.KubeConfigKey: kubeConfig},
+	})
+	for _, caData := range [][]byte{[]byte("old\nnew\n"), []byte("new\n")} {
+		if err := updateClusterInfoCA(client, caData); err != nil {
+			t.Fatal(err)
+		}
+		cm, err := client.CoreV1().ConfigMaps(metav1.NamespacePublic).Get(context.TODO(), bootstrapapi.ConfigMapClusterInfo, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		config, err := clientcmd.Load([]byte(cm.Data[bootstrapapi.KubeConfigKey]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, cluster := range config.Clusters {
+			if string(cluster.CertificateAuthorityData) != string(caData) {
+				t.Errorf("cluster %q certificate-authority-data = %q, want %q", name, cluster.CertificateAuthorityData, caData)
+			}
+			if cluster.Server != "https://apiserver.cluster.local:6443" {
+				t.Errorf("cluster %q server = %s, want it kept", name, cluster.Server)
+			}
+		}
+	}
+}
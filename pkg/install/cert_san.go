@@ -0,0 +1,175 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/fanux/sealos/pkg/logger"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+	"github.com/fanux/sealos/pkg/utils/ssh"
+
+	"github.com/fanux/sealos/pkg/kubernetes/apiclient"
+	"github.com/fanux/sealos/pkg/kubernetes/cert"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	KubeadmConfigConfigMap  = "kubeadm-config"
+	ClusterConfigurationKey = "ClusterConfiguration"
+
+	// apiServerCertTmpDir is the dir on master to run sealos cert, only the apiserver cert is copied back.
+	apiServerCertTmpDir = "/tmp/sealos-apiserver-cert"
+)
+
+// AddSANs add the SANs to the apiserver cert of every master.
+func (c *CertFlags) AddSANs(cfgFile string, sans []string) error {
+	newSANs := append([]string{}, c.APIServerCertSANs...)
+	for _, san := range sans {
+		if utils.InList(san, newSANs) {
+			logger.Warn("SAN %s is already exist, skip", san)
+			continue
+		}
+		newSANs = append(newSANs, san)
+	}
+	return c.updateSANs(cfgFile, newSANs)
+}
+
+// RemoveSANs remove the SANs from the apiserver cert of every master,
+// the masters, vip and apiserver domain can not be removed.
+func (c *CertFlags) RemoveSANs(cfgFile string, sans []string) error {
+	protected := []string{"127.0.0.1", c.APIServerDomain, c.VIP}
+	for _, master := range c.Masters {
+		protected = append(protected, utils.IPFormat(master))
+	}
+	for _, san := range sans {
+		if utils.InList(san, protected) {
+			return fmt.Errorf("SAN %s is used by the cluster, can not be removed", san)
+		}
+	}
+	var newSANs []string
+	for _, san := range c.APIServerCertSANs {
+		if utils.NotIn(san, sans) {
+			newSANs = append(newSANs, san)
+		}
+	}
+	return c.updateSANs(cfgFile, newSANs)
+}
+
+// updateSANs regenerate the apiserver cert and restart apiserver one master at a time,
+// then store the SANs in sealos config and kubeadm-config ConfigMap.
+func (c *CertFlags) updateSANs(cfgFile string, sans []string) error {
+	if !sansChanged(c.APIServerCertSANs, sans) && !sansChanged(sans, c.APIServerCertSANs) {
+		logger.Info("apiserver cert SANs are not changed, skip")
+		return nil
+	}
+	for _, san := range sans {
+		if net.ParseIP(san) == nil && len(validation.IsDNS1123Subdomain(strings.TrimPrefix(san, "*."))) != 0 {
+			return fmt.Errorf("SAN %s is not a valid ip or dns name", san)
+		}
+	}
+	if _, err := NewCertOptions(v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm); err != nil {
+		return err
+	}
+	if err := c.initClient(); err != nil {
+		return err
+	}
+	for _, master := range c.Masters {
+		if err := c.updateMasterSANs(master, sans); err != nil {
+			return fmt.Errorf("[%s] update apiserver cert SANs failed: %w", master, err)
+		}
+		logger.Info("[%s] update apiserver cert SANs success", master)
+	}
+	err := apiclient.MutateConfigMap(c.Client, metav1.ObjectMeta{Namespace: metav1.NamespaceSystem, Name: KubeadmConfigConfigMap}, func(cm *corev1.ConfigMap) error {
+		data, err := updateClusterConfigurationSANs(cm.Data[ClusterConfigurationKey], sans)
+		if err != nil {
+			return err
+		}
+		cm.Data[ClusterConfigurationKey] = data
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("update %s ConfigMap err: %w", KubeadmConfigConfigMap, err)
+	}
+	v1.APIServerCertSANs = sans
+	c.Dump(cfgFile)
+	return nil
+}
+
+func (c *CertFlags) updateMasterSANs(master string, sans []string) error {
+	ip := utils.IPFormat(master)
+	hostname := ssh.RemoteHostName(v1.SSHConfig, master)
+	certCMD := CMD(sans, ip, hostname, c.SvcCIDR, c.DNSDomain, v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm)
+	if err := v1.SSHConfig.CmdAsync(master, apiServerCertCmd(certCMD)); err != nil {
+		return err
+	}
+	// check the new cert before restart apiserver, the old apiserver is still serving.
+	apiServerCert := filepath.Join(cert.KubeDefaultCertPath, "apiserver.crt")
+	infos := parseCertInfos(master, "apiserver.crt", []byte(v1.SSHConfig.CmdToString(master, "cat "+apiServerCert, "\n")))
+	if infos[0].Error != "" {
+		return fmt.Errorf("read %s err: %s", apiServerCert, infos[0].Error)
+	}
+	for _, san := range sans {
+		if utils.NotIn(san, infos[0].SANs) {
+			return fmt.Errorf("SAN %s is not in the new apiserver cert", san)
+		}
+	}
+	return restartStaticPods(c.Client, master, hostname, []string{apiclient.KubeAPIServer})
+}
+
+// apiServerCertCmd run sealos cert with the existing CAs in a tmp dir, and replace the apiserver cert only.
+func apiServerCertCmd(certCMD string) string {
+	return fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s/etcd && cp %[2]s/ca.* %[2]s/front-proxy-ca.* %[2]s/sa.* %[1]s/ && cp %[2]s/etcd/ca.* %[1]s/etcd/ && "+
+		"%[3]s --cert-path %[1]s --cert-etcd-path %[1]s/etcd && cp -f %[1]s/apiserver.crt %[1]s/apiserver.key %[2]s/ && rm -rf %[1]s",
+		apiServerCertTmpDir, cert.KubeDefaultCertPath, certCMD)
+}
+
+// updateClusterConfigurationSANs replace the apiServer.certSANs of the kubeadm ClusterConfiguration.
+func updateClusterConfigurationSANs(data string, sans []string) (string, error) {
+	clusterConfiguration := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(data), &clusterConfiguration); err != nil {
+		return "", fmt.Errorf("unmarshal %s err: %w", ClusterConfigurationKey, err)
+	}
+	apiServer, ok := clusterConfiguration["apiServer"].(map[string]interface{})
+	if !ok {
+		apiServer = make(map[string]interface{})
+	}
+	apiServer["certSANs"] = sans
+	clusterConfiguration["apiServer"] = apiServer
+	y, err := yaml.Marshal(clusterConfiguration)
+	if err != nil {
+		return "", err
+	}
+	return string(y), nil
+}
+
+// sansChanged return true if any SAN in new is not in old.
+func sansChanged(old, new []string) bool {
+	for _, san := range new {
+		if utils.NotIn(san, old) {
+			return true
+		}
+	}
+	return false
+}
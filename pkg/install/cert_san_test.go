@@ -0,0 +1,55 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestUpdateClusterConfigurationSANs(t *testing.T) {
+	data := `apiServer:
+  certSANs:
+  - 127.0.0.1
+  - apiserver.cluster.local
+  extraArgs:
+    feature-gates: TTLAfterFinished=true
+apiVersion: kubeadm.k8s.io/v1beta2
+kind: ClusterConfiguration
+`
+	sans := []string{"127.0.0.1", "apiserver.cluster.local", "lb.sealyun.com"}
+	got, err := updateClusterConfigurationSANs(data, sans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		Kind      string `json:"kind"`
+		APIServer struct {
+			CertSANs  []string          `json:"certSANs"`
+			ExtraArgs map[string]string `json:"extraArgs"`
+		} `json:"apiServer"`
+	}
+	if err := yaml.Unmarshal([]byte(got), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg.APIServer.CertSANs, sans) {
+		t.Errorf("certSANs = %v, want %v", cfg.APIServer.CertSANs, sans)
+	}
+	if cfg.Kind != "ClusterConfiguration" || cfg.APIServer.ExtraArgs["feature-gates"] != "TTLAfterFinished=true" {
+		t.Errorf("other fields are changed: %s", got)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 
+	runtime "github.com/fanux/sealos/pkg/cri/runtime"
 	"github.com/fanux/sealos/pkg/logger"
 
 	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
@@ -49,8 +50,34 @@ func (s *SealosInstaller) CheckValid() {
 		logger.Error("user not allow empty")
 		os.Exit(1)
 	}
+	if err := CheckCA(); err != nil {
+		s.Print("Fail")
+		logger.Error("check ca failed: %s", err)
+		os.Exit(1)
+	}
+	if err := CheckCRI(v1.Version); err != nil {
+		s.Print("Fail")
+		logger.Error("check cri failed: %s", err)
+		os.Exit(1)
+	}
+	if err := CheckLocalRegistry(); err != nil {
+		s.Print("Fail")
+		logger.Error("check local registry failed: %s", err)
+		os.Exit(1)
+	}
+	if err := CheckNetwork(v1.Version); err != nil {
+		s.Print("Fail")
+		logger.Error("check network failed: %s", err)
+		os.Exit(1)
+	}
+	if err := CheckLoadBalancer(); err != nil {
+		s.Print("Fail")
+		logger.Error("check load balancer failed: %s", err)
+		os.Exit(1)
+	}
+	cri := criName(v1.Version)
 	dict := make(map[string]bool)
-	var errList []string
+	var errList, criErrList []string
 	for _, h := range s.Hosts {
 		hostname := v1.SSHConfig.CmdToString(h, "hostname", "") //获取主机名
 		if hostname == "" {
@@ -66,34 +93,57 @@ func (s *SealosInstaller) CheckValid() {
 			}
 			logger.Info("[%s]  ------------ check ok", h)
 		}
-		//if s.Network == cni.CILIUM {
-		//	if err := v1.SSHConfig.CmdAsync(h, "uname -r | grep 5 | awk -F. '{if($2>3)print \"ok\"}' | grep ok && exit 0 || exit 1"); err != nil {
-		//		logger.Error("[%s] ------------ check kernel version  < 5.3", h)
-		//		os.Exit(1)
-		//	}
-		//	if err := v1.SSHConfig.CmdAsync(h, "mount bpffs -t bpf /sys/fs/bpf && mount | grep /sys/fs/bpf && exit 0 || exit 1"); err != nil {
-		//		logger.Error("[%s] ------------ mount  bpffs err", h)
-		//		os.Exit(1)
-		//	}
-		//}
-
-		// version >= 1.20 , Add prefight for containerd
-		if utils.For120(v1.Version) {
+		switch {
+		case cri == runtime.Containerd:
 			// for containerd. if docker exist ; exit frist.
-
 			dockerExist := v1.SSHConfig.CmdToString(h, "command -v dockerd &> /dev/null && echo yes || :", "")
 			if dockerExist == "yes" {
 				errList = append(errList, h)
 			}
+			// the package of version < 1.20 only install docker, containerd must be installed first.
+			if packageCRI(v1.Version) != runtime.Containerd && v1.SSHConfig.CmdToString(h, "command -v containerd &> /dev/null && echo yes || :", "") != "yes" {
+				criErrList = append(criErrList, h)
+			}
+		case cri == runtime.CRIO:
+			// cri-o is not in the package, it must be installed first. the package images are loaded by podman.
+			if v1.SSHConfig.CmdToString(h, "command -v crio &> /dev/null && command -v podman &> /dev/null && echo yes || :", "") != "yes" {
+				criErrList = append(criErrList, h)
+			}
+		case cri == runtime.Docker && utils.For120(v1.Version):
+			// the package of version >= 1.20 only install containerd, docker must be installed first.
+			if v1.SSHConfig.CmdToString(h, "command -v dockerd &> /dev/null && echo yes || :", "") != "yes" {
+				criErrList = append(criErrList, h)
+			}
 		}
 	}
 
+	if len(criErrList) >= 1 {
+		if cri == runtime.CRIO {
+			logger.Error("cri %s or podman is not installed on %s, please install them first", cri, criErrList)
+		} else {
+			logger.Error("cri %s is not installed on %s, please install it first", cri, criErrList)
+		}
+		os.Exit(-1)
+	}
 	if len(errList) >= 1 {
-		logger.Error(`docker exist error when kubernetes version >= 1.20.
-sealos install kubernetes version >= 1.20 use containerd cri instead. 
+		logger.Error(`docker exist error when cri is containerd.
+sealos install kubernetes version >= 1.20 use containerd cri by default, use --cri docker to keep docker. 
 please uninstall docker on [%s]. For example run on centos7: "yum remove docker-ce containerd-io -y",  
 see details:  https://github.com/fanux/sealos/issues/582
 					`, errList)
 		os.Exit(-1)
 	}
 }
+
+// CheckCRI check the container runtime is supported by the kubernetes version.
+func CheckCRI(version string) error {
+	cri := criName(version)
+	if runtime.CRISocket(cri) == "" {
+		return fmt.Errorf("unsupported cri %s, only support %s|%s|%s", cri, runtime.Docker, runtime.Containerd, runtime.CRIO)
+	}
+	// dockershim is removed from kubelet since 1.24.
+	if cri == runtime.Docker && utils.VersionToInt(version) >= 124 {
+		return fmt.Errorf("cri docker is not supported by kubernetes %s, dockershim is removed since v1.24", version)
+	}
+	return nil
+}
@@ -20,13 +20,12 @@ import (
 	"strings"
 	"sync"
 
+	runtime "github.com/fanux/sealos/pkg/cri/runtime"
 	"github.com/fanux/sealos/pkg/logger"
 
 	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
 	"github.com/fanux/sealos/pkg/utils"
 	"github.com/fanux/sealos/pkg/utils/ssh"
-
-	"github.com/fanux/sealos/pkg/ipvs"
 )
 
 type SealosClean struct {
@@ -143,7 +142,9 @@ func (s *SealosClean) Clean() {
 }
 
 func (s *SealosClean) cleanNode(node string) {
-	cleanRoute(node)
+	if v1.LoadBalancer.IsIPVS() {
+		cleanRoute(node)
+	}
 	clean(node)
 	//remove node
 	v1.NodeIPs = utils.IPListRemove(v1.NodeIPs, node)
@@ -169,27 +170,23 @@ func (s *SealosClean) cleanMaster(master string) {
 			_ = v1.SSHConfig.CmdAsync(v1.MasterIPs[0], fmt.Sprintf(cmd, strings.TrimSpace(hostname)))
 		}
 		//清空所有的nodes的数据
-		yaml := ipvs.LvsStaticPodYaml(v1.VIP, v1.MasterIPs, v1.LvscareImage)
-		var wg sync.WaitGroup
-		for _, node := range v1.NodeIPs {
-			wg.Add(1)
-			go func(node string) {
-				defer wg.Done()
-				_ = v1.SSHConfig.CmdAsync(node, "rm -rf  /etc/kubernetes/manifests/kube-sealyun-lvscare*")
-				_ = v1.SSHConfig.CmdAsync(node, fmt.Sprintf("mkdir -p /etc/kubernetes/manifests && echo '%s' > /etc/kubernetes/manifests/kube-sealyun-lvscare.yaml", yaml))
-			}(node)
-		}
-		wg.Wait()
+		s.syncLoadBalancer()
 	}
 }
 
 func clean(host string) {
-	cmd := "kubeadm reset -f " + v1.VLogString()
+	cmd := fmt.Sprintf("kubeadm reset -f --cri-socket %s %s", runtime.CRISocket(criName(v1.Version)), v1.VLogString())
 	_ = v1.SSHConfig.CmdAsync(host, cmd)
 	cmd = `sed -i '/kubectl/d;/sealos/d' /root/.bashrc`
 	_ = v1.SSHConfig.CmdAsync(host, cmd)
 	cmd = "modprobe -r ipip  && lsmod"
 	_ = v1.SSHConfig.CmdAsync(host, cmd)
+	if cmd = networkCleanCmd(); cmd != "" {
+		_ = v1.SSHConfig.CmdAsync(host, cmd)
+	}
+	if cmd = lbCleanCmd(); cmd != "" {
+		_ = v1.SSHConfig.CmdAsync(host, cmd)
+	}
 	cmd = "rm -rf ~/.kube/ && rm -rf /etc/kubernetes/"
 	_ = v1.SSHConfig.CmdAsync(host, cmd)
 	cmd = "rm -rf /etc/systemd/system/kubelet.service.d && rm -rf /etc/systemd/system/kubelet.service"
@@ -207,6 +204,10 @@ func clean(host string) {
 	//clean pki certs
 	cmd = "rm -rf /etc/kubernetes/pki"
 	_ = v1.SSHConfig.CmdAsync(host, cmd)
+	if isLocalRegistryHost(host) {
+		cmd = fmt.Sprintf("systemctl disable --now %[1]s; rm -f /etc/systemd/system/%[1]s.service && rm -rf %[2]s", LocalRegistryService, LocalRegistryDataDir)
+		_ = v1.SSHConfig.CmdAsync(host, cmd)
+	}
 	//clean sealos in /usr/bin/ except exec sealos
 	cmd = "ps -ef |grep -v 'grep'|grep sealos >/dev/null || rm -rf /usr/bin/sealos"
 	_ = v1.SSHConfig.CmdAsync(host, cmd)
@@ -217,7 +218,7 @@ func cleanRoute(node string) {
 	cmdRoute := fmt.Sprintf("sealos route --host %s", utils.IPFormat(node))
 	status := v1.SSHConfig.CmdToString(node, cmdRoute, "")
 	if status != "ok" {
-		// 删除为 vip创建的路由。
+		// 删除为 vip创建的路由，以及开机恢复路由的 systemd unit。
 		delRouteCmd := fmt.Sprintf("sealos route del --host %s --gateway %s", v1.VIP, utils.IPFormat(node))
 		v1.SSHConfig.CmdToString(node, delRouteCmd, "")
 	}
@@ -0,0 +1,181 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fanux/sealos/pkg/cni"
+	"github.com/fanux/sealos/pkg/logger"
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+
+	"github.com/fanux/sealos/pkg/kubernetes/apiclient"
+	"github.com/fanux/sealos/pkg/kubernetes/nodeclient"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// cniDiffCmd show the diff of the cni manifests against the live objects, kubectl diff exits 1 if there is any diff.
+	cniDiffCmd = "kubectl diff -f /tmp/cni.yaml; [ $? -le 1 ]"
+	// calicoConfigMap is the ConfigMap of the calico manifests with the veth_mtu.
+	calicoConfigMap = "calico-config"
+)
+
+var CNIUpgradeTimeout = 10 * time.Minute
+
+type CNIFlags struct {
+	Client *kubernetes.Clientset
+	v1.SealConfig
+}
+
+// GetCNIFlags load the sealos config, exit if failed.
+func GetCNIFlags(cfgFile string) *CNIFlags {
+	c := &CNIFlags{}
+	if err := c.Load(cfgFile); err != nil {
+		logger.Error(err)
+		c.ShowDefaultConfig()
+		os.Exit(0)
+	}
+	return c
+}
+
+// Upgrade render the cni manifests of version by the options in sealos config, show the diff against the live objects,
+// then apply them and wait for the DaemonSet rollout unless dryRun. The custom cni template saved in sealos config is
+// rendered again unless --cni-manifest is set, the version and the template are saved to sealos config.
+func (c *CNIFlags) Upgrade(cfgFile, version string, dryRun bool) error {
+	if v1.WithoutCNI {
+		return fmt.Errorf("cni is not installed by sealos, the cluster is inited with --without-cni")
+	}
+	name := networkName()
+	if v1.CNIManifest == "" {
+		if err := cni.CheckVersion(name, version); err != nil {
+			return err
+		}
+	}
+	if !utils.FileExist(nodeclient.KubeDefaultConfigPath) {
+		return fmt.Errorf("KubeDefaultConfigPath %s is not exist", nodeclient.KubeDefaultConfigPath)
+	}
+	client, err := nodeclient.NewClient(nodeclient.KubeDefaultConfigPath, nil)
+	if err != nil {
+		return fmt.Errorf("get k8s client err: %w", err)
+	}
+	c.Client = client
+	ds, err := client.AppsV1().DaemonSets(metav1.NamespaceSystem).Get(context.TODO(), cni.DaemonSetName(name), metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get %s DaemonSet err: %w", name, err)
+	}
+	// the clusters inited by the old sealos do not save the cni options, read them from the live calico objects.
+	if name == cni.CALICO {
+		setCalicoOptions(ds, calicoVethMTU(client))
+	}
+	manifests, err := renderNetwork(version)
+	if err != nil {
+		return err
+	}
+	configYamlPath := filepath.Join(v1.DefaultConfigPath, "cni.yaml")
+	if err = ioutil.WriteFile(configYamlPath, []byte(manifests), 0644); err != nil {
+		return fmt.Errorf("write cni manifests %s err: %w", configYamlPath, err)
+	}
+	master0 := c.Masters[0]
+	v1.SSHConfig.CopyLocalToRemote(master0, configYamlPath, "/tmp/cni.yaml")
+
+	logger.Info("[%s] upgrade %s %s to %s, diff against the live objects:", master0, name, liveCNIVersion(ds), version)
+	if err = v1.SSHConfig.CmdAsync(master0, cniDiffCmd); err != nil {
+		return fmt.Errorf("diff cni manifests err: %w", err)
+	}
+	if dryRun {
+		logger.Info("dry run, the cni manifests is not applied, it is saved in %s", configYamlPath)
+		return nil
+	}
+	if err = v1.SSHConfig.CmdAsync(master0, cniApplyCmd); err != nil {
+		return fmt.Errorf("apply cni manifests err: %w", err)
+	}
+	waiter := apiclient.NewKubeWaiter(client, CNIUpgradeTimeout, os.Stdout)
+	if err = waiter.WaitForDaemonSetRollout(ds.Name); err != nil {
+		return fmt.Errorf("wait for %s DaemonSet rollout err: %w", ds.Name, err)
+	}
+	// the version and the cni template of the command line are saved for the next upgrade.
+	v1.CniVersion = version
+	c.Dump(cfgFile)
+	logger.Info("upgrade %s to %s success", name, version)
+	return nil
+}
+
+// setCalicoOptions set the interface, encapsulation and MTU not saved in sealos config by the calico-node DaemonSet.
+func setCalicoOptions(ds *appsv1.DaemonSet, mtu string) {
+	env := make(map[string]string)
+	for _, container := range ds.Spec.Template.Spec.Containers {
+		if container.Name != cni.DaemonSetName(cni.CALICO) {
+			continue
+		}
+		for _, e := range container.Env {
+			env[e.Name] = e.Value
+		}
+	}
+	if v1.Interface == "" {
+		method := env["IP_AUTODETECTION_METHOD"]
+		v1.Interface = strings.TrimPrefix(strings.TrimPrefix(method, "interface="), "can-reach=")
+	}
+	if v1.CalicoEncap == "" {
+		switch {
+		case env["CALICO_IPV4POOL_IPIP"] == "Always":
+			v1.CalicoEncap = cni.CalicoEncapsulationIPIP
+		case env["CALICO_IPV4POOL_IPIP"] == "CrossSubnet":
+			v1.CalicoEncap = cni.CalicoEncapsulationIPIPCrossSubnet
+		case env["CALICO_IPV4POOL_VXLAN"] == "Always":
+			v1.CalicoEncap = cni.CalicoEncapsulationVXLAN
+		case env["CALICO_IPV4POOL_VXLAN"] == "CrossSubnet":
+			v1.CalicoEncap = cni.CalicoEncapsulationVXLANCrossSubnet
+		default:
+			v1.CalicoEncap = cni.CalicoEncapsulationNone
+		}
+	}
+	if v1.MTU == "" {
+		v1.MTU = mtu
+	}
+}
+
+// calicoVethMTU return the veth_mtu of calico-config, empty if not found.
+func calicoVethMTU(client kubernetes.Interface) string {
+	cm, err := client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(context.TODO(), calicoConfigMap, metav1.GetOptions{})
+	if err != nil {
+		logger.Warn("get %s ConfigMap err: %v", calicoConfigMap, err)
+		return ""
+	}
+	return cm.Data["veth_mtu"]
+}
+
+// liveCNIVersion return the image tag of the first container of the cni DaemonSet.
+func liveCNIVersion(ds *appsv1.DaemonSet) string {
+	containers := ds.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return ""
+	}
+	image := containers[0].Image
+	if name := imageName(image); name != image {
+		return image[len(name)+1:]
+	}
+	return ""
+}
@@ -0,0 +1,93 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"testing"
+
+	"github.com/fanux/sealos/pkg/cni"
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func calicoNodeDaemonSet(image string, env map[string]string) *appsv1.DaemonSet {
+	container := corev1.Container{Name: "calico-node", Image: image}
+	for k, v := range env {
+		container.Env = append(container.Env, corev1.EnvVar{Name: k, Value: v})
+	}
+	ds := &appsv1.DaemonSet{}
+	ds.Spec.Template.Spec.Containers = []corev1.Container{container}
+	return ds
+}
+
+func TestSetCalicoOptions(t *testing.T) {
+	defer func(iface, encap, mtu string) {
+		v1.Interface, v1.CalicoEncap, v1.MTU = iface, encap, mtu
+	}(v1.Interface, v1.CalicoEncap, v1.MTU)
+	tests := []struct {
+		name                 string
+		iface, encap, mtu    string
+		env                  map[string]string
+		wantIface, wantEncap string
+		wantMTU              string
+	}{
+		{
+			name:      "old config ipip",
+			env:       map[string]string{"IP_AUTODETECTION_METHOD": "interface=eth.*|en.*", "CALICO_IPV4POOL_IPIP": "Always"},
+			wantIface: "eth.*|en.*", wantEncap: cni.CalicoEncapsulationIPIP, wantMTU: "1440",
+		},
+		{
+			name:      "old config bgp with can-reach",
+			env:       map[string]string{"IP_AUTODETECTION_METHOD": "can-reach=192.168.0.1", "CALICO_IPV4POOL_IPIP": "Off"},
+			wantIface: "192.168.0.1", wantEncap: cni.CalicoEncapsulationNone, wantMTU: "1440",
+		},
+		{
+			name:      "vxlan cross subnet",
+			env:       map[string]string{"CALICO_IPV4POOL_IPIP": "Never", "CALICO_IPV4POOL_VXLAN": "CrossSubnet"},
+			wantIface: "", wantEncap: cni.CalicoEncapsulationVXLANCrossSubnet, wantMTU: "1440",
+		},
+		{
+			name:  "saved config is kept",
+			iface: "bond0", encap: cni.CalicoEncapsulationVXLAN, mtu: "1400",
+			env:       map[string]string{"IP_AUTODETECTION_METHOD": "interface=eth0", "CALICO_IPV4POOL_IPIP": "Always"},
+			wantIface: "bond0", wantEncap: cni.CalicoEncapsulationVXLAN, wantMTU: "1400",
+		},
+	}
+	for _, tt := range tests {
+		v1.Interface, v1.CalicoEncap, v1.MTU = tt.iface, tt.encap, tt.mtu
+		setCalicoOptions(calicoNodeDaemonSet("calico/node:v3.8.2", tt.env), "1440")
+		if v1.Interface != tt.wantIface || v1.CalicoEncap != tt.wantEncap || v1.MTU != tt.wantMTU {
+			t.Errorf("%s: got interface %q encapsulation %q mtu %q, want %q %q %q", tt.name, v1.Interface, v1.CalicoEncap, v1.MTU, tt.wantIface, tt.wantEncap, tt.wantMTU)
+		}
+	}
+}
+
+func TestLiveCNIVersion(t *testing.T) {
+	tests := map[string]string{
+		"calico/node:v3.8.2":                            "v3.8.2",
+		"registry.example.com:5000/calico/node:v3.19.1": "v3.19.1",
+		"registry.example.com:5000/calico/node":         "",
+	}
+	for image, want := range tests {
+		if got := liveCNIVersion(calicoNodeDaemonSet(image, nil)); got != want {
+			t.Errorf("liveCNIVersion(%s) = %s, want %s", image, got, want)
+		}
+	}
+	if got := liveCNIVersion(&appsv1.DaemonSet{}); got != "" {
+		t.Errorf("liveCNIVersion of empty DaemonSet = %s, want empty", got)
+	}
+}
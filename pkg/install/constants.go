@@ -32,6 +32,9 @@ const (
 	KUBECONTROLLERCONFIGFILE = "/etc/kubernetes/controller-manager.conf"
 	KUBESCHEDULERCONFIGFILE  = "/etc/kubernetes/scheduler.conf"
 
+	// package metadata
+	METADATAFILE = "/root/kube/Metadata"
+
 	KubeadmV1beta1 = "kubeadm.k8s.io/v1beta1"
 	KubeadmV1beta2 = "kubeadm.k8s.io/v1beta2"
 	KubeadmV1beta3 = "kubeadm.k8s.io/v1beta3"
@@ -71,9 +74,9 @@ caCertPath: /etc/kubernetes/pki/ca.crt
 discovery:
   bootstrapToken:
     {{- if .Master}}
-    apiServerEndpoint: {{.Master0}}:6443
+    apiServerEndpoint: {{.Master0}}:{{.ApiServerPort}}
     {{else}}
-    apiServerEndpoint: {{.VIP}}:6443
+    apiServerEndpoint: {{.VIP}}:{{.LBPort}}
     {{end -}}
     token: {{.TokenDiscovery}}
     caCertHashes:
@@ -84,7 +87,7 @@ discovery:
 kind: InitConfiguration
 localAPIEndpoint:
   advertiseAddress: {{.Master0}}
-  bindPort: 6443
+  bindPort: {{.ApiServerPort}}
 nodeRegistration:
   criSocket: {{.CriSocket}}
 `
@@ -95,7 +98,7 @@ kind: JoinConfiguration
 controlPlane:
   localAPIEndpoint:
     advertiseAddress: {{.Master}}
-    bindPort: 6443
+    bindPort: {{.ApiServerPort}}
 {{- end}}
 nodeRegistration:
   criSocket: {{.CriSocket}}
@@ -105,7 +108,7 @@ nodeRegistration:
 apiVersion: {{.KubeadmApi}}
 kind: ClusterConfiguration
 kubernetesVersion: {{.Version}}
-controlPlaneEndpoint: "{{.ApiServer}}:6443"
+controlPlaneEndpoint: "{{.ApiServer}}:{{.LBPort}}"
 imageRepository: {{.Repo}}
 networking:
   # dnsDomain: cluster.local
@@ -133,7 +136,7 @@ apiServer:
 controllerManager:
   extraArgs:
     feature-gates: TTLAfterFinished=true
-    experimental-cluster-signing-duration: 876000h
+    experimental-cluster-signing-duration: {{.ClusterSigningDuration}}
 {{- if eq .Network "cilium" }}
     allocate-node-cidrs: \"true\"
 {{- end }}
@@ -239,6 +242,13 @@ driver=systemd
 else
 driver=cgroupfs
 fi
+echo ${driver}`
+	// CRIOShell read cgroup_manager of crio config, systemd is the default of CRI-O.
+	CRIOShell = `if crio config 2>/dev/null | grep -E '^\s*cgroup_manager\s*=\s*"cgroupfs"' &> /dev/null; then
+driver=cgroupfs
+else
+driver=systemd
+fi
 echo ${driver}`
 	DockerShell = `driver=$(docker info -f "{{.CgroupDriver}}")
 	echo "${driver}"`
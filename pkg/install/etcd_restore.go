@@ -231,3 +231,27 @@ func CmdWork(node, cmd, workdir string) error {
 	// not safe when use etcdctl to backup
 	return v1.SSHConfig.CmdAsync(node, command)
 }
+
+// RestoreCluster restore the etcd snapshot to every etcd node, the control plane is stopped during restore,
+// and recovered with the old etcd data if the restore is failed.
+func (e *EtcdFlags) RestoreCluster() error {
+	e.RestoreAll()
+	time.Sleep(time.Second * 10)
+	// stop etcd kube-apiserver
+	tmpdir, _ := e.StopPod()
+	time.Sleep(time.Second * 10)
+	logger.Info("send restore file to etcd master node and start etcd")
+	// send restore file to etcd master node to  start etcd
+	if err := e.AfterRestore(); err != nil {
+		logger.Info("Start RecoveryKuBeCluster")
+		e.RecoveryKuBeCluster(tmpdir)
+		return err
+	}
+	logger.Info("Start kube-apiserver kube-controller-manager kube-scheduler")
+	// start kube-apiserver
+	e.StartPod(tmpdir)
+	logger.Info("Wait 60s to health check for etcd")
+	time.Sleep(time.Second * 60)
+	e.HealthCheck()
+	return nil
+}
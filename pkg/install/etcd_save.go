@@ -207,7 +207,8 @@ func GetEtcdClient(ep []string) (*clientv3.Client, error) {
 	})
 }
 
-type epHealth struct {
+// EpHealth is the health result of one etcd endpoint.
+type EpHealth struct {
 	Ep     string `json:"endpoint"`
 	Health bool   `json:"health"`
 	Took   string `json:"took"`
@@ -234,7 +235,8 @@ func GetHealthFlag(cfgFile string) *EtcdFlags {
 	return e
 }
 
-func (e *EtcdFlags) HealthCheck() {
+// HealthCheck checks every etcd endpoint and returns the result of each one.
+func (e *EtcdFlags) HealthCheck() []EpHealth {
 	cfgs := []*clientv3.Config{}
 	for _, ep := range e.Endpoints {
 		cfg, err := GetCfg([]string{ep})
@@ -244,7 +246,7 @@ func (e *EtcdFlags) HealthCheck() {
 		cfgs = append(cfgs, cfg)
 	}
 	var wg sync.WaitGroup
-	hch := make(chan epHealth, len(cfgs))
+	hch := make(chan EpHealth, len(cfgs))
 	for _, cfg := range cfgs {
 		wg.Add(1)
 		go func(cfg *clientv3.Config) {
@@ -252,7 +254,7 @@ func (e *EtcdFlags) HealthCheck() {
 			ep := cfg.Endpoints[0]
 			cli, err := clientv3.New(*cfg)
 			if err != nil {
-				hch <- epHealth{Ep: ep, Health: false, Error: err.Error()}
+				hch <- EpHealth{Ep: ep, Health: false, Error: err.Error()}
 				return
 			}
 			st := time.Now()
@@ -261,7 +263,7 @@ func (e *EtcdFlags) HealthCheck() {
 			ctx, cancel := context.WithTimeout(context.Background(), time.Second*60)
 			_, err = cli.Get(ctx, "health")
 			cancel()
-			eh := epHealth{Ep: ep, Health: false, Took: time.Since(st).String()}
+			eh := EpHealth{Ep: ep, Health: false, Took: time.Since(st).String()}
 			// permission denied is OK since proposal goes through consensus to get it
 			if err == nil || err == rpctypes.ErrPermissionDenied {
 				eh.Health = true
@@ -276,7 +278,7 @@ func (e *EtcdFlags) HealthCheck() {
 	close(hch)
 
 	errs := false
-	healthList := []epHealth{}
+	healthList := []EpHealth{}
 	for h := range hch {
 		healthList = append(healthList, h)
 		if h.Error != "" {
@@ -287,6 +289,7 @@ func (e *EtcdFlags) HealthCheck() {
 	if errs {
 		logger.Error("unhealthy cluster")
 	}
+	return healthList
 }
 
 // CertFileExist if cert file is exist return true
@@ -46,6 +46,14 @@ func ExitInitCase() bool {
 		logger.Error(ErrorFileNotExist + "please check where your PkgUrl is right?")
 		return true
 	}
+	if err := v1.ParseRegistryFlags(); err != nil {
+		logger.Error(err)
+		return true
+	}
+	if v1.APIServerPort <= 0 || v1.APIServerPort > 65535 {
+		logger.Error("apiserver port %d is invalid, must be in 1-65535", v1.APIServerPort)
+		return true
+	}
 
 	return false
 }
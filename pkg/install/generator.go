@@ -37,21 +37,34 @@ func setKubeadmAPI(version string) {
 	//
 	case major < 120:
 		v1.KubeadmAPI = KubeadmV1beta1
-		v1.CriSocket = runtime.DefaultDockerCRISocket
 	case major < 123 && major >= 120:
 		v1.KubeadmAPI = KubeadmV1beta2
-		v1.CriSocket = runtime.DefaultContainerdCRISocket
 	case major >= 123:
 		v1.KubeadmAPI = KubeadmV1beta3
-		v1.CriSocket = runtime.DefaultContainerdCRISocket
 	default:
 		v1.KubeadmAPI = KubeadmV1beta3
-		v1.CriSocket = runtime.DefaultContainerdCRISocket
 	}
+	v1.CriSocket = runtime.CRISocket(criName(version))
 	logger.Debug("KubeadmApi: %s", v1.KubeadmAPI)
 	logger.Debug("CriSocket: %s", v1.CriSocket)
 }
 
+// criName return the container runtime of the cluster, docker below 1.20 and containerd from 1.20 on by default.
+func criName(version string) string {
+	if v1.CRI != "" {
+		return v1.CRI
+	}
+	return packageCRI(version)
+}
+
+// packageCRI is the container runtime installed by init.sh of the package, docker before 1.20 and containerd since 1.20.
+func packageCRI(version string) string {
+	if major, _ := utils.GetMajorMinorInt(version); major < 120 {
+		return runtime.Docker
+	}
+	return runtime.Containerd
+}
+
 func Config() {
 	switch ConfigType {
 	case "kubeadm":
@@ -110,6 +123,8 @@ func JoinTemplateFromTemplateContent(templateContent, ip, cgroup string) []byte
 	envMap["TokenDiscovery"] = JoinToken
 	envMap["TokenDiscoveryCAHash"] = TokenCaCertHash
 	envMap["VIP"] = v1.VIP
+	envMap["ApiServerPort"] = v1.APIServerPort
+	envMap["LBPort"] = v1.LoadBalancer.Port
 	envMap["KubeadmApi"] = v1.KubeadmAPI
 	envMap["CriSocket"] = v1.CriSocket
 	envMap["CgroupDriver"] = cgroup
@@ -140,6 +155,8 @@ func TemplateFromTemplateContent(templateContent string) []byte {
 	envMap["Masters"] = masters
 	envMap["Version"] = v1.Version
 	envMap["ApiServer"] = v1.APIServer
+	envMap["ApiServerPort"] = v1.APIServerPort
+	envMap["LBPort"] = v1.LoadBalancer.Port
 	envMap["PodCIDR"] = v1.PodCIDR
 	envMap["SvcCIDR"] = v1.SvcCIDR
 	envMap["Repo"] = v1.Repo
@@ -147,11 +164,21 @@ func TemplateFromTemplateContent(templateContent string) []byte {
 	envMap["CgroupDriver"] = v1.CgroupDriver
 	envMap["KubeadmApi"] = v1.KubeadmAPI
 	envMap["CriSocket"] = v1.CriSocket
+	envMap["ClusterSigningDuration"] = clusterSigningDuration()
+	envMap["Network"] = networkName()
 	var buffer bytes.Buffer
 	_ = tmpl.Execute(&buffer, envMap)
 	return buffer.Bytes()
 }
 
+// clusterSigningDuration is the validity of certs signed by controller-manager, like the kubelet client certs.
+func clusterSigningDuration() string {
+	if v1.CertValidity > 0 {
+		return v1.CertValidity.String()
+	}
+	return "876000h"
+}
+
 //根据yaml转换kubeadm结构
 func KubeadmDataFromYaml(context string) *KubeadmType {
 	yamls := strings.Split(context, "---")
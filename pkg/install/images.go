@@ -0,0 +1,341 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	runtime "github.com/fanux/sealos/pkg/cri/runtime"
+	"github.com/fanux/sealos/pkg/logger"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+
+	"github.com/fanux/sealos/pkg/kubernetes/nodeclient"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilsexec "k8s.io/utils/exec"
+)
+
+const (
+	ImagesList  = "list"
+	ImagesPull  = "pull"
+	ImagesLoad  = "load"
+	ImagesPrune = "prune"
+
+	// ImagesTmpDir is the dir on the nodes to receive the image tarballs.
+	ImagesTmpDir = "/tmp/sealos-images"
+
+	ImageStatusRequired = "required"
+	ImageStatusMissing  = "missing"
+	ImageStatusFailed   = "failed"
+)
+
+// ImageInfo is the image on the node, Status is empty if the image is not required by kubernetes.
+type ImageInfo struct {
+	Host   string
+	Image  string
+	Status string
+}
+
+type ImagesFlags struct {
+	Hosts []string
+	v1.SealConfig
+}
+
+// GetImagesFlags load the sealos config and select the hosts by label or nodes, all hosts if both are empty. exit if failed.
+func GetImagesFlags(cfgFile, label string, nodes []string) *ImagesFlags {
+	i := &ImagesFlags{}
+	if err := i.Load(cfgFile); err != nil {
+		logger.Error(err)
+		i.ShowDefaultConfig()
+		os.Exit(0)
+	}
+	all := append(append([]string{}, i.Masters...), i.Nodes...)
+	if label == "" && len(nodes) == 0 {
+		i.Hosts = all
+		return i
+	}
+	if !utils.FileExist(nodeclient.KubeDefaultConfigPath) {
+		logger.Error("file %s is not exist", nodeclient.KubeDefaultConfigPath)
+		os.Exit(ErrorExitOSCase)
+	}
+	k8sClient, err := nodeclient.NewClient(nodeclient.KubeDefaultConfigPath, nil)
+	if err != nil {
+		logger.Error("get k8s client err: ", err)
+		os.Exit(ErrorExitOSCase)
+	}
+	ips, err := nodeclient.TransToIP(k8sClient, label, nodes)
+	if err != nil {
+		logger.Error("get ips err: ", err)
+		os.Exit(ErrorExitOSCase)
+	}
+	for _, host := range all {
+		if utils.InList(utils.IPFormat(host), ips) {
+			i.Hosts = append(i.Hosts, host)
+		}
+	}
+	if len(i.Hosts) == 0 {
+		logger.Error("no node is selected by label %s and nodes %v", label, nodes)
+		os.Exit(ErrorExitOSCase)
+	}
+	return i
+}
+
+// List the images on every host, and the required kubernetes images missing on the host.
+// The host failed to list the images is reported as failed, and its error is returned.
+func (i *ImagesFlags) List() ([]ImageInfo, error) {
+	required, err := i.requiredImages()
+	if err != nil {
+		return nil, err
+	}
+	var infos []ImageInfo
+	var errs []error
+	for _, host := range i.Hosts {
+		out, err := v1.SSHConfig.CmdOutput(host, imagesCmd(ImagesList))
+		if err != nil {
+			infos = append(infos, ImageInfo{Host: utils.IPFormat(host), Image: "-", Status: ImageStatusFailed})
+			errs = append(errs, fmt.Errorf("[%s] list images err: %w", host, err))
+			continue
+		}
+		images := strings.Fields(string(out))
+		hostRequired := required
+		if utils.NotIn(host, i.Masters) {
+			hostRequired = nodeRequiredImages(required)
+		}
+		infos = append(infos, imageInfos(utils.IPFormat(host), images, hostRequired)...)
+	}
+	return infos, utilerrors.NewAggregate(errs)
+}
+
+// Pull the images on every host.
+func (i *ImagesFlags) Pull(images []string) error {
+	if len(images) == 0 {
+		return fmt.Errorf("images can not be empty")
+	}
+	return i.forEachHost(func(host string) error {
+		return v1.SSHConfig.CmdAsync(host, imagesCmd(ImagesPull, images...))
+	})
+}
+
+// LoadImages copy the image tarballs to every host and load them to the container runtime.
+func (i *ImagesFlags) LoadImages(files []string) error {
+	if len(files) == 0 {
+		return fmt.Errorf("image files can not be empty")
+	}
+	var remoteFiles []string
+	for _, f := range files {
+		if !utils.FileExist(f) {
+			return fmt.Errorf("image file %s is not exist", f)
+		}
+		remoteFiles = append(remoteFiles, path.Join(ImagesTmpDir, filepath.Base(f)))
+	}
+	return i.forEachHost(func(host string) error {
+		if err := v1.SSHConfig.CmdAsync(host, "mkdir -p "+ImagesTmpDir); err != nil {
+			return err
+		}
+		for j, f := range files {
+			v1.SSHConfig.CopyLocalToRemote(host, f, remoteFiles[j])
+		}
+		err := v1.SSHConfig.CmdAsync(host, imagesCmd(ImagesLoad, remoteFiles...))
+		_ = v1.SSHConfig.CmdAsync(host, "rm -rf "+ImagesTmpDir)
+		return err
+	})
+}
+
+// Prune the images not used by any container on every host, the images required by kubernetes and the keep images are not removed.
+func (i *ImagesFlags) Prune(keep []string) error {
+	required, err := i.requiredImages()
+	if err != nil {
+		return err
+	}
+	return i.forEachHost(func(host string) error {
+		hostRequired := required
+		if utils.NotIn(host, i.Masters) {
+			hostRequired = nodeRequiredImages(required)
+		}
+		return v1.SSHConfig.CmdAsync(host, imagesCmd(ImagesPrune, append(append([]string{}, hostRequired...), keep...)...))
+	})
+}
+
+func (i *ImagesFlags) forEachHost(f func(host string) error) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs []error
+	for _, h := range i.Hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			if err := f(host); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("[%s] %w", host, err))
+				mu.Unlock()
+			}
+		}(h)
+	}
+	wg.Wait()
+	return utilerrors.NewAggregate(errs)
+}
+
+// requiredImages return the control-plane images of the kubeadm config on master0, and the load balancer images.
+func (i *ImagesFlags) requiredImages() ([]string, error) {
+	out, err := v1.SSHConfig.CmdOutput(i.Masters[0], "kubeadm config images list --config /root/kubeadm-config.yaml")
+	if err != nil {
+		return nil, fmt.Errorf("[%s] list the kubernetes images err: %w", i.Masters[0], err)
+	}
+	var required []string
+	for _, image := range strings.Fields(string(out)) {
+		if strings.Contains(image, ":") {
+			required = append(required, image)
+		}
+	}
+	if len(required) == 0 {
+		return nil, fmt.Errorf("[%s] no kubernetes image is listed by kubeadm: %s", i.Masters[0], strings.TrimSpace(string(out)))
+	}
+	if i.LvscareName != "" && i.LoadBalancer.IsIPVS() {
+		required = append(required, fmt.Sprintf("%s:%s", i.LvscareName, i.LvscareTag))
+	}
+	return append(required, lbImages(i.LoadBalancer)...), nil
+}
+
+// imagesCmd is the sealos images command run on the node by the sealos in /usr/bin.
+func imagesCmd(op string, args ...string) string {
+	cmd := fmt.Sprintf("sealos images %s --local --cri %s", op, criName(v1.Version))
+	for _, arg := range args {
+		cmd += " " + shellQuote(arg)
+	}
+	return cmd
+}
+
+// shellQuote quote the arg in single quotes for the shell.
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// nodeRequiredImages filter the images required by the worker nodes, the control-plane images are not needed.
+func nodeRequiredImages(required []string) []string {
+	var images []string
+	for _, image := range required {
+		name := imageName(image)
+		if strings.HasSuffix(name, "/pause") || strings.HasSuffix(name, "/kube-proxy") || strings.HasSuffix(name, "/lvscare") {
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+func imageInfos(host string, images, required []string) []ImageInfo {
+	var infos []ImageInfo
+	exist := make(map[string]bool)
+	for _, image := range images {
+		exist[normalizeImage(image)] = true
+	}
+	requiredSet := make(map[string]bool)
+	for _, image := range required {
+		requiredSet[normalizeImage(image)] = true
+	}
+	for _, image := range images {
+		info := ImageInfo{Host: host, Image: image}
+		if requiredSet[normalizeImage(image)] {
+			info.Status = ImageStatusRequired
+		}
+		infos = append(infos, info)
+	}
+	for _, image := range required {
+		if !exist[normalizeImage(image)] {
+			infos = append(infos, ImageInfo{Host: host, Image: image, Status: ImageStatusMissing})
+		}
+	}
+	return infos
+}
+
+// normalizeImage trim the default registry, docker lists the short name while containerd lists the full name.
+func normalizeImage(image string) string {
+	image = strings.TrimPrefix(image, "docker.io/")
+	if !strings.Contains(image, "/") {
+		return image
+	}
+	return strings.TrimPrefix(image, "library/")
+}
+
+// imageName return the image name without tag.
+func imageName(image string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i]
+	}
+	return image
+}
+
+// PrintImageInfos print image infos as table.
+func PrintImageInfos(w io.Writer, infos []ImageInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tIMAGE\tSTATUS")
+	for _, i := range infos {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", i.Host, i.Image, i.Status)
+	}
+	return tw.Flush()
+}
+
+// LocalImages run the image operation by the container runtime of this host, it is run on the nodes by sealos images --local.
+func LocalImages(w io.Writer, cri, op string, args []string) error {
+	socket := runtime.CRISocket(cri)
+	if socket == "" {
+		return fmt.Errorf("cri %s is not supported, must be one of %s|%s|%s", cri, runtime.Docker, runtime.Containerd, runtime.CRIO)
+	}
+	r, err := runtime.NewContainerRuntime(utilsexec.New(), socket)
+	if err != nil {
+		return err
+	}
+	switch op {
+	case ImagesList:
+		images, err := r.ListImages()
+		if err != nil {
+			return err
+		}
+		for _, image := range images {
+			fmt.Fprintln(w, image)
+		}
+	case ImagesPull:
+		for _, image := range args {
+			if err = r.PullImage(image); err != nil {
+				return err
+			}
+		}
+	case ImagesLoad:
+		for _, f := range args {
+			if err = r.LoadImage(f); err != nil {
+				return err
+			}
+		}
+	case ImagesPrune:
+		keep := make(map[string]bool)
+		for _, image := range args {
+			keep[normalizeImage(image)] = true
+		}
+		return r.PruneImages(func(image string) bool {
+			return keep[normalizeImage(image)]
+		})
+	default:
+		return fmt.Errorf("unknown images operation %s", op)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+)
+
+func TestImageInfos(t *testing.T) {
+	required := []string{
+		"k8s.gcr.io/kube-apiserver:v1.20.15",
+		"k8s.gcr.io/kube-proxy:v1.20.15",
+		"k8s.gcr.io/pause:3.2",
+		"fanux/lvscare:latest",
+	}
+	nodeRequired := nodeRequiredImages(required)
+	if want := []string{"k8s.gcr.io/kube-proxy:v1.20.15", "k8s.gcr.io/pause:3.2", "fanux/lvscare:latest"}; !reflect.DeepEqual(nodeRequired, want) {
+		t.Errorf("nodeRequiredImages() = %v, want %v", nodeRequired, want)
+	}
+
+	images := []string{"docker.io/fanux/lvscare:latest", "k8s.gcr.io/pause:3.2", "docker.io/library/nginx:1.19"}
+	got := imageInfos("192.168.0.5", images, nodeRequired)
+	want := []ImageInfo{
+		{Host: "192.168.0.5", Image: "docker.io/fanux/lvscare:latest", Status: ImageStatusRequired},
+		{Host: "192.168.0.5", Image: "k8s.gcr.io/pause:3.2", Status: ImageStatusRequired},
+		{Host: "192.168.0.5", Image: "docker.io/library/nginx:1.19"},
+		{Host: "192.168.0.5", Image: "k8s.gcr.io/kube-proxy:v1.20.15", Status: ImageStatusMissing},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("imageInfos() = %v, want %v", got, want)
+	}
+}
+
+func TestImagesCmd(t *testing.T) {
+	defer func(cri string) { v1.CRI = cri }(v1.CRI)
+	v1.CRI = "containerd"
+	got := imagesCmd(ImagesLoad, "/tmp/sealos-images/app images.tar", "it's.tar")
+	want := `sealos images load --local --cri containerd '/tmp/sealos-images/app images.tar' 'it'\''s.tar'`
+	if got != want {
+		t.Errorf("imagesCmd() = %s, want %s", got, want)
+	}
+}
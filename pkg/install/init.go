@@ -16,7 +16,6 @@ package install
 
 import (
 	"bufio"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -32,7 +31,7 @@ import (
 
 	cert "github.com/fanux/sealos/pkg/kubernetes/cert"
 
-	"github.com/fanux/sealos/pkg/cni"
+	runtime "github.com/fanux/sealos/pkg/cri/runtime"
 )
 
 //BuildInit is
@@ -44,6 +43,7 @@ func BuildInit() {
 	// 所有node节点
 	nodes := v1.NodeIPs
 	hosts := append(masters, nodes...)
+	SetLocalRegistry(masters[0])
 	i := &SealosInstaller{
 		Hosts:     hosts,
 		Masters:   masters,
@@ -51,9 +51,12 @@ func BuildInit() {
 		APIServer: v1.APIServer,
 	}
 	i.CheckValid()
+	i.CheckNetworkHosts()
 	i.Print()
 	i.SendSealos()
 	i.SendPackage()
+	i.StartLocalRegistry()
+	i.SendRegistryConfig()
 	i.Print("CopyFiles")
 	i.KubeadmConfigInstall()
 	i.Print("CopyFiles", "KubeadmConfigInstall")
@@ -77,12 +80,13 @@ func BuildInit() {
 
 func (s *SealosInstaller) getCgroupDriverFromShell(h string) string {
 	var output string
-	if utils.For120(v1.Version) {
-		cmd := ContainerdShell
-		output = v1.SSHConfig.CmdToString(h, cmd, " ")
-	} else {
-		cmd := DockerShell
-		output = v1.SSHConfig.CmdToString(h, cmd, " ")
+	switch criName(v1.Version) {
+	case runtime.Containerd:
+		output = v1.SSHConfig.CmdToString(h, ContainerdShell, " ")
+	case runtime.CRIO:
+		output = v1.SSHConfig.CmdToString(h, CRIOShell, " ")
+	default:
+		output = v1.SSHConfig.CmdToString(h, DockerShell, " ")
 	}
 	output = strings.TrimSpace(output)
 	logger.Info("cgroup driver is %s", output)
@@ -161,7 +165,24 @@ func (s *SealosInstaller) appendAPIServer() error {
 func (s *SealosInstaller) GenerateCert() {
 	//cert generator in sealos
 	hostname := ssh.RemoteHostName(v1.SSHConfig, s.Masters[0])
-	GenerateCert(v1.CertPath, v1.CertEtcdPath, v1.APIServerCertSANs, utils.IPFormat(s.Masters[0]), hostname, v1.SvcCIDR, v1.DNSDomain)
+	options, err := NewCertOptions(v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm)
+	if err != nil {
+		logger.Error("invalid cert options %s", err)
+		os.Exit(-1)
+	}
+	if v1.CADir != "" {
+		if err = cert.CopyCADir(v1.CADir, v1.CertPath, v1.CertEtcdPath); err != nil {
+			logger.Error("copy ca from %s failed %s", v1.CADir, err)
+			os.Exit(-1)
+		}
+	}
+	if v1.RootCACert != "" {
+		if options.RootCA, err = cert.LoadRootCA(v1.RootCACert, v1.RootCAKey); err != nil {
+			logger.Error("load root ca failed %s", err)
+			os.Exit(-1)
+		}
+	}
+	GenerateCert(v1.CertPath, v1.CertEtcdPath, v1.APIServerCertSANs, utils.IPFormat(s.Masters[0]), hostname, v1.SvcCIDR, v1.DNSDomain, options)
 	//copy all cert to master0
 	//CertSA(kye,pub) + CertCA(key,crt)
 	//s.sendNewCertAndKey(s.Masters)
@@ -170,15 +191,22 @@ func (s *SealosInstaller) GenerateCert() {
 
 func (s *SealosInstaller) CreateKubeconfig() {
 	hostname := ssh.RemoteHostName(v1.SSHConfig, s.Masters[0])
+	options, err := NewCertOptions(v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm)
+	if err != nil {
+		logger.Error("invalid cert options %s", err)
+		os.Exit(-1)
+	}
 
 	certConfig := cert.Config{
-		Path:     v1.CertPath,
-		BaseName: "ca",
+		Path:         v1.CertPath,
+		BaseName:     "ca",
+		Validity:     options.CertValidity,
+		KeyAlgorithm: options.KeyAlgorithm,
 	}
 
-	controlPlaneEndpoint := fmt.Sprintf("https://%s:6443", v1.APIServer)
+	controlPlaneEndpoint := fmt.Sprintf("https://%s:%d", v1.APIServer, v1.LoadBalancer.Port)
 
-	err := cert.CreateJoinControlPlaneKubeConfigFiles(v1.DefaultConfigPath,
+	err = cert.CreateJoinControlPlaneKubeConfigFiles(v1.DefaultConfigPath,
 		certConfig, hostname, controlPlaneEndpoint, "kubernetes")
 	if err != nil {
 		logger.Error("generator kubeconfig failed %s", err)
@@ -200,6 +228,7 @@ func (s *SealosInstaller) InstallMaster0() {
 	cmd := fmt.Sprintf("grep -qF '%s %s' /etc/hosts || echo %s %s >> /etc/hosts", utils.IPFormat(s.Masters[0]), v1.APIServer, utils.IPFormat(s.Masters[0]), v1.APIServer)
 	_ = v1.SSHConfig.CmdAsync(s.Masters[0], cmd)
 
+	s.sendLBManifests([]string{s.Masters[0]}, s.Masters)
 	cmd = s.Command(v1.Version, InitMaster)
 
 	output := v1.SSHConfig.Cmd(s.Masters[0], cmd)
@@ -216,38 +245,19 @@ func (s *SealosInstaller) InstallMaster0() {
 		logger.Info("--without-cni is true, so we not install calico or flannel, install it by yourself")
 		return
 	}
-	//cmd = `kubectl apply -f /root/kube/conf/net/calico.yaml || true`
-	var cniVersion string
-	// can-reach is used by calico multi network , flannel has nothing to add. just Use it.
-	if utils.IsIpv4(v1.Interface) {
-		v1.Interface = "can-reach=" + v1.Interface
-	} else if !utils.IsIpv4(v1.Interface) { //nolint:gofmt
-		v1.Interface = "interface=" + v1.Interface
-	}
-	if v1.SSHConfig.IsFileExist(s.Masters[0], "/root/kube/Metadata") {
-		var metajson string
-		var tmpdata v1.Metadata
-		metajson = v1.SSHConfig.CmdToString(s.Masters[0], "cat /root/kube/Metadata", "")
-		err := json.Unmarshal([]byte(metajson), &tmpdata)
-		if err != nil {
-			logger.Warn("get metadata version err: ", err)
-		} else {
-			cniVersion = tmpdata.CniVersion
-		}
+	netyaml, err := networkManifests(s.Masters[0])
+	if err != nil {
+		logger.Error("[%s] %s, fix it and install the cni by yourself", s.Masters[0], err)
+		os.Exit(1)
 	}
-	netyaml := cni.NewCalico(cni.MetaData{
-		Interface: v1.Interface,
-		CIDR:      v1.PodCIDR,
-		IPIP:      !v1.BGP,
-		MTU:       v1.MTU,
-		CniRepo:   v1.Repo,
-		Version:   cniVersion,
-	}).Manifests("")
 	configYamlPath := filepath.Join(v1.DefaultConfigPath, "cni.yaml")
 	logger.Debug("cni yaml path is : ", configYamlPath)
 	_ = ioutil.WriteFile(configYamlPath, []byte(netyaml), 0755)
 	v1.SSHConfig.Copy(s.Masters[0], configYamlPath, "/tmp/cni.yaml")
-	v1.SSHConfig.Cmd(s.Masters[0], "kubectl apply -f /tmp/cni.yaml")
+	if err := v1.SSHConfig.CmdAsync(s.Masters[0], cniApplyCmd); err != nil {
+		logger.Error("apply cni manifests err: %s", err)
+		os.Exit(1)
+	}
 }
 
 //SendKubeConfigs
@@ -257,7 +267,7 @@ func (s *SealosInstaller) SendKubeConfigs(masters []string) {
 	s.sendKubeConfigFile(masters, "controller-manager.conf")
 	s.sendKubeConfigFile(masters, "scheduler.conf")
 
-	if s.to11911192(masters) {
+	if to11911192(masters) {
 		logger.Info("set 1191 1192 config")
 	}
 }
@@ -266,12 +276,14 @@ func (s *SealosInstaller) SendJoinMasterKubeConfigs(masters []string) {
 	s.sendKubeConfigFile(masters, "admin.conf")
 	s.sendKubeConfigFile(masters, "controller-manager.conf")
 	s.sendKubeConfigFile(masters, "scheduler.conf")
-	if s.to11911192(masters) {
+	if to11911192(masters) {
 		logger.Info("set 1191 1192 config")
 	}
 }
 
-func (s *SealosInstaller) to11911192(masters []string) (to11911192 bool) {
+// to11911192 point the kubeconfigs of kube-controller-manager and kube-scheduler on the masters to themselves,
+// return true if the version is 1.19.1 or 1.19.2.
+func to11911192(masters []string) bool {
 	// fix > 1.19.1 kube-controller-manager and kube-scheduler use the LocalAPIEndpoint instead of the ControlPlaneEndpoint.
 	if utils.VersionToIntAll(v1.Version) >= 1191 && utils.VersionToIntAll(v1.Version) <= 1192 {
 		for _, v := range masters {
@@ -284,9 +296,7 @@ sed -i 's/%s/%s/' %s`, v1.APIServer, KUBESCHEDULERCONFIGFILE,
 				v1.APIServer, ip, KUBESCHEDULERCONFIGFILE)
 			_ = v1.SSHConfig.CmdAsync(v, cmd)
 		}
-		to11911192 = true
-	} else {
-		to11911192 = false
+		return true
 	}
-	return
+	return false
 }
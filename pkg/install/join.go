@@ -26,8 +26,6 @@ import (
 	"github.com/fanux/sealos/pkg/utils/ssh"
 
 	cert "github.com/fanux/sealos/pkg/kubernetes/cert"
-
-	"github.com/fanux/sealos/pkg/ipvs"
 )
 
 //BuildJoin is
@@ -50,13 +48,15 @@ func joinMastersFunc(joinMasters []string) {
 		APIServer: v1.APIServer,
 	}
 	i.CheckValid()
+	i.CheckNetworkHosts()
 	i.SendSealos()
 	i.SendPackage()
+	i.SendRegistryConfig()
 	i.GeneratorCerts()
 	i.JoinMasters(joinMasters)
 	//master join to MasterIPs
 	v1.MasterIPs = append(v1.MasterIPs, joinMasters...)
-	i.lvscare()
+	i.syncLoadBalancer()
 }
 
 //joinNodesFunc is join nodes func
@@ -69,8 +69,10 @@ func joinNodesFunc(joinNodes []string) {
 		Nodes:   nodes,
 	}
 	i.CheckValid()
+	i.CheckNetworkHosts()
 	i.SendSealos()
 	i.SendPackage()
+	i.SendRegistryConfig()
 	i.GeneratorToken()
 	i.JoinNodes()
 	//node join to NodeIPs
@@ -127,6 +129,14 @@ func (s *SealosInstaller) JoinMasters(masters []string) {
 	s.sendNewCertAndKey(masters)
 	// send CP nodes configuration
 	s.sendJoinCPConfig(masters)
+	// the load balancer forwards to the existing and joining masters
+	lbMasters := append([]string{}, s.Masters...)
+	for _, master := range masters {
+		if utils.NotIn(master, lbMasters) {
+			lbMasters = append(lbMasters, master)
+		}
+	}
+	s.sendLBManifests(masters, lbMasters)
 
 	//join master do sth
 	cmd := s.Command(v1.Version, JoinMaster)
@@ -135,7 +145,7 @@ func (s *SealosInstaller) JoinMasters(masters []string) {
 		go func(master string) {
 			defer wg.Done()
 			hostname := ssh.RemoteHostName(v1.SSHConfig, master)
-			certCMD := CMD(v1.APIServerCertSANs, utils.IPFormat(master), hostname, v1.SvcCIDR, v1.DNSDomain)
+			certCMD := CMD(v1.APIServerCertSANs, utils.IPFormat(master), hostname, v1.SvcCIDR, v1.DNSDomain, v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm)
 			_ = v1.SSHConfig.CmdAsync(master, certCMD)
 
 			cmdHosts := fmt.Sprintf("echo %s >> /etc/hosts", getApiserverHost(utils.IPFormat(s.Masters[0])))
@@ -155,12 +165,13 @@ func (s *SealosInstaller) JoinMasters(masters []string) {
 
 //JoinNodes is
 func (s *SealosInstaller) JoinNodes() {
-	var masters string
+	var masters []string
 	var wg sync.WaitGroup
 	for _, master := range s.Masters {
-		masters += fmt.Sprintf(" --rs %s:6443", utils.IPFormat(master))
+		masters = append(masters, fmt.Sprintf("%s:%d", utils.IPFormat(master), v1.APIServerPort))
 	}
-	ipvsCmd := fmt.Sprintf("sealos ipvs --vs %s:6443 %s --health-path /healthz --health-schem https --run-once", v1.VIP, masters)
+	// the same health check as the lvscare static pod
+	ipvsCmd := v1.Lvscare.RunOnceCmd(fmt.Sprintf("%s:%d", v1.VIP, v1.APIServerPort), masters)
 	for _, node := range s.Nodes {
 		wg.Add(1)
 		go func(node string) {
@@ -174,22 +185,27 @@ func (s *SealosInstaller) JoinNodes() {
 			cmdHosts := fmt.Sprintf("echo %s %s >> /etc/hosts", v1.VIP, v1.APIServer)
 			_ = v1.SSHConfig.CmdAsync(node, cmdHosts)
 
+			cmd := s.Command(v1.Version, JoinNode)
+			// the VIP of the other modes is not on the node, no route and ipvs rules
+			if !v1.LoadBalancer.IsIPVS() {
+				_ = v1.SSHConfig.CmdAsync(node, cmd)
+				_ = v1.SSHConfig.CmdAsync(node, `rm -rf /root/kube`)
+				return
+			}
+
 			// 如果不是默认路由， 则添加 vip 到 master的路由。
 			cmdRoute := fmt.Sprintf("sealos route --host %s", utils.IPFormat(node))
 			status := v1.SSHConfig.CmdToString(node, cmdRoute, "")
 			if status != "ok" {
 				// 以自己的ip作为路由网关
-				addRouteCmd := fmt.Sprintf("sealos route add --host %s --gateway %s", v1.VIP, utils.IPFormat(node))
+				addRouteCmd := fmt.Sprintf("sealos route add --host %s --gateway %s --persist", v1.VIP, utils.IPFormat(node))
 				v1.SSHConfig.CmdToString(node, addRouteCmd, "")
 			}
 
 			_ = v1.SSHConfig.CmdAsync(node, ipvsCmd) // create ipvs rules before we join node
-			cmd := s.Command(v1.Version, JoinNode)
 			//create lvscare static pod
-			yaml := ipvs.LvsStaticPodYaml(v1.VIP, v1.MasterIPs, v1.LvscareImage)
 			_ = v1.SSHConfig.CmdAsync(node, cmd)
-			_ = v1.SSHConfig.Cmd(node, "mkdir -p /etc/kubernetes/manifests")
-			v1.SSHConfig.CopyConfigFile(node, "/etc/kubernetes/manifests/kube-sealyun-lvscare.yaml", []byte(yaml))
+			sendLvscare(node, lvscareYaml())
 
 			cleaninstall := `rm -rf /root/kube`
 			_ = v1.SSHConfig.CmdAsync(node, cleaninstall)
@@ -199,21 +215,6 @@ func (s *SealosInstaller) JoinNodes() {
 	wg.Wait()
 }
 
-func (s *SealosInstaller) lvscare() {
-	var wg sync.WaitGroup
-	for _, node := range s.Nodes {
-		wg.Add(1)
-		go func(node string) {
-			defer wg.Done()
-			yaml := ipvs.LvsStaticPodYaml(v1.VIP, v1.MasterIPs, v1.LvscareImage)
-			_ = v1.SSHConfig.Cmd(node, "rm -rf  /etc/kubernetes/manifests/kube-sealyun-lvscare* || :")
-			v1.SSHConfig.CopyConfigFile(node, "/etc/kubernetes/manifests/kube-sealyun-lvscare.yaml", []byte(yaml))
-		}(node)
-	}
-
-	wg.Wait()
-}
-
 func (s *SealosInstaller) sendNewCertAndKey(Hosts []string) {
 	var wg sync.WaitGroup
 	for _, node := range Hosts {
@@ -0,0 +1,245 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fanux/sealos/pkg/logger"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+
+	"github.com/fanux/sealos/pkg/kubernetes/apiclient"
+	"github.com/fanux/sealos/pkg/kubernetes/cert"
+	"github.com/fanux/sealos/pkg/kubernetes/nodeclient"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/client-go/util/keyutil"
+)
+
+const (
+	KubeconfigClusterName = "kubernetes"
+	// KubeconfigManagedByLabel mark the ServiceAccounts and ClusterRoleBindings created by sealos kubeconfig.
+	KubeconfigManagedByLabel = "sealos.io/kubeconfig"
+	// DefaultKubeconfigTTL is the validity of the user client cert or token, far shorter than the cluster certs.
+	DefaultKubeconfigTTL = 30 * 24 * time.Hour
+)
+
+type KubeconfigFlags struct {
+	User        string
+	Groups      []string
+	TTL         time.Duration
+	Server      string
+	ClusterRole string
+	// Token create a ServiceAccount token instead of a client cert.
+	Token     bool
+	Namespace string
+	Output    string
+
+	// Endpoint is vip, eip or a custom apiserver address, used by Get.
+	Endpoint  string
+	InfraFile string
+	Context   string
+
+	Client kubernetes.Interface
+	v1.SealConfig
+}
+
+// GetKubeconfigFlags load the sealos config, exit if failed.
+func GetKubeconfigFlags(cfgFile string) *KubeconfigFlags {
+	k := &KubeconfigFlags{}
+	if err := k.Load(cfgFile); err != nil {
+		logger.Error(err)
+		k.ShowDefaultConfig()
+		os.Exit(0)
+	}
+	return k
+}
+
+// Create issue a kubeconfig for the user and write it to the output file.
+func (k *KubeconfigFlags) Create() error {
+	if k.User == "" {
+		return fmt.Errorf("user can not be empty")
+	}
+	if k.TTL <= 0 {
+		return fmt.Errorf("ttl must be greater than 0, got %s", k.TTL)
+	}
+	if k.Server == "" {
+		if len(k.Masters) == 0 {
+			return fmt.Errorf("masters is empty, please specify the server")
+		}
+		k.Server = fmt.Sprintf("https://%s:%d", utils.IPFormat(k.Masters[0]), k.APIServerPort)
+	}
+	if k.Output == "" {
+		k.Output = k.User + ".kubeconfig"
+	}
+	caData, err := ioutil.ReadFile(filepath.Join(v1.CertPath, "ca.crt"))
+	if err != nil {
+		return fmt.Errorf("read cluster ca err: %w", err)
+	}
+	if k.Token || k.ClusterRole != "" {
+		if !utils.FileExist(nodeclient.KubeDefaultConfigPath) {
+			return fmt.Errorf("KubeDefaultConfigPath %s is not exist", nodeclient.KubeDefaultConfigPath)
+		}
+		if k.Client, err = nodeclient.NewClient(nodeclient.KubeDefaultConfigPath, nil); err != nil {
+			return fmt.Errorf("get k8s client err: %w", err)
+		}
+	}
+
+	var config *clientcmdapi.Config
+	subject := rbacv1.Subject{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: k.User}
+	if k.Token {
+		token, err := k.serviceAccountToken()
+		if err != nil {
+			return err
+		}
+		config = cert.CreateWithToken(k.Server, KubeconfigClusterName, k.User, caData, token)
+		subject = rbacv1.Subject{Kind: rbacv1.ServiceAccountKind, Namespace: k.Namespace, Name: k.User}
+	} else {
+		if config, err = k.clientCertConfig(caData); err != nil {
+			return err
+		}
+	}
+	if k.ClusterRole != "" {
+		if err = k.bindClusterRole(subject); err != nil {
+			return err
+		}
+	}
+	if err = cert.WriteToDisk(k.Output, config); err != nil {
+		return err
+	}
+	logger.Info("kubeconfig of %s is written to %s", k.User, k.Output)
+	return nil
+}
+
+// clientCertConfig sign a client cert by the cluster ca in sealos pki dir, the groups are the organizations of the cert.
+func (k *KubeconfigFlags) clientCertConfig(caData []byte) (*clientcmdapi.Config, error) {
+	options, err := NewCertOptions(v1.CAValidity, v1.CertValidity, v1.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	caCert, caKey, err := cert.LoadCaCertAndKeyFromDisk(cert.Config{Path: v1.CertPath, BaseName: "ca"})
+	if err != nil {
+		return nil, fmt.Errorf("load cluster ca err: %w", err)
+	}
+	clientCert, clientKey, err := cert.NewCaCertAndKeyFromRoot(cert.Config{
+		CommonName:   k.User,
+		Organization: k.Groups,
+		Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		Validity:     k.TTL,
+		KeyAlgorithm: options.KeyAlgorithm,
+	}, caCert, caKey)
+	if err != nil {
+		return nil, err
+	}
+	keyData, err := keyutil.MarshalPrivateKeyToPEM(clientKey)
+	if err != nil {
+		return nil, err
+	}
+	return cert.CreateWithCerts(k.Server, KubeconfigClusterName, k.User, caData, keyData, cert.EncodeCertPEM(clientCert)), nil
+}
+
+// serviceAccountToken create the ServiceAccount and request a token by the TokenRequest API,
+// fallback to the legacy token secret if the API is not enabled, the ttl is not honoured then.
+func (k *KubeconfigFlags) serviceAccountToken() (string, error) {
+	sa := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      k.User,
+			Namespace: k.Namespace,
+			Labels:    map[string]string{KubeconfigManagedByLabel: "true"},
+		},
+	}
+	if err := apiclient.CreateOrUpdateServiceAccount(k.Client, sa); err != nil {
+		return "", err
+	}
+	seconds := int64(k.TTL.Seconds())
+	tokenRequest := &authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{ExpirationSeconds: &seconds}}
+	tr, err := k.Client.CoreV1().ServiceAccounts(k.Namespace).CreateToken(context.TODO(), k.User, tokenRequest, metav1.CreateOptions{})
+	if err == nil {
+		return tr.Status.Token, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("request token for ServiceAccount %s/%s err: %w", k.Namespace, k.User, err)
+	}
+	logger.Warn("TokenRequest API is not enabled, use the legacy token secret without expiration")
+	return k.legacyServiceAccountToken()
+}
+
+func (k *KubeconfigFlags) legacyServiceAccountToken() (string, error) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        k.User + "-token",
+			Namespace:   k.Namespace,
+			Labels:      map[string]string{KubeconfigManagedByLabel: "true"},
+			Annotations: map[string]string{corev1.ServiceAccountNameKey: k.User},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+	// the token is filled by the token controller, do not update the existing secret.
+	if _, err := k.Client.CoreV1().Secrets(k.Namespace).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("create secret %s/%s err: %w", k.Namespace, secret.Name, err)
+	}
+	var token string
+	err := wait.PollImmediate(apiclient.APICallRetryInterval, 30*time.Second, func() (bool, error) {
+		s, err := k.Client.CoreV1().Secrets(k.Namespace).Get(context.TODO(), secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, nil
+		}
+		token = string(s.Data[corev1.ServiceAccountTokenKey])
+		return token != "", nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("wait for token of secret %s/%s err: %w", k.Namespace, secret.Name, err)
+	}
+	return token, nil
+}
+
+func (k *KubeconfigFlags) bindClusterRole(subject rbacv1.Subject) error {
+	name := fmt.Sprintf("sealos:%s:%s", subject.Name, k.ClusterRole)
+	if subject.Kind == rbacv1.ServiceAccountKind {
+		name = fmt.Sprintf("sealos:%s:%s:%s", subject.Namespace, subject.Name, k.ClusterRole)
+	}
+	binding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{KubeconfigManagedByLabel: "true"},
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     k.ClusterRole,
+		},
+		Subjects: []rbacv1.Subject{subject},
+	}
+	if err := apiclient.CreateOrUpdateClusterRoleBinding(k.Client, binding); err != nil {
+		return err
+	}
+	logger.Info("ClusterRole %s is bound to %s %s by ClusterRoleBinding %s", k.ClusterRole, subject.Kind, subject.Name, name)
+	return nil
+}
@@ -0,0 +1,193 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fanux/sealos/pkg/logger"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	v2 "github.com/fanux/sealos/pkg/types/v1beta1"
+	"github.com/fanux/sealos/pkg/utils"
+
+	"github.com/fanux/sealos/pkg/kubernetes/cert"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	KubeconfigEndpointVIP     = "vip"
+	KubeconfigEndpointEIP     = "eip"
+	KubeconfigEndpointMaster0 = "master0"
+
+	DefaultKubeconfigContext = "sealos"
+)
+
+var DefaultLocalKubeconfig = filepath.Join(utils.UserHomeDir(), ".kube", "config")
+
+// Get fetch the admin kubeconfig from master0, rewrite the server to the endpoint
+// and merge it into the local kubeconfig under the context.
+func (k *KubeconfigFlags) Get() error {
+	if len(k.Masters) == 0 {
+		return fmt.Errorf("masters is empty")
+	}
+	if k.Context == "" {
+		k.Context = DefaultKubeconfigContext
+	}
+	if k.Output == "" {
+		k.Output = DefaultLocalKubeconfig
+	}
+	server, err := k.endpointServer()
+	if err != nil {
+		return err
+	}
+	k.checkEndpointSAN(server)
+
+	tmpDir, err := ioutil.TempDir("", "sealos-kubeconfig-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+	adminConf := filepath.Join(tmpDir, "admin.conf")
+	v1.SSHConfig.CopyRemoteFileToLocal(k.Masters[0], adminConf, filepath.Join(cert.KubernetesDir, "admin.conf"))
+	admin, err := clientcmd.LoadFromFile(adminConf)
+	if err != nil {
+		return fmt.Errorf("load admin kubeconfig from %s err: %w", k.Masters[0], err)
+	}
+
+	local := clientcmdapi.NewConfig()
+	if utils.FileExist(k.Output) {
+		if local, err = clientcmd.LoadFromFile(k.Output); err != nil {
+			return fmt.Errorf("load local kubeconfig %s err: %w", k.Output, err)
+		}
+	}
+	if err = mergeAdminKubeconfig(local, admin, k.Context, server); err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(k.Output), 0700); err != nil {
+		return err
+	}
+	if err = clientcmd.WriteToFile(*local, k.Output); err != nil {
+		return err
+	}
+	logger.Info("context %s with server %s is merged into %s", k.Context, server, k.Output)
+	if local.CurrentContext != k.Context {
+		logger.Info("run `kubectl config use-context %s` to use it", k.Context)
+	}
+	return nil
+}
+
+// endpointServer return the apiserver url of the endpoint, the endpoint is vip, eip, master0 or a custom address.
+// The default endpoint is master0 in ipvs mode, the vip is a virtual server on every node that is not reachable
+// outside the cluster, and the vip of the other load balancer modes.
+func (k *KubeconfigFlags) endpointServer() (string, error) {
+	var host string
+	port := k.LoadBalancer.Port
+	endpoint := k.Endpoint
+	if endpoint == "" {
+		endpoint = KubeconfigEndpointVIP
+		if k.LoadBalancer.IsIPVS() {
+			endpoint = KubeconfigEndpointMaster0
+		}
+	}
+	switch endpoint {
+	case KubeconfigEndpointVIP:
+		if k.LoadBalancer.IsIPVS() {
+			logger.Warn("vip %s of ipvs mode is only reachable on the cluster nodes", k.VIP)
+		}
+		host = k.VIP
+	case KubeconfigEndpointMaster0:
+		host, port = utils.IPFormat(k.Masters[0]), k.APIServerPort
+	case KubeconfigEndpointEIP:
+		if k.InfraFile == "" {
+			return "", fmt.Errorf("infra file is required by the eip endpoint")
+		}
+		data, err := ioutil.ReadFile(k.InfraFile)
+		if err != nil {
+			return "", err
+		}
+		infra := &v2.Infra{}
+		if err = yaml.Unmarshal(data, infra); err != nil {
+			return "", fmt.Errorf("unmarshal infra %s err: %w", k.InfraFile, err)
+		}
+		if infra.Status.Cluster.EIP == "" {
+			return "", fmt.Errorf("eip is not found in the status of infra %s", k.InfraFile)
+		}
+		// the eip is bound to master0, the security group exports the apiserver port.
+		host, port = infra.Status.Cluster.EIP, k.APIServerPort
+	default:
+		if strings.Contains(k.Endpoint, "://") {
+			return k.Endpoint, nil
+		}
+		if _, _, err := net.SplitHostPort(k.Endpoint); err == nil {
+			return "https://" + k.Endpoint, nil
+		}
+		host = k.Endpoint
+	}
+	return "https://" + net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
+
+// checkEndpointSAN warn if the endpoint host is not in the apiserver cert, kubectl will fail on tls verify.
+func (k *KubeconfigFlags) checkEndpointSAN(server string) {
+	u, err := url.Parse(server)
+	if err != nil {
+		return
+	}
+	sans := append([]string{"127.0.0.1", k.APIServerDomain, k.VIP}, k.APIServerCertSANs...)
+	for _, master := range k.Masters {
+		sans = append(sans, utils.IPFormat(master))
+	}
+	if utils.NotIn(u.Hostname(), sans) {
+		logger.Warn("%s is not in the apiserver cert SANs, add it by `sealos cert add-san %s`", u.Hostname(), u.Hostname())
+	}
+}
+
+// mergeAdminKubeconfig add the cluster, user and context of the admin kubeconfig to the local kubeconfig,
+// all named by the context, the other entries of the local kubeconfig are kept.
+func mergeAdminKubeconfig(local, admin *clientcmdapi.Config, contextName, server string) error {
+	adminContext, ok := admin.Contexts[admin.CurrentContext]
+	if !ok {
+		return fmt.Errorf("current context %s is not found in admin kubeconfig", admin.CurrentContext)
+	}
+	cluster, ok := admin.Clusters[adminContext.Cluster]
+	if !ok {
+		return fmt.Errorf("cluster %s is not found in admin kubeconfig", adminContext.Cluster)
+	}
+	authInfo, ok := admin.AuthInfos[adminContext.AuthInfo]
+	if !ok {
+		return fmt.Errorf("user %s is not found in admin kubeconfig", adminContext.AuthInfo)
+	}
+	cluster = cluster.DeepCopy()
+	cluster.Server = server
+	local.Clusters[contextName] = cluster
+	local.AuthInfos[contextName] = authInfo.DeepCopy()
+	context := adminContext.DeepCopy()
+	context.Cluster, context.AuthInfo = contextName, contextName
+	local.Contexts[contextName] = context
+	if local.CurrentContext == "" {
+		local.CurrentContext = contextName
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestMergeAdminKubeconfig(t *testing.T) {
+	admin := clientcmdapi.NewConfig()
+	admin.Clusters["kubernetes"] = &clientcmdapi.Cluster{Server: "https://apiserver.cluster.local:6443", CertificateAuthorityData: []byte("ca")}
+	admin.AuthInfos["kubernetes-admin"] = &clientcmdapi.AuthInfo{Token: "admin"}
+	admin.Contexts["kubernetes-admin@kubernetes"] = &clientcmdapi.Context{Cluster: "kubernetes", AuthInfo: "kubernetes-admin"}
+	admin.CurrentContext = "kubernetes-admin@kubernetes"
+
+	local := clientcmdapi.NewConfig()
+	local.Clusters["other"] = &clientcmdapi.Cluster{Server: "https://other:6443"}
+	local.AuthInfos["other"] = &clientcmdapi.AuthInfo{Token: "other"}
+	local.Contexts["other"] = &clientcmdapi.Context{Cluster: "other", AuthInfo: "other"}
+	local.CurrentContext = "other"
+
+	if err := mergeAdminKubeconfig(local, admin, "prod", "https://10.103.97.2:6443"); err != nil {
+		t.Fatal(err)
+	}
+	if local.CurrentContext != "other" || local.Contexts["other"] == nil || local.Clusters["other"].Server != "https://other:6443" {
+		t.Errorf("other entries are clobbered: %+v", local)
+	}
+	if ctx := local.Contexts["prod"]; ctx == nil || ctx.Cluster != "prod" || ctx.AuthInfo != "prod" {
+		t.Errorf("context prod = %+v", ctx)
+	}
+	if c := local.Clusters["prod"]; c == nil || c.Server != "https://10.103.97.2:6443" || string(c.CertificateAuthorityData) != "ca" {
+		t.Errorf("cluster prod = %+v", c)
+	}
+	if admin.Clusters["kubernetes"].Server != "https://apiserver.cluster.local:6443" {
+		t.Errorf("admin kubeconfig is changed")
+	}
+}
+
+func TestEndpointServer(t *testing.T) {
+	k := &KubeconfigFlags{}
+	k.VIP = "10.103.97.2"
+	k.Masters = []string{"192.168.0.2:22"}
+	k.APIServerPort = 6443
+	k.LoadBalancer.Port = 6443
+	for endpoint, want := range map[string]string{
+		"":                       "https://192.168.0.2:6443",
+		"master0":                "https://192.168.0.2:6443",
+		"vip":                    "https://10.103.97.2:6443",
+		"lb.sealyun.com":         "https://lb.sealyun.com:6443",
+		"lb.sealyun.com:443":     "https://lb.sealyun.com:443",
+		"https://lb.sealyun.com": "https://lb.sealyun.com",
+	} {
+		k.Endpoint = endpoint
+		got, err := k.endpointServer()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("endpointServer(%q) = %s, want %s", endpoint, got, want)
+		}
+	}
+	k.LoadBalancer.Mode = v1.LBModeKeepalived
+	k.LoadBalancer.Port = 8443
+	k.Endpoint = ""
+	if got, _ := k.endpointServer(); got != "https://10.103.97.2:8443" {
+		t.Errorf("endpointServer() of keepalived mode = %s, want the vip and load balancer port 8443", got)
+	}
+	k.Endpoint = "eip"
+	if _, err := k.endpointServer(); err == nil {
+		t.Errorf("eip endpoint without infra file should fail")
+	}
+	k.InfraFile = filepath.Join(t.TempDir(), "infra.yaml")
+	if err := ioutil.WriteFile(k.InfraFile, []byte("status:\n  cluster:\n    eip: 47.0.0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	k.APIServerPort = 7443
+	if got, err := k.endpointServer(); err != nil || got != "https://47.0.0.1:7443" {
+		t.Errorf("endpointServer() of eip = %s, %v, want the eip and apiserver port 7443", got, err)
+	}
+}
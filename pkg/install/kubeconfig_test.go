@@ -0,0 +1,157 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"context"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+
+	"github.com/fanux/sealos/pkg/kubernetes/cert"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func TestKubeconfigCreateTTL(t *testing.T) {
+	for _, ttl := range []time.Duration{0, -time.Hour} {
+		k := &KubeconfigFlags{User: "alice", TTL: ttl}
+		if err := k.Create(); err == nil || !strings.Contains(err.Error(), "ttl") {
+			t.Errorf("Create() with ttl %s err = %v, want ttl error", ttl, err)
+		}
+	}
+}
+
+func TestClientCertConfig(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "sealos-kubeconfig-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	defer func(certPath, algorithm string) { v1.CertPath, v1.KeyAlgorithm = certPath, algorithm }(v1.CertPath, v1.KeyAlgorithm)
+	v1.CertPath, v1.KeyAlgorithm = tmpDir, "ecdsa"
+	caCfg := cert.Config{Path: tmpDir, BaseName: "ca", CommonName: "kubernetes", KeyAlgorithm: x509.ECDSA}
+	caCert, caKey, err := cert.NewCaCertAndKey(caCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cert.WriteCertAndKey(tmpDir, "ca", caCert, caKey); err != nil {
+		t.Fatal(err)
+	}
+
+	k := &KubeconfigFlags{User: "alice", Groups: []string{"dev"}, TTL: DefaultKubeconfigTTL, Server: "https://192.168.0.2:6443"}
+	config, err := k.clientCertConfig(cert.EncodeCertPEM(caCert))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authInfo, ok := config.AuthInfos["alice"]
+	if !ok {
+		t.Fatalf("user alice is not in kubeconfig %+v", config.AuthInfos)
+	}
+	certs, err := certutil.ParseCertsPEM(authInfo.ClientCertificateData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := certs[0]
+	if c.Subject.CommonName != "alice" || !reflect.DeepEqual(c.Subject.Organization, []string{"dev"}) {
+		t.Errorf("client cert subject = %+v", c.Subject)
+	}
+	if !reflect.DeepEqual(c.ExtKeyUsage, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}) {
+		t.Errorf("client cert usages = %v", c.ExtKeyUsage)
+	}
+	if d := time.Until(c.NotAfter) - DefaultKubeconfigTTL; d > time.Minute || d < -time.Minute {
+		t.Errorf("client cert expires at %s, want after the ttl %s", c.NotAfter, DefaultKubeconfigTTL)
+	}
+	if err = c.CheckSignatureFrom(caCert); err != nil {
+		t.Errorf("client cert is not signed by the cluster ca: %v", err)
+	}
+}
+
+func TestBindClusterRole(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	k := &KubeconfigFlags{ClusterRole: "view", Client: client}
+	subjects := map[string]rbacv1.Subject{
+		"sealos:alice:view":      {Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "alice"},
+		"sealos:default:ci:view": {Kind: rbacv1.ServiceAccountKind, Namespace: "default", Name: "ci"},
+	}
+	for name, subject := range subjects {
+		if err := k.bindClusterRole(subject); err != nil {
+			t.Fatal(err)
+		}
+		binding, err := client.RbacV1().ClusterRoleBindings().Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("ClusterRoleBinding %s err: %v", name, err)
+		}
+		if binding.RoleRef.Name != "view" || !reflect.DeepEqual(binding.Subjects, []rbacv1.Subject{subject}) || binding.Labels[KubeconfigManagedByLabel] != "true" {
+			t.Errorf("ClusterRoleBinding %s = %+v", name, binding)
+		}
+	}
+}
+
+func TestServiceAccountToken(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	var expiration int64
+	client.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		tr := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+		expiration = *tr.Spec.ExpirationSeconds
+		return true, &authenticationv1.TokenRequest{Status: authenticationv1.TokenRequestStatus{Token: "bound-token"}}, nil
+	})
+	k := &KubeconfigFlags{User: "ci", Namespace: "default", TTL: time.Hour, Client: client}
+	token, err := k.serviceAccountToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "bound-token" || expiration != 3600 {
+		t.Errorf("serviceAccountToken() = %s with expiration %d, want bound-token with 3600", token, expiration)
+	}
+
+	// fallback to the legacy token secret if the TokenRequest API is not enabled.
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "ci-token", Namespace: "default"},
+		Data:       map[string][]byte{corev1.ServiceAccountTokenKey: []byte("legacy-token")},
+	}
+	client = fake.NewSimpleClientset(secret)
+	client.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "serviceaccounts/token"}, "ci")
+	})
+	k.Client = client
+	if token, err = k.serviceAccountToken(); err != nil || token != "legacy-token" {
+		t.Errorf("serviceAccountToken() fallback = %s, %v, want legacy-token", token, err)
+	}
+	if _, err = client.CoreV1().ServiceAccounts("default").Get(context.TODO(), "ci", metav1.GetOptions{}); err != nil {
+		t.Errorf("ServiceAccount ci should be created: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fanux/sealos/pkg/lb"
+	"github.com/fanux/sealos/pkg/logger"
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+)
+
+// lbIgnorePreflight ignore the non-empty manifests dir, the load balancer static pods are placed before kubeadm init and join.
+const lbIgnorePreflight = " --ignore-preflight-errors=DirAvailable--etc-kubernetes-manifests"
+
+// CheckLoadBalancer check the load balancer mode and set the default port and images.
+func CheckLoadBalancer() error {
+	l := &v1.LoadBalancer
+	if l.Mode == "" {
+		l.Mode = v1.LBModeIPVS
+	}
+	if !utils.InList(l.Mode, v1.LBModes) {
+		return fmt.Errorf("load balancer mode %s is not supported, must be one of %s", l.Mode, strings.Join(v1.LBModes, ", "))
+	}
+	if net.ParseIP(v1.VIP) == nil {
+		return fmt.Errorf("vip %s must be an ip in %s mode", v1.VIP, l.Mode)
+	}
+	if l.OnMasters() {
+		for _, master := range v1.MasterIPs {
+			if utils.IPFormat(master) == v1.VIP {
+				return fmt.Errorf("vip %s floating on the masters must not be the ip of a master", v1.VIP)
+			}
+		}
+	}
+	if l.Port == 0 {
+		l.Port = v1.APIServerPort
+		if l.Mode == v1.LBModeKeepalived {
+			l.Port = lb.DefaultHAProxyPort
+		}
+	}
+	if l.Port < 0 || l.Port > 65535 {
+		return fmt.Errorf("load balancer port %d is invalid, must be in 1-65535", l.Port)
+	}
+	if l.Mode == v1.LBModeIPVS {
+		if err := v1.Lvscare.Validate(); err != nil {
+			return err
+		}
+	}
+	switch l.Mode {
+	// the masters resolve the apiserver domain to themselves, the control plane endpoint port must be served by the apiserver.
+	case v1.LBModeIPVS, v1.LBModeKubeVIP, v1.LBModeExternal:
+		if l.Port != v1.APIServerPort {
+			return fmt.Errorf("load balancer port %d must be the apiserver port %d in %s mode", l.Port, v1.APIServerPort, l.Mode)
+		}
+	case v1.LBModeKeepalived:
+		if l.Port == v1.APIServerPort {
+			return fmt.Errorf("load balancer port %d is taken by the apiserver on the masters, haproxy must listen on another port", l.Port)
+		}
+		if l.KeepalivedImage == "" {
+			l.KeepalivedImage = lb.DefaultKeepalivedImage
+		}
+		if l.HAProxyImage == "" {
+			l.HAProxyImage = lb.DefaultHAProxyImage
+		}
+	}
+	if l.Mode == v1.LBModeKubeVIP && l.KubeVIPImage == "" {
+		l.KubeVIPImage = lb.DefaultKubeVIPImage
+	}
+	return nil
+}
+
+// lbImages return the images of the load balancer static pods on the masters.
+func lbImages(l v1.LoadBalancerConfig) []string {
+	switch l.Mode {
+	case v1.LBModeKeepalived:
+		return []string{l.KeepalivedImage, l.HAProxyImage}
+	case v1.LBModeKubeVIP:
+		return []string{l.KubeVIPImage}
+	default:
+		return nil
+	}
+}
+
+// lbInterface return the NIC holding the VIP on the master, default the NIC of the master ip.
+func lbInterface(master string) (string, error) {
+	if v1.LoadBalancer.Interface != "" {
+		return v1.LoadBalancer.Interface, nil
+	}
+	cmd := fmt.Sprintf(`ip -o -4 addr show | awk '$4 ~ "^%s/" {print $2; exit}'`, utils.IPFormat(master))
+	iface := strings.TrimSpace(v1.SSHConfig.CmdToString(master, cmd, ""))
+	if iface == "" {
+		return "", fmt.Errorf("NIC of the ip %s is not found, set it by --lb-interface", utils.IPFormat(master))
+	}
+	return iface, nil
+}
+
+// lbManifests return the static pods and configs of the load balancer on the master, configs are in front of the static pods.
+func lbManifests(master, iface string, masters []string) ([]string, map[string]string) {
+	md := lb.MetaData{
+		VIP:           v1.VIP,
+		Port:          v1.LoadBalancer.Port,
+		APIServerPort: v1.APIServerPort,
+		Master:        utils.IPFormat(master),
+		Interface:     iface,
+		Priority:      100,
+		RouterID:      lb.RouterID(v1.VIP),
+	}
+	for i, m := range masters {
+		md.Masters = append(md.Masters, utils.IPFormat(m))
+		if md.Masters[i] == md.Master {
+			md.Priority = 100 - i
+		}
+	}
+	var files map[string]string
+	switch v1.LoadBalancer.Mode {
+	case v1.LBModeKeepalived:
+		files = lb.KeepalivedManifests(md, v1.LoadBalancer.KeepalivedImage, v1.LoadBalancer.HAProxyImage)
+	case v1.LBModeKubeVIP:
+		files = lb.KubeVIPManifests(md, v1.LoadBalancer.KubeVIPImage)
+	}
+	var paths []string
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		pi, pj := strings.HasPrefix(paths[i], lb.ManifestsDir), strings.HasPrefix(paths[j], lb.ManifestsDir)
+		if pi != pj {
+			return pj
+		}
+		return paths[i] < paths[j]
+	})
+	return paths, files
+}
+
+// sendLBManifests send the load balancer static pods to the hosts, masters is all the masters of the cluster. exit if failed.
+func (s *SealosInstaller) sendLBManifests(hosts, masters []string) {
+	if !v1.LoadBalancer.OnMasters() {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			iface, err := lbInterface(host)
+			if err != nil {
+				logger.Error("[%s] %s", host, err)
+				os.Exit(1)
+			}
+			paths, files := lbManifests(host, iface, masters)
+			_ = v1.SSHConfig.CmdAsync(host, fmt.Sprintf("mkdir -p %s %s", lb.ConfigDir, lb.ManifestsDir))
+			for _, path := range paths {
+				v1.SSHConfig.CopyConfigFile(host, path, []byte(files[path]))
+			}
+		}(host)
+	}
+	wg.Wait()
+}
+
+// syncLoadBalancer update the load balancer after the masters changed, the VIP forwards to v1.MasterIPs.
+func (s *SealosInstaller) syncLoadBalancer() {
+	switch v1.LoadBalancer.Mode {
+	case v1.LBModeKeepalived:
+		s.sendLBManifests(v1.MasterIPs, v1.MasterIPs)
+	case v1.LBModeExternal:
+		logger.Warn("update the backends of the external load balancer %s to the masters %v by yourself", v1.VIP, v1.MasterIPs)
+	case v1.LBModeKubeVIP:
+	default:
+		yaml := lvscareYaml()
+		var wg sync.WaitGroup
+		for _, node := range v1.NodeIPs {
+			wg.Add(1)
+			go func(node string) {
+				defer wg.Done()
+				sendLvscare(node, yaml)
+			}(node)
+		}
+		wg.Wait()
+	}
+}
+
+// lbCleanCmd return the cmd removing the VIP left on the NIC of the master.
+func lbCleanCmd() string {
+	if !v1.LoadBalancer.OnMasters() {
+		return ""
+	}
+	return fmt.Sprintf(`for dev in $(ip -o -4 addr show | awk '$4 == "%[1]s/32" {print $2}'); do ip addr del %[1]s/32 dev $dev; done`, v1.VIP)
+}
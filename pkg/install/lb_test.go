@@ -0,0 +1,91 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/fanux/sealos/pkg/lb"
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+)
+
+func TestCheckLoadBalancer(t *testing.T) {
+	defer func(l v1.LoadBalancerConfig, vip string, masters []string, port int) {
+		v1.LoadBalancer, v1.VIP, v1.MasterIPs, v1.APIServerPort = l, vip, masters, port
+	}(v1.LoadBalancer, v1.VIP, v1.MasterIPs, v1.APIServerPort)
+	v1.MasterIPs = []string{"192.168.0.2:22", "192.168.0.3"}
+	v1.APIServerPort = 6443
+	tests := []struct {
+		mode     string
+		vip      string
+		port     int
+		wantPort int
+		wantErr  string
+	}{
+		{"", "10.103.97.2", 0, 6443, ""},
+		{v1.LBModeExternal, "192.168.0.200", 0, 6443, ""},
+		{v1.LBModeKeepalived, "192.168.0.100", 0, lb.DefaultHAProxyPort, ""},
+		{v1.LBModeKubeVIP, "192.168.0.100", 0, 6443, ""},
+		{"nginx", "10.103.97.2", 0, 0, "is not supported"},
+		{v1.LBModeExternal, "lb.example.com", 0, 0, "must be an ip"},
+		{v1.LBModeKubeVIP, "192.168.0.2", 0, 0, "must not be the ip of a master"},
+		{v1.LBModeIPVS, "10.103.97.2", 8443, 0, "must be the apiserver port"},
+		{v1.LBModeExternal, "192.168.0.200", 443, 0, "must be the apiserver port"},
+		{v1.LBModeKeepalived, "192.168.0.100", 6443, 0, "taken by the apiserver"},
+	}
+	for _, tt := range tests {
+		v1.LoadBalancer = v1.LoadBalancerConfig{Mode: tt.mode, Port: tt.port}
+		v1.VIP = tt.vip
+		err := CheckLoadBalancer()
+		if tt.wantErr == "" && (err != nil || v1.LoadBalancer.Port != tt.wantPort) {
+			t.Errorf("CheckLoadBalancer(%s) = %v, port %d, want port %d", tt.mode, err, v1.LoadBalancer.Port, tt.wantPort)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("CheckLoadBalancer(%s) error = %v, want %q", tt.mode, err, tt.wantErr)
+		}
+	}
+	v1.LoadBalancer = v1.LoadBalancerConfig{Mode: v1.LBModeKeepalived}
+	v1.VIP = "192.168.0.100"
+	if err := CheckLoadBalancer(); err != nil || len(lbImages(v1.LoadBalancer)) != 2 {
+		t.Errorf("keepalived images = %v, %v", lbImages(v1.LoadBalancer), err)
+	}
+}
+
+func TestLBManifests(t *testing.T) {
+	defer func(l v1.LoadBalancerConfig, vip string) {
+		v1.LoadBalancer, v1.VIP = l, vip
+	}(v1.LoadBalancer, v1.VIP)
+	v1.LoadBalancer = v1.LoadBalancerConfig{Mode: v1.LBModeKeepalived, Port: 8443}
+	v1.VIP = "192.168.0.100"
+	paths, files := lbManifests("192.168.0.3:22", "eth0", []string{"192.168.0.2:22", "192.168.0.3:22"})
+	if len(paths) != 4 || len(files) != 4 {
+		t.Fatalf("lbManifests() = %v", paths)
+	}
+	// configs are written before the static pods
+	for i, path := range paths {
+		if strings.HasPrefix(path, lb.ManifestsDir) != (i >= 2) {
+			t.Errorf("lbManifests() paths = %v, want configs in front of the static pods", paths)
+		}
+	}
+	if conf := files[paths[1]]; !strings.Contains(conf, "priority 99") || !strings.Contains(conf, "unicast_src_ip 192.168.0.3") {
+		t.Errorf("keepalived.conf of the second master:\n%s", conf)
+	}
+
+	v1.LoadBalancer.Mode = v1.LBModeExternal
+	if paths, _ := lbManifests("192.168.0.2", "eth0", []string{"192.168.0.2"}); len(paths) != 0 {
+		t.Errorf("external mode has no manifests on the masters, got %v", paths)
+	}
+}
@@ -0,0 +1,451 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	runtime "github.com/fanux/sealos/pkg/cri/runtime"
+	"github.com/fanux/sealos/pkg/logger"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+	"github.com/fanux/sealos/pkg/utils/ssh"
+)
+
+const (
+	LocalRegistryPort         = 5000
+	LocalRegistryDataDir      = "/var/lib/sealos/registry"
+	LocalRegistryService      = "sealos-registry"
+	DefaultLocalRegistryImage = "docker.io/library/registry:2.7.1"
+	// localRegistryNamespace is the containerd namespace of the registry image, it is not collected by kubelet.
+	localRegistryNamespace = "sealos"
+
+	// packageImagesTar is the images in the package, it is removed from the package sent to the hosts without the local registry.
+	packageImagesTar = "kube/images/images.tar"
+	// emptyImagesTarCmd create an empty images tar for init.sh of the package without images.
+	emptyImagesTarCmd = "mkdir -p /root/kube/images && tar cf /root/" + packageImagesTar + " -T /dev/null"
+)
+
+const localRegistryUnit = `[Unit]
+Description=sealos local image registry
+After=containerd.service
+Requires=containerd.service
+
+[Service]
+ExecStartPre=-/usr/bin/ctr -n %[1]s task rm -f %[3]s
+ExecStartPre=-/usr/bin/ctr -n %[1]s container rm %[3]s
+ExecStart=/usr/bin/ctr -n %[1]s run --rm --net-host --env REGISTRY_HTTP_ADDR=0.0.0.0:%[4]d --mount type=bind,src=%[5]s,dst=/var/lib/registry,options=rbind:rw %[2]s %[3]s
+Restart=always
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// SetLocalRegistry set the local registry address on the host, master0 if the host is empty.
+func SetLocalRegistry(master0 string) {
+	if !v1.LocalRegistryEnabled {
+		return
+	}
+	host := v1.LocalRegistryHost
+	if host == "" {
+		host = master0
+	}
+	v1.LocalRegistry = fmt.Sprintf("%s:%d", utils.IPFormat(host), LocalRegistryPort)
+	if v1.LocalRegistryImage == "" {
+		v1.LocalRegistryImage = DefaultLocalRegistryImage
+	}
+}
+
+// CheckLocalRegistry check the local registry is supported by the cri, the mirrors of any registry are only supported by containerd.
+func CheckLocalRegistry() error {
+	if v1.LocalRegistry == "" {
+		return nil
+	}
+	if cri := criName(v1.Version); cri != runtime.Containerd {
+		return fmt.Errorf("local registry only supports cri %s, but cri is %s", runtime.Containerd, cri)
+	}
+	if v1.LocalRegistryImageFile != "" && !utils.FileExist(v1.LocalRegistryImageFile) {
+		return fmt.Errorf("local registry image file %s is not exist", v1.LocalRegistryImageFile)
+	}
+	_, err := localRegistryHost()
+	return err
+}
+
+// isLocalRegistryHost return true if the local registry runs on the host.
+func isLocalRegistryHost(host string) bool {
+	return v1.LocalRegistry != "" && utils.IPFormat(host) == strings.Split(v1.LocalRegistry, ":")[0]
+}
+
+// localRegistryHost return the host of the local registry in the masters and nodes.
+func localRegistryHost() (string, error) {
+	for _, host := range append(append([]string{}, v1.MasterIPs...), v1.NodeIPs...) {
+		if isLocalRegistryHost(host) {
+			return host, nil
+		}
+	}
+	return "", fmt.Errorf("local registry %s is not on the masters or nodes", v1.LocalRegistry)
+}
+
+// sendPackageWithLocalRegistry send the whole package to the local registry host, and the package without images to the other hosts.
+func (s *SealosInstaller) sendPackageWithLocalRegistry(kubeHook string) {
+	location, _ := utils.DownloadFile(v1.PkgURL)
+	var registryHosts, hosts []string
+	for _, host := range s.Hosts {
+		if isLocalRegistryHost(host) {
+			registryHosts = append(registryHosts, host)
+		} else {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(registryHosts) != 0 {
+		ssh.CopyFiles(v1.SSHConfig, location, registryHosts, "/root", nil, &kubeHook)
+	}
+	if len(hosts) != 0 {
+		slim, err := packageWithoutImages(location)
+		if err != nil {
+			logger.Error("create package without images err: %s", err)
+			os.Exit(ErrorExitOSCase)
+		}
+		hook := strings.Replace(kubeHook, " && cd /root/kube/shell", " && "+emptyImagesTarCmd+" && cd /root/kube/shell", 1)
+		// the images are pulled from the local registry, there is nothing to load.
+		hook = strings.Replace(hook, " && "+imageLoadCmd(criName(v1.Version), "../images/images.tar"), "", 1)
+		ssh.CopyFiles(v1.SSHConfig, slim, hosts, "/root", nil, &hook)
+	}
+	v1.PkgURL = location
+}
+
+// packageWithoutImages create the package without images in the same name, so the package hook is not changed.
+func packageWithoutImages(pkg string) (string, error) {
+	dir := filepath.Join(filepath.Dir(pkg), "without-images")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	slim := filepath.Join(dir, filepath.Base(pkg))
+	if src, err := os.Stat(pkg); err != nil {
+		return "", err
+	} else if dst, err := os.Stat(slim); err == nil && dst.ModTime().After(src.ModTime()) {
+		return slim, nil
+	}
+	// write to a temp file in the same dir and rename it, a partial package is never taken as the cache.
+	tmp, err := ioutil.TempFile(dir, filepath.Base(pkg)+".tmp")
+	if err != nil {
+		return "", err
+	}
+	err = stripImages(pkg, tmp)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), slim)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return slim, nil
+}
+
+// stripImages write the package without the images tar to out.
+func stripImages(pkg string, out io.Writer) error {
+	in, err := os.Open(pkg)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	gr, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	gw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gw)
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if strings.TrimPrefix(hdr.Name, "./") == packageImagesTar {
+			continue
+		}
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err = io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+	if err = tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// StartLocalRegistry start the registry on the local registry host, push the package images to it,
+// and mirror the registries of the images to it.
+func (s *SealosInstaller) StartLocalRegistry() {
+	if v1.LocalRegistry == "" {
+		return
+	}
+	var host string
+	for _, h := range s.Hosts {
+		if isLocalRegistryHost(h) {
+			host = h
+		}
+	}
+	if host == "" {
+		return
+	}
+	if err := startLocalRegistry(host); err != nil {
+		logger.Error("[%s] start local registry err: %s", host, err)
+		os.Exit(ErrorExitOSCase)
+	}
+	images := strings.Fields(v1.SSHConfig.CmdToString(host, imagesCmd(ImagesList), "\n"))
+	if err := pushImages(host, images); err != nil {
+		logger.Error("[%s] push images to local registry err: %s", host, err)
+		os.Exit(ErrorExitOSCase)
+	}
+	addLocalRegistryMirrors(images)
+}
+
+func startLocalRegistry(host string) error {
+	unit := fmt.Sprintf(localRegistryUnit, localRegistryNamespace, v1.LocalRegistryImage, LocalRegistryService, LocalRegistryPort, LocalRegistryDataDir)
+	if err := writeRemoteFile(host, fmt.Sprintf("/etc/systemd/system/%s.service", LocalRegistryService), []byte(unit)); err != nil {
+		return err
+	}
+	if err := importLocalRegistryImage(host); err != nil {
+		return err
+	}
+	startCmd := fmt.Sprintf("mkdir -p %s && systemctl daemon-reload && systemctl enable %s && systemctl restart %s", LocalRegistryDataDir, LocalRegistryService, LocalRegistryService)
+	if err := v1.SSHConfig.CmdAsync(host, startCmd); err != nil {
+		return err
+	}
+	waitCmd := fmt.Sprintf("for i in $(seq 60); do curl -sf http://%s/v2/ >/dev/null && exit 0; sleep 2; done; exit 1", v1.LocalRegistry)
+	if err := v1.SSHConfig.CmdAsync(host, waitCmd); err != nil {
+		return fmt.Errorf("registry %s is not ready: %w", v1.LocalRegistry, err)
+	}
+	return nil
+}
+
+// importLocalRegistryImage import the registry image to the sealos namespace from --local-registry-image-file,
+// or from the package images. It is never pulled, the local registry is for the hosts without internet access.
+func importLocalRegistryImage(host string) error {
+	tarFile := fmt.Sprintf("/tmp/%s.tar", LocalRegistryService)
+	var importCmd string
+	if v1.LocalRegistryImageFile != "" {
+		v1.SSHConfig.CopyLocalToRemote(host, v1.LocalRegistryImageFile, tarFile)
+		importCmd = fmt.Sprintf("ctr -n %[1]s image import %[2]s; rm -f %[2]s", localRegistryNamespace, tarFile)
+	} else {
+		importCmd = fmt.Sprintf("(ctr -n k8s.io image export %[1]s %[2]s && ctr -n %[3]s image import %[1]s; rm -f %[1]s) || true",
+			tarFile, v1.LocalRegistryImage, localRegistryNamespace)
+	}
+	if err := v1.SSHConfig.CmdAsync(host, importCmd); err != nil {
+		return err
+	}
+	checkCmd := fmt.Sprintf("ctr -n %s image ls -q | grep -qx %s", localRegistryNamespace, v1.LocalRegistryImage)
+	if err := v1.SSHConfig.CmdAsync(host, checkCmd); err != nil {
+		return fmt.Errorf("registry image %s is not in the package or --local-registry-image-file: %w", v1.LocalRegistryImage, err)
+	}
+	return nil
+}
+
+// pushImages tag the images in containerd of the host to the local registry and push them.
+func pushImages(host string, images []string) error {
+	for _, image := range images {
+		domain, _ := splitImage(image)
+		if domain == v1.LocalRegistry {
+			continue
+		}
+		if err := v1.SSHConfig.CmdAsync(host, pushImageCmd(image, v1.LocalRegistry)); err != nil {
+			return fmt.Errorf("push image %s err: %w", image, err)
+		}
+	}
+	return nil
+}
+
+func pushImageCmd(image, registry string) string {
+	target := localRegistryImage(image, registry)
+	return fmt.Sprintf("ctr -n k8s.io image tag --force %[1]s %[2]s && ctr -n k8s.io image push --plain-http %[2]s", normalizeImageRef(image), target)
+}
+
+// addLocalRegistryMirrors mirror the registries of the images to the local registry, return true if the mirrors are changed.
+func addLocalRegistryMirrors(images []string) bool {
+	mirror := "http://" + v1.LocalRegistry
+	changed := false
+	for _, image := range images {
+		domain, _ := splitImage(image)
+		if domain == v1.LocalRegistry || utils.InList(mirror, v1.Registry.Mirrors[domain]) {
+			continue
+		}
+		if v1.Registry.Mirrors == nil {
+			v1.Registry.Mirrors = make(map[string][]string)
+		}
+		v1.Registry.Mirrors[domain] = append(v1.Registry.Mirrors[domain], mirror)
+		changed = true
+	}
+	if utils.NotIn(v1.LocalRegistry, v1.Registry.Insecure) {
+		v1.Registry.Insecure = append(v1.Registry.Insecure, v1.LocalRegistry)
+		changed = true
+	}
+	return changed
+}
+
+// Push load the image tarballs on the local registry host and push the images to the local registry,
+// the new registries of the images are mirrored and stored in the sealos config.
+func (i *ImagesFlags) Push(cfgFile string, files []string) error {
+	if v1.LocalRegistry == "" {
+		return fmt.Errorf("local registry is not enabled, please init with --local-registry")
+	}
+	host, err := localRegistryHost()
+	if err != nil {
+		return err
+	}
+	var images []string
+	for _, f := range files {
+		fileImages, err := imagesInTar(f)
+		if err != nil {
+			return fmt.Errorf("read images in %s err: %w", f, err)
+		}
+		remote := path.Join(ImagesTmpDir, filepath.Base(f))
+		if err = v1.SSHConfig.CmdAsync(host, "mkdir -p "+ImagesTmpDir); err != nil {
+			return err
+		}
+		v1.SSHConfig.CopyLocalToRemote(host, f, remote)
+		err = v1.SSHConfig.CmdAsync(host, imagesCmd(ImagesLoad, remote))
+		_ = v1.SSHConfig.CmdAsync(host, "rm -f "+remote)
+		if err != nil {
+			return err
+		}
+		if err = pushImages(host, fileImages); err != nil {
+			return err
+		}
+		images = append(images, fileImages...)
+	}
+	for _, image := range images {
+		logger.Info("image %s is pushed to %s", image, localRegistryImage(image, v1.LocalRegistry))
+	}
+	if addLocalRegistryMirrors(images) {
+		i.Dump(cfgFile)
+		logger.Info("new registries are mirrored to the local registry, please run sealos registry sync to render them to the hosts")
+	}
+	return nil
+}
+
+// imagesInTar read the image names in the docker save or oci tarball, the tarball may be gzipped.
+func imagesInTar(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no manifest.json or index.json found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch strings.TrimPrefix(hdr.Name, "./") {
+		case "manifest.json":
+			var manifests []struct {
+				RepoTags []string `json:"RepoTags"`
+			}
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			if err = json.Unmarshal(data, &manifests); err != nil {
+				return nil, err
+			}
+			var images []string
+			for _, m := range manifests {
+				images = append(images, m.RepoTags...)
+			}
+			return images, nil
+		case "index.json":
+			var index struct {
+				Manifests []struct {
+					Annotations map[string]string `json:"annotations"`
+				} `json:"manifests"`
+			}
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			if err = json.Unmarshal(data, &index); err != nil {
+				return nil, err
+			}
+			var images []string
+			for _, m := range index.Manifests {
+				if name := m.Annotations["io.containerd.image.name"]; name != "" {
+					images = append(images, name)
+				}
+			}
+			return images, nil
+		}
+	}
+}
+
+// splitImage split the image to the registry domain and the remainder, the docker hub images are normalized like containerd.
+func splitImage(image string) (domain, remainder string) {
+	i := strings.IndexRune(image, '/')
+	if i == -1 || (!strings.ContainsAny(image[:i], ".:") && image[:i] != "localhost") {
+		domain, remainder = DockerHubRegistry, image
+	} else {
+		domain, remainder = image[:i], image[i+1:]
+	}
+	if domain == DockerHubRegistry && !strings.ContainsRune(remainder, '/') {
+		remainder = "library/" + remainder
+	}
+	return domain, remainder
+}
+
+// normalizeImageRef return the full image name like containerd, ex. nginx:1.19 is docker.io/library/nginx:1.19.
+func normalizeImageRef(image string) string {
+	domain, remainder := splitImage(image)
+	return domain + "/" + remainder
+}
+
+// localRegistryImage return the image in the local registry, the path is kept so the registry can be used as a mirror.
+func localRegistryImage(image, registry string) string {
+	_, remainder := splitImage(image)
+	return registry + "/" + remainder
+}
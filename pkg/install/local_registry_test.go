@@ -0,0 +1,176 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	runtime "github.com/fanux/sealos/pkg/cri/runtime"
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+)
+
+func TestLocalRegistryImage(t *testing.T) {
+	tests := []struct {
+		image, want string
+	}{
+		{"nginx:1.19", "192.168.0.2:5000/library/nginx:1.19"},
+		{"docker.io/calico/node:v3.8.2", "192.168.0.2:5000/calico/node:v3.8.2"},
+		{"k8s.gcr.io/kube-proxy:v1.20.15", "192.168.0.2:5000/kube-proxy:v1.20.15"},
+		{"localhost:5000/app:v1", "192.168.0.2:5000/app:v1"},
+	}
+	for _, tt := range tests {
+		if got := localRegistryImage(tt.image, "192.168.0.2:5000"); got != tt.want {
+			t.Errorf("localRegistryImage(%s) = %s, want %s", tt.image, got, tt.want)
+		}
+	}
+	if got := normalizeImageRef("nginx:1.19"); got != "docker.io/library/nginx:1.19" {
+		t.Errorf("normalizeImageRef(nginx:1.19) = %s", got)
+	}
+}
+
+func writeTestTar(t *testing.T, file string, gz bool, files map[string]string) {
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var w io.Writer = f
+	if gz {
+		gw := gzip.NewWriter(f)
+		defer gw.Close()
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+	for name, content := range files {
+		if err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestImagesInTar(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sealos-images-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	dockerTar := filepath.Join(dir, "docker.tar")
+	writeTestTar(t, dockerTar, false, map[string]string{"manifest.json": `[{"RepoTags":["nginx:1.19","nginx:latest"]}]`})
+	ociTar := filepath.Join(dir, "oci.tar.gz")
+	writeTestTar(t, ociTar, true, map[string]string{"index.json": `{"manifests":[{"annotations":{"io.containerd.image.name":"docker.io/library/busybox:1.32"}}]}`})
+
+	for file, want := range map[string][]string{
+		dockerTar: {"nginx:1.19", "nginx:latest"},
+		ociTar:    {"docker.io/library/busybox:1.32"},
+	} {
+		got, err := imagesInTar(file)
+		if err != nil {
+			t.Fatalf("imagesInTar(%s) err: %v", file, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("imagesInTar(%s) = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func TestPackageWithoutImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sealos-package-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pkg := filepath.Join(dir, "kube1.20.15.tar.gz")
+	writeTestTar(t, pkg, true, map[string]string{"kube/bin/kubeadm": "kubeadm", packageImagesTar: "images"})
+	slim, err := packageWithoutImages(pkg)
+	if err != nil {
+		t.Fatalf("packageWithoutImages() err: %v", err)
+	}
+	if filepath.Base(slim) != filepath.Base(pkg) {
+		t.Errorf("package without images %s is not in the same name", slim)
+	}
+	f, err := os.Open(slim)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gr)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, hdr.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"kube/bin/kubeadm"}) {
+		t.Errorf("files in package without images = %v", names)
+	}
+	if files, _ := ioutil.ReadDir(filepath.Dir(slim)); len(files) != 1 {
+		t.Errorf("temp files are left in %s: %v", filepath.Dir(slim), files)
+	}
+
+	broken := filepath.Join(dir, "kube1.21.0.tar.gz")
+	if err = ioutil.WriteFile(broken, []byte("not a gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = packageWithoutImages(broken); err == nil {
+		t.Errorf("packageWithoutImages(%s) should fail", broken)
+	}
+	if utils.FileExist(filepath.Join(filepath.Dir(slim), filepath.Base(broken))) {
+		t.Errorf("partial package without images of %s is left", broken)
+	}
+	if files, _ := ioutil.ReadDir(filepath.Dir(slim)); len(files) != 1 {
+		t.Errorf("temp files are left in %s: %v", filepath.Dir(slim), files)
+	}
+}
+
+func TestCheckLocalRegistry(t *testing.T) {
+	defer func(registry, cri string, masters, nodes []string) {
+		v1.LocalRegistry, v1.CRI, v1.MasterIPs, v1.NodeIPs = registry, cri, masters, nodes
+	}(v1.LocalRegistry, v1.CRI, v1.MasterIPs, v1.NodeIPs)
+	v1.CRI = runtime.Containerd
+	v1.MasterIPs = []string{"192.168.0.2:22"}
+	v1.NodeIPs = []string{"192.168.0.3"}
+	v1.LocalRegistry = "192.168.0.3:5000"
+	if err := CheckLocalRegistry(); err != nil {
+		t.Errorf("CheckLocalRegistry(%s) err: %v", v1.LocalRegistry, err)
+	}
+	v1.LocalRegistry = "192.168.0.4:5000"
+	if err := CheckLocalRegistry(); err == nil || !strings.Contains(err.Error(), "not on the masters or nodes") {
+		t.Errorf("CheckLocalRegistry(%s) err = %v, want not on the masters or nodes", v1.LocalRegistry, err)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fanux/sealos/pkg/ipvs"
+	"github.com/fanux/sealos/pkg/logger"
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+)
+
+const lvscareManifest = "/etc/kubernetes/manifests/kube-sealyun-lvscare.yaml"
+
+type LvscareFlags struct {
+	v1.SealConfig
+}
+
+func GetLvscareFlags(cfgFile string) *LvscareFlags {
+	l := &LvscareFlags{}
+	if err := l.Load(cfgFile); err != nil {
+		logger.Error(err)
+		l.ShowDefaultConfig()
+		os.Exit(0)
+	}
+	return l
+}
+
+// Sync regenerate the lvscare static pod by sealos config and push it to every node,
+// and report the nodes whose ipvs rules of the VIP do not match the masters before the sync.
+func (l *LvscareFlags) Sync() error {
+	if !l.LoadBalancer.IsIPVS() {
+		return fmt.Errorf("lvscare is not used in %s mode", l.LoadBalancer.Mode)
+	}
+	if err := l.Lvscare.Validate(); err != nil {
+		return err
+	}
+	yaml := lvscareYaml()
+	if yaml == "" {
+		return fmt.Errorf("render lvscare static pod failed")
+	}
+	vs := net.JoinHostPort(l.VIP, strconv.Itoa(l.APIServerPort))
+	var rs []string
+	for _, master := range l.Masters {
+		rs = append(rs, net.JoinHostPort(utils.IPFormat(master), strconv.Itoa(l.APIServerPort)))
+	}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var drifted []string
+	for _, node := range l.Nodes {
+		wg.Add(1)
+		go func(node string) {
+			defer wg.Done()
+			rules := parseIPVSRules(v1.SSHConfig.CmdToString(node, "cat /proc/net/ip_vs 2>/dev/null", "\n"))
+			if missing, unexpected := ipvsRulesDiff(rules[vs], rs); len(missing) != 0 || len(unexpected) != 0 {
+				logger.Warn("[%s] ipvs rules of %s do not match the masters, missing %v, unexpected %v", node, vs, missing, unexpected)
+				mu.Lock()
+				drifted = append(drifted, node)
+				mu.Unlock()
+			}
+			sendLvscare(node, yaml)
+		}(node)
+	}
+	wg.Wait()
+	if len(drifted) != 0 {
+		sort.Strings(drifted)
+		logger.Warn("ipvs rules of nodes %v did not match the masters %v, lvscare removes the unhealthy masters, check them if they are running", drifted, rs)
+	}
+	logger.Info("lvscare static pod is synced to %d nodes", len(l.Nodes))
+	return nil
+}
+
+func lvscareYaml() string {
+	return ipvs.LvsStaticPodYaml(v1.VIP, v1.MasterIPs, v1.APIServerPort, v1.LvscareImage, v1.Lvscare)
+}
+
+// sendLvscare replace the lvscare static pod of the node, the kubelet restarts it.
+func sendLvscare(node, yaml string) {
+	_ = v1.SSHConfig.Cmd(node, "rm -rf  /etc/kubernetes/manifests/kube-sealyun-lvscare* || :")
+	_ = v1.SSHConfig.Cmd(node, "mkdir -p /etc/kubernetes/manifests")
+	v1.SSHConfig.CopyConfigFile(node, lvscareManifest, []byte(yaml))
+}
+
+// parseIPVSRules parse /proc/net/ip_vs to the ipv4 real servers of the virtual servers, keyed by ip:port.
+func parseIPVSRules(out string) map[string][]string {
+	rules := make(map[string][]string)
+	var vs string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "TCP", "UDP":
+			vs = hexAddr(fields[1])
+		case "->":
+			if rs := hexAddr(fields[1]); rs != "" && vs != "" {
+				rules[vs] = append(rules[vs], rs)
+			}
+		}
+	}
+	return rules
+}
+
+// hexAddr convert the ipv4 address of /proc/net/ip_vs like 0A676102:192B to 10.103.97.2:6443, empty if it is not.
+func hexAddr(s string) string {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 || len(parts[0]) != 8 {
+		return ""
+	}
+	ip, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return ""
+	}
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return ""
+	}
+	return net.JoinHostPort(net.IP(ip).String(), strconv.Itoa(int(port)))
+}
+
+// ipvsRulesDiff return the masters missing in the real servers, and the real servers which are not masters.
+func ipvsRulesDiff(rs, masters []string) (missing, unexpected []string) {
+	for _, m := range masters {
+		if utils.NotIn(m, rs) {
+			missing = append(missing, m)
+		}
+	}
+	for _, r := range rs {
+		if utils.NotIn(r, masters) {
+			unexpected = append(unexpected, r)
+		}
+	}
+	return missing, unexpected
+}
@@ -0,0 +1,53 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"reflect"
+	"testing"
+)
+
+const procIPVS = `IP Virtual Server version 1.2.1 (size=4096)
+Prot LocalAddress:Port Scheduler Flags
+  -> RemoteAddress:Port Forward Weight ActiveConn InActConn
+TCP  0A676102:192B rr
+  -> C0A80002:192B      Masq    1      0          0
+  -> C0A80004:192B      Masq    1      3          0
+TCP  0A600001:01BB rr
+  -> C0A80002:192B      Masq    1      2          0
+`
+
+func TestParseIPVSRules(t *testing.T) {
+	rules := parseIPVSRules(procIPVS)
+	want := map[string][]string{
+		"10.103.97.2:6443": {"192.168.0.2:6443", "192.168.0.4:6443"},
+		"10.96.0.1:443":    {"192.168.0.2:6443"},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("parseIPVSRules() = %v, want %v", rules, want)
+	}
+	if rules := parseIPVSRules(""); len(rules) != 0 {
+		t.Errorf("parseIPVSRules(\"\") = %v, want empty", rules)
+	}
+
+	masters := []string{"192.168.0.2:6443", "192.168.0.3:6443"}
+	missing, unexpected := ipvsRulesDiff(rules["10.103.97.2:6443"], masters)
+	if !reflect.DeepEqual(missing, []string{"192.168.0.3:6443"}) || !reflect.DeepEqual(unexpected, []string{"192.168.0.4:6443"}) {
+		t.Errorf("ipvsRulesDiff() = %v, %v", missing, unexpected)
+	}
+	if missing, unexpected := ipvsRulesDiff(masters, masters); len(missing) != 0 || len(unexpected) != 0 {
+		t.Errorf("ipvsRulesDiff() of the same = %v, %v", missing, unexpected)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+)
+
+// GetMetadata read the package Metadata from host, return error if it is not exist.
+func GetMetadata(host string) (*v1.Metadata, error) {
+	if !v1.SSHConfig.IsFileExist(host, METADATAFILE) {
+		return nil, fmt.Errorf("[%s] metadata file %s is not exist", host, METADATAFILE)
+	}
+	metajson := v1.SSHConfig.CmdToString(host, "cat "+METADATAFILE, "")
+	metadata := &v1.Metadata{}
+	if err := json.Unmarshal([]byte(metajson), metadata); err != nil {
+		return nil, fmt.Errorf("[%s] decode metadata err: %w", host, err)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,459 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fanux/sealos/pkg/cni"
+	"github.com/fanux/sealos/pkg/logger"
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+
+	"sigs.k8s.io/yaml"
+)
+
+// cilium kube-proxy replacement needs kernel >= 5.4, see the README.
+const (
+	ciliumKernelMajor = 5
+	ciliumKernelMinor = 4
+)
+
+// cniApplyCmd retry kubectl apply, the calico bgp resources are rejected until the crds in the same file are established.
+const cniApplyCmd = "n=0; until kubectl apply -f /tmp/cni.yaml; do n=$((n+1)); [ $n -ge 5 ] && exit 1; sleep 3; done"
+
+// networkName return the cni plugin, calico by default.
+func networkName() string {
+	if v1.Network == "" {
+		return cni.CALICO
+	}
+	return v1.Network
+}
+
+// CheckNetwork check the cni plugin and its options.
+func CheckNetwork(version string) error {
+	if v1.WithoutCNI {
+		return nil
+	}
+	switch networkName() {
+	case cni.CALICO:
+		return checkCalico()
+	case cni.FLANNEL:
+		if b := v1.FlannelBackend; b != "" && b != cni.FlannelBackendVXLAN && b != cni.FlannelBackendHostGW {
+			return fmt.Errorf("unsupported flannel backend %s, only support %s|%s", b, cni.FlannelBackendVXLAN, cni.FlannelBackendHostGW)
+		}
+	case cni.CILIUM:
+		if t := v1.CiliumTunnel; t != "" && t != cni.CiliumTunnelVXLAN && t != cni.CiliumTunnelGeneve && t != cni.CiliumTunnelDisabled {
+			return fmt.Errorf("unsupported cilium tunnel %s, only support %s|%s|%s", t, cni.CiliumTunnelVXLAN, cni.CiliumTunnelGeneve, cni.CiliumTunnelDisabled)
+		}
+		// kubeadm init --skip-phases=addon/kube-proxy is supported since 1.16.
+		if utils.VersionToInt(version) < 116 {
+			return fmt.Errorf("cilium without kube-proxy needs kubernetes >= v1.16, but version is %s", version)
+		}
+	default:
+		return fmt.Errorf("unsupported network %s, only support %s|%s|%s", v1.Network, cni.CALICO, cni.FLANNEL, cni.CILIUM)
+	}
+	// render the custom manifest before init, the version of the package is unknown here.
+	if v1.CNIManifest != "" || v1.CNIValues != "" {
+		if _, err := renderNetwork(""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkCalico() error {
+	encap := calicoEncapsulation()
+	if !utils.InList(encap, cni.CalicoEncapsulations) {
+		return fmt.Errorf("unsupported calico encapsulation %s, only support %s", encap, strings.Join(cni.CalicoEncapsulations, "|"))
+	}
+	if v1.CalicoASNumber != "" && !isASNumber(v1.CalicoASNumber) {
+		return fmt.Errorf("calico AS number %s is invalid", v1.CalicoASNumber)
+	}
+	for ip, as := range v1.CalicoBGPPeers {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("calico bgp peer %s is not a valid ip", ip)
+		}
+		if !isASNumber(as) {
+			return fmt.Errorf("AS number %s of calico bgp peer %s is invalid", as, ip)
+		}
+	}
+	// the bgp peers are served by bird, which is disabled by vxlan.
+	if encap == cni.CalicoEncapsulationVXLAN && (v1.CalicoASNumber != "" || len(v1.CalicoBGPPeers) != 0) {
+		return fmt.Errorf("calico AS number and bgp peers are not supported by encapsulation %s", encap)
+	}
+	return nil
+}
+
+// calicoEncapsulation return the calico encapsulation, none with --bgp and ipip by default.
+func calicoEncapsulation() string {
+	switch {
+	case v1.CalicoEncap != "":
+		return v1.CalicoEncap
+	case v1.BGP:
+		return cni.CalicoEncapsulationNone
+	default:
+		return cni.CalicoEncapsulationIPIP
+	}
+}
+
+// calicoBGPPeers return the bgp peers sorted by ip.
+func calicoBGPPeers() []cni.BGPPeer {
+	var peers []cni.BGPPeer
+	for ip, as := range v1.CalicoBGPPeers {
+		peers = append(peers, cni.BGPPeer{IP: ip, ASNumber: as})
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].IP < peers[j].IP })
+	return peers
+}
+
+// isASNumber return true if as is a 4 bytes AS number.
+func isASNumber(as string) bool {
+	n, err := strconv.ParseUint(as, 10, 32)
+	return err == nil && n != 0
+}
+
+// CheckNetworkHosts check every host is able to run the cni plugin, and probe the interface and MTU of the hosts.
+// The calico MTU is derived from the NIC MTU if --mtu is not set. exit if failed.
+func (s *SealosInstaller) CheckNetworkHosts() {
+	if v1.WithoutCNI {
+		return
+	}
+	var nics []hostNIC
+	for _, h := range s.Hosts {
+		if err := checkNetworkHost(h); err != nil {
+			logger.Error("[%s] ------------ check network error: %s", h, err)
+			os.Exit(1)
+		}
+		nic, err := probeHostNIC(h)
+		if err != nil {
+			logger.Error("[%s] ------------ check interface error: %s", h, err)
+			os.Exit(1)
+		}
+		if nic.Name != "" {
+			nics = append(nics, nic)
+		}
+	}
+	if networkName() != cni.CALICO || len(nics) == 0 {
+		return
+	}
+	mtu, err := calicoMTU(nics, v1.MTU, calicoEncapsulation())
+	if err != nil {
+		logger.Error("check calico mtu error: %s", err)
+		os.Exit(1)
+	}
+	if v1.MTU == "" {
+		logger.Info("calico mtu is %s by the NIC mtu %d and encapsulation %s", mtu, nics[0].MTU, calicoEncapsulation())
+	}
+	v1.MTU = mtu
+}
+
+// checkNetworkHost check the host is able to run the cni plugin.
+func checkNetworkHost(host string) error {
+	if networkName() != cni.CILIUM {
+		return nil
+	}
+	release := v1.SSHConfig.CmdToString(host, "uname -r", "")
+	if !kernelAtLeast(release, ciliumKernelMajor, ciliumKernelMinor) {
+		return fmt.Errorf("kernel %s is too old for cilium, must be >= %d.%d", release, ciliumKernelMajor, ciliumKernelMinor)
+	}
+	if err := v1.SSHConfig.CmdAsync(host, "mount | grep -q 'type bpf' || mount bpffs -t bpf /sys/fs/bpf"); err != nil {
+		return fmt.Errorf("mount bpffs on /sys/fs/bpf failed: %w", err)
+	}
+	return nil
+}
+
+// hostNIC is the NIC of the host selected by --interface.
+type hostNIC struct {
+	Host  string
+	Name  string
+	MTU   int
+	Addrs []string
+}
+
+// probeHostNIC return the NIC selected by --interface, empty if the cni does not select the NIC by --interface.
+func probeHostNIC(host string) (hostNIC, error) {
+	name := networkName()
+	iface := v1.Interface
+	if (name != cni.CALICO && name != cni.FLANNEL) || iface == "" || (name == cni.FLANNEL && utils.IsIpv4(iface)) {
+		return hostNIC{}, nil
+	}
+	nics, err := parseHostNICs(host, v1.SSHConfig.CmdToString(host, listNICsCmd, "\n"))
+	if err != nil {
+		return hostNIC{}, err
+	}
+	routeDev := ""
+	if utils.IsIpv4(iface) {
+		routeDev = strings.TrimSpace(v1.SSHConfig.CmdToString(host, fmt.Sprintf("ip -o route get %s | grep -o 'dev [^ ]*' | awk '{print $2}'", iface), ""))
+	}
+	return selectNIC(nics, utils.IPFormat(host), iface, routeDev)
+}
+
+// listNICsCmd print the name, MTU and ipv4 addresses of every NIC in a line.
+const listNICsCmd = `for d in /sys/class/net/*; do n=$(basename $d); echo "$n $(cat $d/mtu) $(ip -o -4 addr show dev $n | awk '{print $4}' | tr '\n' ' ')"; done`
+
+func parseHostNICs(host, out string) ([]hostNIC, error) {
+	var nics []hostNIC
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		mtu, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("parse mtu of NIC %s err: %w", fields[0], err)
+		}
+		nics = append(nics, hostNIC{Host: host, Name: fields[0], MTU: mtu, Addrs: fields[2:]})
+	}
+	if len(nics) == 0 {
+		return nil, fmt.Errorf("no NIC is found")
+	}
+	return nics, nil
+}
+
+// selectNIC select the NIC like calico, the NIC with ipv4 matching the regex of --interface, or routing to the can-reach ip.
+// It must be exactly one, and warn if it does not carry the node ip.
+func selectNIC(nics []hostNIC, nodeIP, iface, routeDev string) (hostNIC, error) {
+	var nodeNIC string
+	var names, matched []string
+	var selected hostNIC
+	for _, nic := range nics {
+		if nic.Name == "lo" || len(nic.Addrs) == 0 {
+			continue
+		}
+		names = append(names, nic.Name)
+		for _, addr := range nic.Addrs {
+			if strings.Split(addr, "/")[0] == nodeIP {
+				nodeNIC = nic.Name
+			}
+		}
+	}
+	if routeDev != "" {
+		for _, nic := range nics {
+			if nic.Name == routeDev {
+				selected = nic
+				matched = append(matched, nic.Name)
+			}
+		}
+		if len(matched) == 0 {
+			return hostNIC{}, fmt.Errorf("NIC %s routing to %s is not found", routeDev, iface)
+		}
+	} else {
+		re, err := regexp.Compile(iface)
+		if err != nil {
+			return hostNIC{}, fmt.Errorf("interface %s is not a valid regex: %w", iface, err)
+		}
+		for _, nic := range nics {
+			if utils.InList(nic.Name, names) && re.MatchString(nic.Name) {
+				selected = nic
+				matched = append(matched, nic.Name)
+			}
+		}
+		if len(matched) == 0 {
+			return hostNIC{}, fmt.Errorf("interface %s matches no NIC with ipv4 in %v, set --interface to the NIC of the node ip %s", iface, names, nodeNIC)
+		}
+		if len(matched) > 1 {
+			return hostNIC{}, fmt.Errorf("interface %s matches NICs %v, it must match exactly one, like --interface %s", iface, matched, nodeNIC)
+		}
+	}
+	if nodeNIC != "" && selected.Name != nodeNIC {
+		logger.Warn("[%s] interface %s selects NIC %s, but the node ip %s is on NIC %s", selected.Host, iface, selected.Name, nodeIP, nodeNIC)
+	}
+	return selected, nil
+}
+
+// calicoMTU return the calico MTU, the NIC MTU minus the overhead of the encapsulation. The NIC MTU must be the same on all hosts,
+// and mtu must not be larger than the derived MTU if it is set.
+func calicoMTU(nics []hostNIC, mtu, encap string) (string, error) {
+	for _, nic := range nics[1:] {
+		if nic.MTU != nics[0].MTU {
+			return "", fmt.Errorf("mtu %d of NIC %s on %s is different from mtu %d of NIC %s on %s", nic.MTU, nic.Name, nic.Host, nics[0].MTU, nics[0].Name, nics[0].Host)
+		}
+	}
+	derived := nics[0].MTU - cni.CalicoOptions{Encapsulation: encap}.MTUOverhead()
+	if mtu == "" {
+		return strconv.Itoa(derived), nil
+	}
+	m, err := strconv.Atoi(mtu)
+	if err != nil || m <= 0 {
+		return "", fmt.Errorf("mtu %s is invalid", mtu)
+	}
+	if m > derived {
+		return "", fmt.Errorf("mtu %d is larger than %d, the mtu %d of NIC %s on %s minus the overhead of encapsulation %s", m, derived, nics[0].MTU, nics[0].Name, nics[0].Host, encap)
+	}
+	return mtu, nil
+}
+
+// networkCleanCmd remove the links left by flannel and cilium, calico is cleaned by removing the ipip module.
+func networkCleanCmd() string {
+	switch networkName() {
+	case cni.FLANNEL:
+		return "ip link delete cni0; ip link delete flannel.1; rm -rf /run/flannel"
+	case cni.CILIUM:
+		return "ip link delete cilium_host; ip link delete cilium_vxlan; ip link delete cilium_geneve; rm -rf /var/run/cilium"
+	default:
+		return ""
+	}
+}
+
+// kernelAtLeast return true if the kernel release like 4.19.0-17-amd64 is >= major.minor.
+func kernelAtLeast(release string, major, minor int) bool {
+	parts := strings.SplitN(strings.TrimSpace(release), ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	ma, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	mi, err := strconv.Atoi(strings.TrimRightFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' }))
+	if err != nil {
+		return false
+	}
+	return ma > major || (ma == major && mi >= minor)
+}
+
+// networkManifests render the manifests of the cni plugin, the version is from the package if it is the same plugin.
+func networkManifests(host string) (string, error) {
+	name := networkName()
+	var cniVersion string
+	if metadata, err := GetMetadata(host); err != nil {
+		logger.Warn("get metadata version err: %s", err)
+	} else if metadata.CniName == name || (metadata.CniName == "" && name == cni.CALICO) {
+		cniVersion = metadata.CniVersion
+	}
+	if cniVersion == "" {
+		cniVersion = cni.DefaultVersion(name)
+	}
+	v1.CniVersion = cniVersion
+	return renderNetwork(cniVersion)
+}
+
+// renderNetwork render the manifests of the cni plugin by --cni-manifest and --cni-values, and validate the output.
+func renderNetwork(cniVersion string) (string, error) {
+	tmpl, err := loadCNIManifest(v1.CNIManifest)
+	if err != nil {
+		return "", err
+	}
+	if err = cni.CheckTemplate(tmpl); err != nil {
+		return "", fmt.Errorf("parse cni manifest %s failed: %w", v1.CNIManifest, err)
+	}
+	values, err := loadCNIValues(v1.CNIValues)
+	if err != nil {
+		return "", err
+	}
+	// can-reach is used by calico multi network, flannel uses the default route interface.
+	iface := v1.Interface
+	if utils.IsIpv4(iface) {
+		iface = "can-reach=" + iface
+	} else if iface != "" {
+		iface = "interface=" + iface
+	}
+	manifests, err := cni.NewNetwork(networkName(), cni.MetaData{
+		Interface: iface,
+		CIDR:      v1.PodCIDR,
+		IPIP:      !v1.BGP,
+		MTU:       v1.MTU,
+		CniRepo:   v1.Repo,
+		Version:   cniVersion,
+		Calico: cni.CalicoOptions{
+			Encapsulation: calicoEncapsulation(),
+			ASNumber:      v1.CalicoASNumber,
+			BGPPeers:      calicoBGPPeers(),
+		},
+		Flannel: cni.FlannelOptions{Backend: v1.FlannelBackend},
+		Cilium: cni.CiliumOptions{
+			Tunnel:        v1.CiliumTunnel,
+			APIServerHost: v1.APIServer,
+			APIServerPort: strconv.Itoa(v1.LoadBalancer.Port),
+		},
+		Values: values,
+	}).Manifests(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("render cni manifests failed: %w", err)
+	}
+	if err = cni.ValidateManifests(manifests); err != nil {
+		return "", fmt.Errorf("cni manifests is invalid: %w", err)
+	}
+	return manifests, nil
+}
+
+// loadCNIManifest read the custom template, the yaml files of the dir are joined by name. empty if path is empty.
+func loadCNIManifest(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("cni manifest %s is not exist: %w", path, err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = yamlFiles(path); err != nil {
+			return "", err
+		}
+		if len(files) == 0 {
+			return "", fmt.Errorf("no yaml file is found in cni manifest dir %s", path)
+		}
+	}
+	var docs []string
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return "", fmt.Errorf("read cni manifest %s failed: %w", f, err)
+		}
+		docs = append(docs, string(data))
+	}
+	return strings.Join(docs, "\n---\n"), nil
+}
+
+func yamlFiles(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read cni manifest dir %s failed: %w", dir, err)
+	}
+	var files []string
+	for _, e := range entries {
+		if ext := filepath.Ext(e.Name()); !e.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// loadCNIValues read the user variables of the custom template.
+func loadCNIValues(path string) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if path == "" {
+		return values, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cni values %s failed: %w", path, err)
+	}
+	if err = yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("unmarshal cni values %s failed: %w", path, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,237 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fanux/sealos/pkg/cni"
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+)
+
+func TestKernelAtLeast(t *testing.T) {
+	tests := []struct {
+		release string
+		want    bool
+	}{
+		{"5.4.0-80-generic", true},
+		{"5.10.0-17-amd64", true},
+		{"6.1.0", true},
+		{"4.19.0-17-amd64", false},
+		{"3.10.0-1160.el7.x86_64", false},
+		{"5.4+", true},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := kernelAtLeast(tt.release, ciliumKernelMajor, ciliumKernelMinor); got != tt.want {
+			t.Errorf("kernelAtLeast(%q) = %v, want %v", tt.release, got, tt.want)
+		}
+	}
+}
+
+func TestCheckNetwork(t *testing.T) {
+	defer func(network, backend, tunnel string) {
+		v1.Network, v1.FlannelBackend, v1.CiliumTunnel = network, backend, tunnel
+	}(v1.Network, v1.FlannelBackend, v1.CiliumTunnel)
+	tests := []struct {
+		network, backend, tunnel, version string
+		wantErr                           string
+	}{
+		{"", "", "", "v1.18.0", ""},
+		{cni.FLANNEL, cni.FlannelBackendHostGW, "", "v1.18.0", ""},
+		{cni.FLANNEL, "udp", "", "v1.18.0", "unsupported flannel backend"},
+		{cni.CILIUM, "", cni.CiliumTunnelGeneve, "v1.20.0", ""},
+		{cni.CILIUM, "", "ipip", "v1.20.0", "unsupported cilium tunnel"},
+		{cni.CILIUM, "", "", "v1.15.0", "needs kubernetes >= v1.16"},
+		{"weave", "", "", "v1.18.0", "unsupported network"},
+	}
+	for _, tt := range tests {
+		v1.Network, v1.FlannelBackend, v1.CiliumTunnel = tt.network, tt.backend, tt.tunnel
+		err := CheckNetwork(tt.version)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("CheckNetwork(%s) unexpected error: %v", tt.network, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("CheckNetwork(%s) error = %v, want %q", tt.network, err, tt.wantErr)
+		}
+	}
+}
+
+func TestTemplateNetwork(t *testing.T) {
+	defer func(network string, masters []string) {
+		v1.Network, v1.MasterIPs = network, masters
+	}(v1.Network, v1.MasterIPs)
+	v1.MasterIPs = []string{"192.168.0.2:22"}
+	for _, network := range []string{cni.CALICO, cni.CILIUM} {
+		v1.Network = network
+		out := string(TemplateFromTemplateContent(ClusterConfigurationDefault))
+		if !strings.Contains(out, "scheduler:") {
+			t.Errorf("%s cluster configuration is truncated:\n%s", network, out)
+		}
+		if got := strings.Contains(out, "allocate-node-cidrs"); got != (network == cni.CILIUM) {
+			t.Errorf("%s cluster configuration allocate-node-cidrs = %v", network, got)
+		}
+	}
+}
+
+func TestCheckCalico(t *testing.T) {
+	defer func(encap, as string, peers map[string]string, bgp bool) {
+		v1.CalicoEncap, v1.CalicoASNumber, v1.CalicoBGPPeers, v1.BGP = encap, as, peers, bgp
+	}(v1.CalicoEncap, v1.CalicoASNumber, v1.CalicoBGPPeers, v1.BGP)
+	tests := []struct {
+		encap, as string
+		peers     map[string]string
+		wantErr   string
+	}{
+		{"", "", nil, ""},
+		{cni.CalicoEncapsulationVXLANCrossSubnet, "", nil, ""},
+		{cni.CalicoEncapsulationNone, "64512", map[string]string{"192.168.0.1": "64513"}, ""},
+		{"gre", "", nil, "unsupported calico encapsulation"},
+		{cni.CalicoEncapsulationNone, "as64512", nil, "AS number as64512 is invalid"},
+		{cni.CalicoEncapsulationNone, "", map[string]string{"router1": "64513"}, "not a valid ip"},
+		{cni.CalicoEncapsulationNone, "", map[string]string{"192.168.0.1": "0"}, "AS number 0 of calico bgp peer"},
+		{cni.CalicoEncapsulationVXLAN, "64512", nil, "not supported by encapsulation vxlan"},
+	}
+	for _, tt := range tests {
+		v1.CalicoEncap, v1.CalicoASNumber, v1.CalicoBGPPeers = tt.encap, tt.as, tt.peers
+		err := checkCalico()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("checkCalico(%s) unexpected error: %v", tt.encap, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("checkCalico(%s) error = %v, want %q", tt.encap, err, tt.wantErr)
+		}
+	}
+
+	v1.CalicoEncap = ""
+	for bgp, want := range map[bool]string{true: cni.CalicoEncapsulationNone, false: cni.CalicoEncapsulationIPIP} {
+		v1.BGP = bgp
+		if got := calicoEncapsulation(); got != want {
+			t.Errorf("calicoEncapsulation() with bgp %v = %s, want %s", bgp, got, want)
+		}
+	}
+}
+
+func TestRenderNetwork(t *testing.T) {
+	defer func(network, manifest, values, cidr string) {
+		v1.Network, v1.CNIManifest, v1.CNIValues, v1.PodCIDR = network, manifest, values, cidr
+	}(v1.Network, v1.CNIManifest, v1.CNIValues, v1.PodCIDR)
+	dir, err := ioutil.TempDir("", "cni-manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"manifests/10-config.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: calico-config\ndata:\n  cidr: {{ .CIDR }}\n",
+		"manifests/20-node.yml":    "apiVersion: apps/v1\nkind: DaemonSet\nmetadata:\n  name: calico-node\nspec:\n  image: {{ .Values.image }}\n",
+		"manifests/README.md":      "not a manifest",
+		"values.yaml":              "image: registry.example.com/calico/node:v3.19.1-patched\n",
+		"broken.yaml":              "apiVersion: v1\nkind: ConfigMap\n",
+		"unclosed.yaml":            "cidr: {{ .CIDR ",
+		"typo.yaml":                "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\ndata:\n  image: {{ .Values.imgae }}\n",
+	}
+	for name, data := range files {
+		_ = os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755)
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v1.Network, v1.PodCIDR = cni.CALICO, "10.244.0.0/16"
+	v1.CNIManifest, v1.CNIValues = filepath.Join(dir, "manifests"), filepath.Join(dir, "values.yaml")
+	out, err := renderNetwork("")
+	if err != nil {
+		t.Fatalf("renderNetwork unexpected error: %v", err)
+	}
+	for _, want := range []string{"cidr: 10.244.0.0/16", "image: registry.example.com/calico/node:v3.19.1-patched"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("rendered manifests missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "calico-config") > strings.Index(out, "calico-node") {
+		t.Errorf("manifests of the dir should be joined by name:\n%s", out)
+	}
+	if strings.Contains(out, "not a manifest") {
+		t.Errorf("non yaml files should be skipped:\n%s", out)
+	}
+
+	for name, wantErr := range map[string]string{
+		"broken.yaml":   "has no metadata.name",
+		"unclosed.yaml": "parse cni manifest",
+		"typo.yaml":     "render cni manifests failed",
+		"missing.yaml":  "is not exist",
+	} {
+		v1.CNIManifest = filepath.Join(dir, name)
+		if _, err = renderNetwork(""); err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("renderNetwork(%s) error = %v, want %q", name, err, wantErr)
+		}
+	}
+}
+
+func TestSelectNIC(t *testing.T) {
+	out := "eth0 1500 192.168.0.2/24 \nlo 65536 127.0.0.1/8 \ndocker0 1500 \neth1 1450 10.0.0.2/16 \n"
+	nics, err := parseHostNICs("192.168.0.2:22", out)
+	if err != nil || len(nics) != 4 || nics[2].MTU != 1500 || len(nics[2].Addrs) != 0 {
+		t.Fatalf("parseHostNICs() = %v, %v", nics, err)
+	}
+	tests := []struct {
+		iface    string
+		routeDev string
+		want     string
+		wantErr  bool
+	}{
+		{"eth0", "", "eth0", false},
+		{"eth.*", "", "", true},
+		{"docker", "", "", true},
+		{"ens.*", "", "", true},
+		{"eth1", "", "eth1", false},
+		{"10.0.0.1", "eth1", "eth1", false},
+		{"10.0.0.1", "eth9", "", true},
+	}
+	for _, tt := range tests {
+		got, err := selectNIC(nics, "192.168.0.2", tt.iface, tt.routeDev)
+		if (err != nil) != tt.wantErr || got.Name != tt.want {
+			t.Errorf("selectNIC(%s, %s) = %s, %v, want %s", tt.iface, tt.routeDev, got.Name, err, tt.want)
+		}
+	}
+}
+
+func TestCalicoMTU(t *testing.T) {
+	nics := []hostNIC{{Host: "a", Name: "eth0", MTU: 1500}, {Host: "b", Name: "eth0", MTU: 1500}}
+	tests := []struct {
+		nics    []hostNIC
+		mtu     string
+		encap   string
+		want    string
+		wantErr bool
+	}{
+		{nics, "", cni.CalicoEncapsulationIPIP, "1480", false},
+		{nics, "", cni.CalicoEncapsulationVXLAN, "1450", false},
+		{nics, "", cni.CalicoEncapsulationNone, "1500", false},
+		{nics, "1440", cni.CalicoEncapsulationIPIP, "1440", false},
+		{nics, "1490", cni.CalicoEncapsulationIPIP, "", true},
+		{nics, "abc", cni.CalicoEncapsulationIPIP, "", true},
+		{append(nics, hostNIC{Host: "c", Name: "eth0", MTU: 1450}), "", cni.CalicoEncapsulationIPIP, "", true},
+	}
+	for _, tt := range tests {
+		got, err := calicoMTU(tt.nics, tt.mtu, tt.encap)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("calicoMTU(%s, %s) = %s, %v, want %s", tt.mtu, tt.encap, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,402 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	runtime "github.com/fanux/sealos/pkg/cri/runtime"
+	"github.com/fanux/sealos/pkg/logger"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+	"github.com/fanux/sealos/pkg/utils/ssh"
+
+	"github.com/fanux/sealos/pkg/kubernetes/nodeclient"
+
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	ContainerdConfigFile = "/etc/containerd/config.toml"
+	// ContainerdCertsDir is the config_path of containerd, the hosts.toml of every registry is managed by sealos.
+	ContainerdCertsDir = "/etc/containerd/certs.d"
+	DockerDaemonFile   = "/etc/docker/daemon.json"
+	DockerCertsDir     = "/etc/docker/certs.d"
+	// KubeletDockerConfigFile is the pull credentials of kubelet with docker.
+	KubeletDockerConfigFile = "/var/lib/kubelet/config.json"
+
+	DockerHubRegistry = "docker.io"
+
+	registryBlockBegin      = "# sealos registry begin"
+	registryBlockEnd        = "# sealos registry end"
+	containerdRegistryTable = `[plugins."io.containerd.grpc.v1.cri".registry]`
+	// ContainerdConfigPathVersion is the first containerd version reading the registry hosts.toml in config_path.
+	ContainerdConfigPathVersion = "1.5.0"
+)
+
+var (
+	RegistryReadyTimeout = 5 * time.Minute
+
+	containerdConfigPathRegexp = regexp.MustCompile(`(?m)^(\s*)config_path\s*=.*$`)
+	registryBlockRegexp        = regexp.MustCompile(`(?s)\n?` + registryBlockBegin + `.*` + registryBlockEnd + `\n?`)
+	// containerdRegistryConflictRegexp match the headers of the registry mirrors and tls tables, they conflict with config_path.
+	containerdRegistryConflictRegexp = regexp.MustCompile(`^\[plugins\.("io\.containerd\.grpc\.v1\.cri"|cri)\.registry\.(mirrors|configs\..+\.tls)(\..*)?\]$`)
+)
+
+type RegistryFlags struct {
+	Client *kubernetes.Clientset
+	v1.SealConfig
+}
+
+// GetRegistryFlags load the sealos config, exit if failed.
+func GetRegistryFlags(cfgFile string) *RegistryFlags {
+	r := &RegistryFlags{}
+	if err := r.Load(cfgFile); err != nil {
+		logger.Error(err)
+		r.ShowDefaultConfig()
+		os.Exit(0)
+	}
+	return r
+}
+
+// SendRegistryConfig render the registry config to the container runtime of every host and restart it, skip if nothing is configured.
+func (s *SealosInstaller) SendRegistryConfig() {
+	if v1.Registry.IsEmpty() {
+		return
+	}
+	cri := criName(v1.Version)
+	if err := validateRegistryConfig(cri, v1.Registry); err != nil {
+		logger.Error(err)
+		os.Exit(ErrorExitOSCase)
+	}
+	var wg sync.WaitGroup
+	for _, host := range s.Hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			if err := applyRegistryConfig(host, cri, v1.Registry); err != nil {
+				logger.Error("[%s] config registry err: %v", host, err)
+				os.Exit(ErrorExitOSCase)
+			}
+		}(host)
+	}
+	wg.Wait()
+}
+
+// Sync render the registry config and restart the container runtime one host at a time,
+// the next host is synced after the node is Ready.
+func (r *RegistryFlags) Sync() error {
+	cri := criName(v1.Version)
+	if err := validateRegistryConfig(cri, v1.Registry); err != nil {
+		return err
+	}
+	if utils.FileExist(nodeclient.KubeDefaultConfigPath) {
+		client, err := nodeclient.NewClient(nodeclient.KubeDefaultConfigPath, nil)
+		if err != nil {
+			return fmt.Errorf("get k8s client err: %w", err)
+		}
+		r.Client = client
+	}
+	for _, host := range append(append([]string{}, r.Masters...), r.Nodes...) {
+		if err := applyRegistryConfig(host, cri, v1.Registry); err != nil {
+			return fmt.Errorf("[%s] config registry err: %w", host, err)
+		}
+		if r.Client == nil {
+			continue
+		}
+		since, err := remoteTime(host)
+		if err != nil {
+			return err
+		}
+		// kubelet reports the node status on start, so the Ready after the cri restarted is known.
+		if err = v1.SSHConfig.CmdAsync(host, "systemctl restart kubelet"); err != nil {
+			return fmt.Errorf("[%s] restart kubelet err: %w", host, err)
+		}
+		node := ssh.RemoteHostName(v1.SSHConfig, host)
+		if err := waitNodeReady(r.Client, node, since, RegistryReadyTimeout); err != nil {
+			return fmt.Errorf("[%s] node %s is not ready in %s after %s restarted: %w", host, node, RegistryReadyTimeout, cri, err)
+		}
+		logger.Info("[%s] registry config is synced", host)
+	}
+	return nil
+}
+
+// checkContainerdVersion check the containerd on the host reads the registry hosts.toml in config_path,
+// the registry config is not written to the config.toml of the old containerd.
+func checkContainerdVersion(host string) error {
+	out, err := v1.SSHConfig.CmdOutput(host, "containerd --version")
+	if err != nil {
+		return fmt.Errorf("get containerd version err: %w", err)
+	}
+	v, err := containerdVersion(string(out))
+	if err != nil {
+		return err
+	}
+	if !v.AtLeast(version.MustParseGeneric(ContainerdConfigPathVersion)) {
+		return fmt.Errorf("containerd %s does not support the registry config_path, please upgrade it to %s or later", v, ContainerdConfigPathVersion)
+	}
+	return nil
+}
+
+// containerdVersion parse the version in the output of containerd --version, ex. containerd github.com/containerd/containerd v1.5.8 1e5ef94,
+// or containerd containerd.io 1.4.12 7b11cfa of the docker repo.
+func containerdVersion(out string) (*version.Version, error) {
+	for _, field := range strings.Fields(out) {
+		if v, err := version.ParseGeneric(field); err == nil {
+			return v, nil
+		}
+	}
+	return nil, fmt.Errorf("containerd version is not found in %q", strings.TrimSpace(out))
+}
+
+func validateRegistryConfig(cri string, r v1.RegistryConfig) error {
+	if cri == runtime.CRIO && !r.IsEmpty() {
+		return fmt.Errorf("registry config of %s is not supported, please config /etc/containers/registries.conf by yourself", cri)
+	}
+	for registry, caFile := range r.CAFiles {
+		if !utils.FileExist(caFile) {
+			return fmt.Errorf("ca file %s of registry %s is not exist", caFile, registry)
+		}
+	}
+	if cri == runtime.Docker {
+		for registry := range r.Mirrors {
+			if registry != DockerHubRegistry {
+				logger.Warn("docker only supports the mirrors of %s, the mirrors of %s are ignored", DockerHubRegistry, registry)
+			}
+		}
+	}
+	return nil
+}
+
+// applyRegistryConfig write the registry config files to the host and restart the container runtime.
+func applyRegistryConfig(host, cri string, r v1.RegistryConfig) error {
+	switch cri {
+	case runtime.Containerd:
+		if err := checkContainerdVersion(host); err != nil {
+			return err
+		}
+		if err := v1.SSHConfig.CmdAsync(host, fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s", ContainerdCertsDir)); err != nil {
+			return err
+		}
+		for _, registry := range r.Registries() {
+			dir := path.Join(ContainerdCertsDir, registry)
+			if err := writeRemoteFile(host, path.Join(dir, "hosts.toml"), []byte(containerdHostsToml(registry, r))); err != nil {
+				return err
+			}
+			if caFile, ok := r.CAFiles[registry]; ok {
+				v1.SSHConfig.CopyLocalToRemote(host, caFile, path.Join(dir, "ca.crt"))
+			}
+		}
+		current := v1.SSHConfig.CmdToString(host, fmt.Sprintf("cat %s 2>/dev/null || true", ContainerdConfigFile), "\n")
+		if err := writeRemoteFile(host, ContainerdConfigFile, []byte(containerdConfig(current, r))); err != nil {
+			return err
+		}
+		return v1.SSHConfig.CmdAsync(host, "systemctl daemon-reload && systemctl restart containerd")
+	case runtime.Docker:
+		for registry, caFile := range r.CAFiles {
+			dir := path.Join(DockerCertsDir, registry)
+			if err := v1.SSHConfig.CmdAsync(host, "mkdir -p "+dir); err != nil {
+				return err
+			}
+			v1.SSHConfig.CopyLocalToRemote(host, caFile, path.Join(dir, "ca.crt"))
+		}
+		current := v1.SSHConfig.CmdToString(host, fmt.Sprintf("cat %s 2>/dev/null || true", DockerDaemonFile), "\n")
+		daemon, err := dockerDaemonJSON([]byte(current), r)
+		if err != nil {
+			return fmt.Errorf("render %s err: %w", DockerDaemonFile, err)
+		}
+		if err = writeRemoteFile(host, DockerDaemonFile, daemon); err != nil {
+			return err
+		}
+		if len(r.Auths) == 0 {
+			if err = v1.SSHConfig.CmdAsync(host, "rm -f "+KubeletDockerConfigFile); err != nil {
+				return err
+			}
+		} else {
+			auths, err := dockerConfigJSON(r)
+			if err != nil {
+				return err
+			}
+			if err = writeRemoteFile(host, KubeletDockerConfigFile, auths); err != nil {
+				return err
+			}
+		}
+		// containers are restarted by kubelet if docker live-restore is not enabled.
+		return v1.SSHConfig.CmdAsync(host, "systemctl daemon-reload && systemctl restart docker")
+	default:
+		return fmt.Errorf("registry config of %s is not supported", cri)
+	}
+}
+
+// writeRemoteFile write data to the file on host, the parent dir is created if not exist.
+func writeRemoteFile(host, file string, data []byte) error {
+	tmpFile, err := ioutil.TempFile("", "sealos-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err = tmpFile.Write(data); err != nil {
+		return err
+	}
+	_ = tmpFile.Close()
+	if err = v1.SSHConfig.CmdAsync(host, "mkdir -p "+path.Dir(file)); err != nil {
+		return err
+	}
+	v1.SSHConfig.CopyLocalToRemote(host, tmpFile.Name(), file)
+	return nil
+}
+
+// containerdHostsToml render the hosts.toml of the registry, the mirrors are tried in order before the registry.
+// Like docker, an insecure registry is tried by https without tls verify first, then by plain http.
+func containerdHostsToml(registry string, r v1.RegistryConfig) string {
+	var b strings.Builder
+	server := registryServer(registry)
+	insecure := utils.InList(registry, r.Insecure)
+	if insecure {
+		fmt.Fprintf(&b, "server = %q\n", strings.Replace(server, "https://", "http://", 1))
+	} else {
+		fmt.Fprintf(&b, "server = %q\n", server)
+		if _, ok := r.CAFiles[registry]; ok {
+			fmt.Fprintf(&b, "ca = %q\n", path.Join(ContainerdCertsDir, registry, "ca.crt"))
+		}
+	}
+	for _, mirror := range r.Mirrors[registry] {
+		endpoint := mirrorEndpoint(mirror)
+		u, err := url.Parse(endpoint)
+		mirrorInsecure := err == nil && utils.InList(u.Host, r.Insecure)
+		writeContainerdHost(&b, endpoint, `["pull", "resolve"]`, mirrorInsecure)
+		// the insecure mirror without scheme falls back to plain http like the registry.
+		if mirrorInsecure && !strings.Contains(mirror, "://") {
+			writeContainerdHost(&b, "http://"+mirror, `["pull", "resolve"]`, false)
+		}
+	}
+	if insecure {
+		writeContainerdHost(&b, server, `["pull", "resolve", "push"]`, true)
+	}
+	return b.String()
+}
+
+func writeContainerdHost(b *strings.Builder, endpoint, capabilities string, skipVerify bool) {
+	fmt.Fprintf(b, "\n[host.%q]\n  capabilities = %s\n", endpoint, capabilities)
+	if skipVerify {
+		b.WriteString("  skip_verify = true\n")
+	}
+}
+
+// containerdConfig set the config_path of the cri registry to ContainerdCertsDir, and render the auths in the sealos block.
+// containerd refuses to load the cri plugin if the mirrors or the tls configs are set with config_path, so these tables
+// are removed from the current config, the mirrors and CAs of sealos config are rendered to hosts.toml instead.
+func containerdConfig(current string, r v1.RegistryConfig) string {
+	config := strings.TrimRight(registryBlockRegexp.ReplaceAllString(current, "\n"), "\n") + "\n"
+	config = removeTomlTables(config, containerdRegistryConflictRegexp)
+	configPath := fmt.Sprintf("config_path = %q", ContainerdCertsDir)
+	var block strings.Builder
+	block.WriteString("\n" + registryBlockBegin + "\n")
+	switch {
+	case containerdConfigPathRegexp.MatchString(config):
+		config = containerdConfigPathRegexp.ReplaceAllString(config, "${1}"+configPath)
+	case strings.Contains(config, containerdRegistryTable):
+		config = strings.Replace(config, containerdRegistryTable, containerdRegistryTable+"\n  "+configPath, 1)
+	default:
+		fmt.Fprintf(&block, "%s\n  %s\n", containerdRegistryTable, configPath)
+	}
+	for _, registry := range r.Registries() {
+		auth, ok := r.Auths[registry]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&block, "[plugins.\"io.containerd.grpc.v1.cri\".registry.configs.%q.auth]\n  username = %q\n  password = %q\n", registry, auth.Username, auth.Password)
+	}
+	block.WriteString(registryBlockEnd + "\n")
+	return config + block.String()
+}
+
+// removeTomlTables remove the tables whose header matches re, a table ends at the next table header.
+func removeTomlTables(config string, re *regexp.Regexp) string {
+	var b strings.Builder
+	removing := false
+	for _, line := range strings.SplitAfter(config, "\n") {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "[") {
+			removing = re.MatchString(trimmed)
+		}
+		if !removing {
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// dockerDaemonJSON set the registry-mirrors and insecure-registries of daemon.json, the other settings are kept.
+func dockerDaemonJSON(current []byte, r v1.RegistryConfig) ([]byte, error) {
+	daemon := make(map[string]interface{})
+	if len(bytes.TrimSpace(current)) != 0 {
+		if err := json.Unmarshal(current, &daemon); err != nil {
+			return nil, err
+		}
+	}
+	delete(daemon, "registry-mirrors")
+	delete(daemon, "insecure-registries")
+	if mirrors := r.Mirrors[DockerHubRegistry]; len(mirrors) != 0 {
+		var endpoints []string
+		for _, mirror := range mirrors {
+			endpoints = append(endpoints, mirrorEndpoint(mirror))
+		}
+		daemon["registry-mirrors"] = endpoints
+	}
+	if len(r.Insecure) != 0 {
+		daemon["insecure-registries"] = r.Insecure
+	}
+	return json.MarshalIndent(daemon, "", "  ")
+}
+
+// dockerConfigJSON render the auths in the docker config.json format.
+func dockerConfigJSON(r v1.RegistryConfig) ([]byte, error) {
+	auths := make(map[string]map[string]string)
+	for registry, auth := range r.Auths {
+		if registry == DockerHubRegistry {
+			registry = "https://index.docker.io/v1/"
+		}
+		auths[registry] = map[string]string{"auth": base64.StdEncoding.EncodeToString([]byte(auth.Username + ":" + auth.Password))}
+	}
+	return json.MarshalIndent(map[string]interface{}{"auths": auths}, "", "  ")
+}
+
+func registryServer(registry string) string {
+	if registry == DockerHubRegistry {
+		return "https://registry-1.docker.io"
+	}
+	return "https://" + registry
+}
+
+// mirrorEndpoint add https scheme if the mirror has no scheme.
+func mirrorEndpoint(mirror string) string {
+	if strings.Contains(mirror, "://") {
+		return mirror
+	}
+	return "https://" + mirror
+}
@@ -0,0 +1,176 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+)
+
+var testRegistryConfig = v1.RegistryConfig{
+	Mirrors:  map[string][]string{"docker.io": {"mirror.example.com", "http://10.0.0.2:5000"}},
+	Insecure: []string{"10.0.0.2:5000", "harbor.example.com"},
+	CAFiles:  map[string]string{"harbor.example.com": "/root/harbor-ca.crt"},
+	Auths:    map[string]v1.RegistryAuth{"harbor.example.com": {Username: "admin", Password: "pass"}},
+}
+
+func TestContainerdHostsToml(t *testing.T) {
+	want := `server = "https://registry-1.docker.io"
+
+[host."https://mirror.example.com"]
+  capabilities = ["pull", "resolve"]
+
+[host."http://10.0.0.2:5000"]
+  capabilities = ["pull", "resolve"]
+  skip_verify = true
+`
+	if got := containerdHostsToml("docker.io", testRegistryConfig); got != want {
+		t.Errorf("containerdHostsToml(docker.io) = %s, want %s", got, want)
+	}
+	want = `server = "http://harbor.example.com"
+
+[host."https://harbor.example.com"]
+  capabilities = ["pull", "resolve", "push"]
+  skip_verify = true
+`
+	if got := containerdHostsToml("harbor.example.com", testRegistryConfig); got != want {
+		t.Errorf("containerdHostsToml(harbor.example.com) = %s, want %s", got, want)
+	}
+
+	r := v1.RegistryConfig{
+		Mirrors:  map[string][]string{"quay.io": {"10.0.0.3:5000"}},
+		Insecure: []string{"10.0.0.3:5000"},
+		CAFiles:  map[string]string{"quay.io": "/root/quay-ca.crt"},
+	}
+	want = `server = "https://quay.io"
+ca = "/etc/containerd/certs.d/quay.io/ca.crt"
+
+[host."https://10.0.0.3:5000"]
+  capabilities = ["pull", "resolve"]
+  skip_verify = true
+
+[host."http://10.0.0.3:5000"]
+  capabilities = ["pull", "resolve"]
+`
+	if got := containerdHostsToml("quay.io", r); got != want {
+		t.Errorf("containerdHostsToml(quay.io) = %s, want %s", got, want)
+	}
+}
+
+func TestContainerdConfig(t *testing.T) {
+	current := `version = 2
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = ""
+`
+	want := `version = 2
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "/etc/containerd/certs.d"
+
+# sealos registry begin
+[plugins."io.containerd.grpc.v1.cri".registry.configs."harbor.example.com".auth]
+  username = "admin"
+  password = "pass"
+# sealos registry end
+`
+	got := containerdConfig(current, testRegistryConfig)
+	if got != want {
+		t.Errorf("containerdConfig() = %s, want %s", got, want)
+	}
+	// render again should replace the sealos block.
+	if again := containerdConfig(got, testRegistryConfig); again != want {
+		t.Errorf("containerdConfig() again = %s, want %s", again, want)
+	}
+
+	want = `version = 2
+
+# sealos registry begin
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "/etc/containerd/certs.d"
+# sealos registry end
+`
+	if got := containerdConfig("version = 2\n", v1.RegistryConfig{}); got != want {
+		t.Errorf("containerdConfig() without registry table = %s, want %s", got, want)
+	}
+
+	// the mirrors and tls configs conflict with config_path, they are removed.
+	current = `version = 2
+[plugins."io.containerd.grpc.v1.cri".registry]
+[plugins."io.containerd.grpc.v1.cri".registry.mirrors]
+  [plugins."io.containerd.grpc.v1.cri".registry.mirrors."docker.io"]
+    endpoint = ["https://mirror.example.com"]
+[plugins."io.containerd.grpc.v1.cri".registry.configs."harbor.example.com".tls]
+  insecure_skip_verify = true
+[plugins."io.containerd.grpc.v1.cri".registry.configs."quay.io".auth]
+  username = "quay"
+[plugins."io.containerd.runtime.v1.linux"]
+  shim = "containerd-shim"
+`
+	want = `version = 2
+[plugins."io.containerd.grpc.v1.cri".registry]
+  config_path = "/etc/containerd/certs.d"
+[plugins."io.containerd.grpc.v1.cri".registry.configs."quay.io".auth]
+  username = "quay"
+[plugins."io.containerd.runtime.v1.linux"]
+  shim = "containerd-shim"
+
+# sealos registry begin
+# sealos registry end
+`
+	if got := containerdConfig(current, v1.RegistryConfig{}); got != want {
+		t.Errorf("containerdConfig() with mirrors = %s, want %s", got, want)
+	}
+}
+
+func TestDockerDaemonJSON(t *testing.T) {
+	data, err := dockerDaemonJSON([]byte(`{"exec-opts": ["native.cgroupdriver=systemd"], "registry-mirrors": ["https://old.example.com"]}`), testRegistryConfig)
+	if err != nil {
+		t.Fatalf("dockerDaemonJSON() err: %v", err)
+	}
+	var got map[string][]string
+	if err = json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"exec-opts":           {"native.cgroupdriver=systemd"},
+		"registry-mirrors":    {"https://mirror.example.com", "http://10.0.0.2:5000"},
+		"insecure-registries": {"10.0.0.2:5000", "harbor.example.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dockerDaemonJSON() = %v, want %v", got, want)
+	}
+}
+
+func TestContainerdVersion(t *testing.T) {
+	for out, want := range map[string]string{
+		"containerd github.com/containerd/containerd v1.5.8 1e5ef943eb76627a6d3b6de8cd1ef6537f393a71\n": "1.5.8",
+		"containerd containerd.io 1.4.12 7b11cfaabd73bb80907dd23182b9347b4245eb5d":                      "1.4.12",
+		"containerd: command not found":                                "",
+		"containerd github.com/containerd/containerd v1.10.0-rc.1 abc": "1.10.0",
+	} {
+		v, err := containerdVersion(out)
+		if want == "" {
+			if err == nil {
+				t.Errorf("containerdVersion(%q) = %s, want error", out, v)
+			}
+			continue
+		}
+		if err != nil || v.String() != want {
+			t.Errorf("containerdVersion(%q) = %v, %v, want %s", out, v, err, want)
+		}
+	}
+}
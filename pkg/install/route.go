@@ -15,8 +15,17 @@
 package install
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
 
 	"github.com/fanux/sealos/pkg/utils"
 
@@ -24,9 +33,39 @@ import (
 	k8snet "k8s.io/apimachinery/pkg/util/net"
 )
 
+const (
+	// routePriority is the metric of the routes managed by sealos, it tells them from the other routes.
+	routePriority   = 50
+	routeUnitDir    = "/etc/systemd/system"
+	routeUnitPrefix = "sealos-route-"
+	routeUnit       = `[Unit]
+Description=sealos route %[1]s via %[2]s
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=/usr/bin/sealos route add --host %[1]s --gateway %[2]s
+
+[Install]
+WantedBy=multi-user.target
+`
+)
+
 type RouteFlags struct {
 	Host    string
 	Gateway string
+	// Persist install a systemd unit to add the route again at boot.
+	Persist bool
+}
+
+// RouteInfo is a route managed by sealos.
+type RouteInfo struct {
+	Host       string
+	Gateway    string
+	Persistent bool
+	Active     bool
 }
 
 func GetRouteFlag(host, gateway string) *RouteFlags {
@@ -56,10 +95,16 @@ func (r *RouteFlags) CheckRoute() {
 
 func (r *RouteFlags) SetRoute() {
 	if r.useGatewayManageRoute() {
-		err := addRouteGatewayViaHost(r.Host, r.Gateway, 50)
-		if err != nil {
+		err := addRouteGatewayViaHost(r.Host, r.Gateway, routePriority)
+		// the route is added again by the unit at boot, or by a join after the unit.
+		if err != nil && !errors.Is(err, syscall.EEXIST) {
 			fmt.Println("addRouteGatewayViaHost err: ", err)
 		}
+		if r.Persist {
+			if err := persistRoute(r.Host, r.Gateway); err != nil {
+				fmt.Println("persistRoute err: ", err)
+			}
+		}
 	}
 }
 
@@ -69,7 +114,121 @@ func (r *RouteFlags) DelRoute() {
 		if err != nil {
 			fmt.Println("delRouteGatewayViaHost err: ", err)
 		}
+		if err := unpersistRoute(r.Host); err != nil {
+			fmt.Println("unpersistRoute err: ", err)
+		}
+	}
+}
+
+// ListRoute print the routes managed by sealos on this host, the active ones and the persistent ones.
+func (r *RouteFlags) ListRoute() error {
+	routes, err := listRoutes()
+	if err != nil {
+		return err
+	}
+	return printRoutes(os.Stdout, routes)
+}
+
+// routeUnitName return the systemd unit name of the route to host.
+func routeUnitName(host string) string {
+	return fmt.Sprintf("%s%s.service", routeUnitPrefix, host)
+}
+
+// parseRouteUnit return the host and gateway of the route in the unit content.
+func parseRouteUnit(content string) (host, gateway string) {
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(line, "ExecStart=") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "ExecStart="))
+		for i := 0; i+1 < len(fields); i++ {
+			switch fields[i] {
+			case "--host":
+				host = fields[i+1]
+			case "--gateway":
+				gateway = fields[i+1]
+			}
+		}
+	}
+	return host, gateway
+}
+
+func persistRoute(host, gateway string) error {
+	unit := filepath.Join(routeUnitDir, routeUnitName(host))
+	if err := ioutil.WriteFile(unit, []byte(fmt.Sprintf(routeUnit, host, gateway)), 0644); err != nil {
+		return err
+	}
+	if out, err := utils.RunSimpleCmd(fmt.Sprintf("systemctl daemon-reload && systemctl enable %s", routeUnitName(host))); err != nil {
+		return fmt.Errorf("%s: %w", strings.TrimSpace(out), err)
+	}
+	return nil
+}
+
+func unpersistRoute(host string) error {
+	unit := filepath.Join(routeUnitDir, routeUnitName(host))
+	if !utils.FileExist(unit) {
+		return nil
+	}
+	_, _ = utils.RunSimpleCmd(fmt.Sprintf("systemctl disable %s", routeUnitName(host)))
+	if err := os.Remove(unit); err != nil {
+		return err
+	}
+	if out, err := utils.RunSimpleCmd("systemctl daemon-reload"); err != nil {
+		return fmt.Errorf("%s: %w", strings.TrimSpace(out), err)
+	}
+	return nil
+}
+
+// listRoutes merge the host routes added by sealos and the route units.
+func listRoutes() ([]RouteInfo, error) {
+	routes := map[string]*RouteInfo{}
+	units, err := filepath.Glob(filepath.Join(routeUnitDir, routeUnitPrefix+"*.service"))
+	if err != nil {
+		return nil, err
+	}
+	for _, unit := range units {
+		content, err := ioutil.ReadFile(unit)
+		if err != nil {
+			return nil, err
+		}
+		host, gateway := parseRouteUnit(string(content))
+		if host == "" {
+			continue
+		}
+		routes[host] = &RouteInfo{Host: host, Gateway: gateway, Persistent: true}
+	}
+	list, err := netlink.RouteList(nil, netlink.FAMILY_V4)
+	if err != nil {
+		return nil, err
+	}
+	for _, rt := range list {
+		if rt.Priority != routePriority || rt.Dst == nil || rt.Gw == nil {
+			continue
+		}
+		if ones, _ := rt.Dst.Mask.Size(); ones != 32 {
+			continue
+		}
+		host := rt.Dst.IP.String()
+		if _, ok := routes[host]; !ok {
+			routes[host] = &RouteInfo{Host: host, Gateway: rt.Gw.String()}
+		}
+		routes[host].Active = true
+	}
+	infos := make([]RouteInfo, 0, len(routes))
+	for _, r := range routes {
+		infos = append(infos, *r)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Host < infos[j].Host })
+	return infos, nil
+}
+
+func printRoutes(w io.Writer, routes []RouteInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOST\tGATEWAY\tPERSISTENT\tACTIVE")
+	for _, r := range routes {
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%t\n", r.Host, r.Gateway, r.Persistent, r.Active)
 	}
+	return tw.Flush()
 }
 
 // getDefaultRouteIp is get host ip by ChooseHostInterface() .
@@ -0,0 +1,47 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseRouteUnit(t *testing.T) {
+	host, gateway := parseRouteUnit(fmt.Sprintf(routeUnit, "10.103.97.2", "192.168.0.2"))
+	if host != "10.103.97.2" || gateway != "192.168.0.2" {
+		t.Errorf("parseRouteUnit() = %s, %s", host, gateway)
+	}
+	if host, gateway = parseRouteUnit("[Service]\nType=oneshot\n"); host != "" || gateway != "" {
+		t.Errorf("parseRouteUnit() = %s, %s, want empty", host, gateway)
+	}
+	if got := routeUnitName("10.103.97.2"); got != "sealos-route-10.103.97.2.service" {
+		t.Errorf("routeUnitName() = %s", got)
+	}
+}
+
+func TestPrintRoutes(t *testing.T) {
+	var buf bytes.Buffer
+	routes := []RouteInfo{{Host: "10.103.97.2", Gateway: "192.168.0.2", Persistent: true, Active: false}}
+	if err := printRoutes(&buf, routes); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || strings.Join(strings.Fields(lines[1]), " ") != "10.103.97.2 192.168.0.2 true false" {
+		t.Errorf("printRoutes() = %q", buf.String())
+	}
+}
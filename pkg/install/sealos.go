@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/fanux/sealos/pkg/cni"
 	"github.com/fanux/sealos/pkg/logger"
 
 	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
@@ -75,8 +76,8 @@ func (s *SealosInstaller) Command(version string, name CommandType) (cmd string)
 	// "kubeadm config migrate" command of kubeadm v1.15.x, 因此1.14 版本不支持双网卡.
 	commands := map[CommandType]string{
 		InitMaster: `kubeadm init --config=/root/kubeadm-config.yaml --experimental-upload-certs` + v1.VLogString(),
-		JoinMaster: fmt.Sprintf("kubeadm join %s:6443 --token %s --discovery-token-ca-cert-hash %s --experimental-control-plane --certificate-key %s"+v1.VLogString(), utils.IPFormat(s.Masters[0]), JoinToken, TokenCaCertHash, CertificateKey),
-		JoinNode:   fmt.Sprintf("kubeadm join %s:6443 --token %s --discovery-token-ca-cert-hash %s"+v1.VLogString(), v1.VIP, JoinToken, TokenCaCertHash),
+		JoinMaster: fmt.Sprintf("kubeadm join %s:%d --token %s --discovery-token-ca-cert-hash %s --experimental-control-plane --certificate-key %s"+v1.VLogString(), utils.IPFormat(s.Masters[0]), v1.APIServerPort, JoinToken, TokenCaCertHash, CertificateKey),
+		JoinNode:   fmt.Sprintf("kubeadm join %s:%d --token %s --discovery-token-ca-cert-hash %s"+v1.VLogString(), v1.VIP, v1.LoadBalancer.Port, JoinToken, TokenCaCertHash),
 	}
 	//other version >= 1.15.x
 	//todo
@@ -86,18 +87,16 @@ func (s *SealosInstaller) Command(version string, name CommandType) (cmd string)
 		commands[JoinNode] = "kubeadm join --config=/root/kubeadm-join-config.yaml " + v1.VLogString()
 	}
 
-	// version >= 1.16.x support kubeadm init --skip-phases=addon/kube-proxy
-	// version <= 115
-	// kubectl -n kube-system delete ds kube-proxy
-	// # Run on each node:
-	// iptables-restore <(iptables-save | grep -v KUBE)
-	//if s.Network == cni.CILIUM {
-	//	if utils.VersionToInt(version) >= 116 {
-	//		commands[InitMaster] = `kubeadm init --skip-phases=addon/kube-proxy --config=/root/kubeadm-config.yaml --upload-certs` + v1.VLogString()
-	//	} else {
-	//		commands[InitMaster] = `kubeadm init --config=/root/kubeadm-config.yaml --upload-certs` + v1.VLogString()
-	//	}
-	//}
+	// cilium replaces kube-proxy, version >= 1.16.x support kubeadm init --skip-phases=addon/kube-proxy
+	if !v1.WithoutCNI && networkName() == cni.CILIUM && utils.VersionToInt(version) >= 116 {
+		commands[InitMaster] = `kubeadm init --skip-phases=addon/kube-proxy --config=/root/kubeadm-config.yaml --upload-certs` + v1.VLogString()
+	}
+
+	// the load balancer static pods are placed on the masters before kubeadm init and join
+	if v1.LoadBalancer.OnMasters() {
+		commands[InitMaster] += lbIgnorePreflight
+		commands[JoinMaster] += lbIgnorePreflight
+	}
 
 	v, ok := commands[name]
 	defer func() {
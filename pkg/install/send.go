@@ -17,6 +17,10 @@ package install
 import (
 	"fmt"
 	"path"
+	"sync"
+
+	runtime "github.com/fanux/sealos/pkg/cri/runtime"
+	"github.com/fanux/sealos/pkg/logger"
 
 	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
 	"github.com/fanux/sealos/pkg/utils"
@@ -27,10 +31,14 @@ import (
 func (s *SealosInstaller) SendPackage() {
 	pkg := path.Base(v1.PkgURL)
 	// rm old sealos in package avoid old version problem. if sealos not exist in package then skip rm
-	kubeHook := fmt.Sprintf("cd /root && rm -rf kube && tar zxvf %s  && cd /root/kube/shell && rm -f ../bin/sealos && bash init.sh", pkg)
+	kubeHook := fmt.Sprintf("cd /root && rm -rf kube && tar zxvf %s  && cd /root/kube/shell && rm -f ../bin/sealos && %s", pkg, packageInitCmd(v1.Version))
 	deletekubectl := `sed -i '/kubectl/d;/sealos/d' /root/.bashrc `
 	completion := "echo 'command -v kubectl &>/dev/null && source <(kubectl completion bash)' >> /root/.bashrc && echo '[ -x /usr/bin/sealos ] && source <(sealos completion bash)' >> /root/.bashrc && source /root/.bashrc"
 	kubeHook = kubeHook + " && " + deletekubectl + " && " + completion
+	if v1.LocalRegistry != "" {
+		s.sendPackageWithLocalRegistry(kubeHook)
+		return
+	}
 	v1.PkgURL = ssh.CopyFiles(v1.SSHConfig, v1.PkgURL, s.Hosts, "/root", nil, &kubeHook)
 }
 
@@ -43,18 +51,67 @@ func (s *SealosInstaller) SendSealos() {
 	ssh.CopyFiles(v1.SSHConfig, sealos, s.Hosts, "/usr/bin", &beforeHook, &afterHook)
 }
 
-// SendPackage is send new pkg to all nodes.
-func (u *SealosUpgrade) SendPackage() {
+// SendPackage is send new pkg to all nodes. The binaries of the package are installed only after its images
+// are loaded on all nodes, so the nodes are not changed if the images failed to load.
+func (u *SealosUpgrade) SendPackage() error {
 	all := append(u.Masters, u.Nodes...)
 	pkg := path.Base(u.NewPkgURL)
 	// rm old sealos in package avoid old version problem. if sealos not exist in package then skip rm
-	var kubeHook string
-	if utils.For120(v1.Version) {
-		// TODO update need load modprobe -- br_netfilter modprobe -- bridge.
-		// https://github.com/fanux/cloud-kernel/issues/23
-		kubeHook = fmt.Sprintf("cd /root && rm -rf kube && tar zxvf %s  && cd /root/kube/shell && rm -f ../bin/sealos && (ctr -n=k8s.io image import ../images/images.tar || true) && cp -f ../bin/* /usr/bin/ ", pkg)
-	} else {
-		kubeHook = fmt.Sprintf("cd /root && rm -rf kube && tar zxvf %s  && cd /root/kube/shell && rm -f ../bin/sealos && (docker load -i ../images/images.tar || true) && cp -f ../bin/* /usr/bin/ ", pkg)
+	// TODO update need load modprobe -- br_netfilter modprobe -- bridge.
+	// https://github.com/fanux/cloud-kernel/issues/23
+	kubeHook := fmt.Sprintf("cd /root && rm -rf kube && tar zxvf %s  && cd /root/kube/shell && rm -f ../bin/sealos", pkg)
+	v1.PkgURL = ssh.CopyFiles(v1.SSHConfig, u.NewPkgURL, all, "/root", nil, &kubeHook)
+	// the hook errors are ignored by CopyFiles, the images are loaded here to stop the upgrade if it failed.
+	load := "cd /root/kube/shell && " + imageLoadCmd(criName(v1.Version), "../images/images.tar")
+	if failed := cmdOnHosts(all, load); len(failed) != 0 {
+		return fmt.Errorf("load the images of the package failed on %v, the binaries are not installed", failed)
+	}
+	if failed := cmdOnHosts(all, "cp -f /root/kube/bin/* /usr/bin/"); len(failed) != 0 {
+		return fmt.Errorf("install the binaries of the package failed on %v", failed)
+	}
+	return nil
+}
+
+// cmdOnHosts run the cmd on the hosts in parallel, return the hosts failed.
+func cmdOnHosts(hosts []string, cmd string) []string {
+	var wg sync.WaitGroup
+	var failed []string
+	var mu sync.Mutex
+	for _, host := range hosts {
+		wg.Add(1)
+		go func(host string) {
+			defer wg.Done()
+			if err := v1.SSHConfig.CmdAsync(host, cmd); err != nil {
+				logger.Error("[%s] %s err: %s", host, cmd, err)
+				mu.Lock()
+				failed = append(failed, host)
+				mu.Unlock()
+			}
+		}(host)
+	}
+	wg.Wait()
+	return failed
+}
+
+// packageInitCmd run init.sh of the package. If the selected cri is not the one of the package, the cri setup script
+// of the package is emptied, so init.sh does not install it, and the package images are loaded to the selected cri.
+func packageInitCmd(version string) string {
+	cri, pkgCRI := criName(version), packageCRI(version)
+	if cri == pkgCRI {
+		return "bash init.sh"
+	}
+	return fmt.Sprintf(": > %s.sh && bash init.sh && %s", pkgCRI, imageLoadCmd(cri, "../images/images.tar"))
+}
+
+// imageLoadCmd load the images tar to the container runtime.
+func imageLoadCmd(cri, imagesTar string) string {
+	switch cri {
+	case runtime.Containerd:
+		return "ctr -n=k8s.io image import " + imagesTar
+	case runtime.CRIO:
+		// CRI-O shares the containers storage with podman.
+		return "podman load -i " + imagesTar
+	default:
+		return "docker load -i " + imagesTar
 	}
-	v1.PkgURL = ssh.CopyFiles(v1.SSHConfig, pkg, all, "/root", nil, &kubeHook)
 }
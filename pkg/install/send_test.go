@@ -0,0 +1,44 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"testing"
+
+	runtime "github.com/fanux/sealos/pkg/cri/runtime"
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+)
+
+func TestPackageInitCmd(t *testing.T) {
+	defer func(cri string) { v1.CRI = cri }(v1.CRI)
+	tests := []struct {
+		cri     string
+		version string
+		want    string
+	}{
+		{"", "v1.19.0", "bash init.sh"},
+		{"", "v1.20.0", "bash init.sh"},
+		{runtime.Containerd, "v1.20.0", "bash init.sh"},
+		{runtime.Docker, "v1.20.0", ": > containerd.sh && bash init.sh && docker load -i ../images/images.tar"},
+		{runtime.CRIO, "v1.21.0", ": > containerd.sh && bash init.sh && podman load -i ../images/images.tar"},
+		{runtime.Containerd, "v1.19.0", ": > docker.sh && bash init.sh && ctr -n=k8s.io image import ../images/images.tar"},
+	}
+	for _, tt := range tests {
+		v1.CRI = tt.cri
+		if got := packageInitCmd(tt.version); got != tt.want {
+			t.Errorf("packageInitCmd(%s) with cri %q = %s, want %s", tt.version, tt.cri, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fanux/sealos/pkg/logger"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+
+	"github.com/fanux/sealos/pkg/kubernetes/cert"
+	"github.com/fanux/sealos/pkg/kubernetes/nodeclient"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	certutil "k8s.io/client-go/util/cert"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	StatusOutputTable = "table"
+	StatusOutputJSON  = "json"
+	StatusOutputYAML  = "yaml"
+
+	LvscarePodName = "kube-sealyun-lvscare"
+)
+
+// NodeStatus is a sealos host matched with the kubernetes node object.
+type NodeStatus struct {
+	IP             string `json:"ip"`
+	Role           string `json:"role"`
+	Name           string `json:"name"`
+	Ready          bool   `json:"ready"`
+	KubeletVersion string `json:"kubeletVersion"`
+	Arch           string `json:"arch"`
+	Lvscare        bool   `json:"lvscare"`
+	Error          string `json:"error,omitempty"`
+}
+
+// StaticPodStatus is the health of a control-plane static pod.
+type StaticPodStatus struct {
+	Name  string `json:"name"`
+	Node  string `json:"node"`
+	Phase string `json:"phase"`
+	Ready bool   `json:"ready"`
+}
+
+// CertStatus is the expiration of a cert in sealos pki dir.
+type CertStatus struct {
+	Name     string    `json:"name"`
+	NotAfter time.Time `json:"notAfter"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// ClusterStatus is the cluster overview for sealos status.
+type ClusterStatus struct {
+	Version      string            `json:"version"`
+	Metadata     *v1.Metadata      `json:"metadata,omitempty"`
+	Nodes        []NodeStatus      `json:"nodes"`
+	ControlPlane []StaticPodStatus `json:"controlPlane"`
+	Etcd         []EpHealth        `json:"etcd"`
+	Certs        []CertStatus      `json:"certs"`
+}
+
+// GetClusterStatus collect the cluster status by sealos config and kubernetes api.
+func GetClusterStatus(cfgFile string) (*ClusterStatus, error) {
+	c := &v1.SealConfig{}
+	if err := c.Load(cfgFile); err != nil {
+		return nil, err
+	}
+	if !utils.FileExist(nodeclient.KubeDefaultConfigPath) {
+		return nil, fmt.Errorf("KubeDefaultConfigPath %s is not exist", nodeclient.KubeDefaultConfigPath)
+	}
+	k8sClient, err := nodeclient.NewClient(nodeclient.KubeDefaultConfigPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("get k8s client err: %w", err)
+	}
+	status := &ClusterStatus{Version: c.Version}
+	if len(c.Masters) > 0 {
+		if status.Metadata, err = GetMetadata(c.Masters[0]); err != nil {
+			logger.Warn("get metadata err: %s", err)
+		}
+	}
+	nodes, err := nodeclient.GetNodeList(k8sClient)
+	if err != nil {
+		return nil, fmt.Errorf("get kubernetes node list err: %w", err)
+	}
+	pods, err := k8sClient.CoreV1().Pods(metav1.NamespaceSystem).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get kube-system pod list err: %w", err)
+	}
+	lvscareNodes := make(map[string]bool)
+	for _, pod := range pods.Items {
+		component := pod.Labels["component"]
+		if component == LvscarePodName {
+			lvscareNodes[pod.Spec.NodeName] = true
+			continue
+		}
+		if pod.Labels["tier"] == "control-plane" {
+			status.ControlPlane = append(status.ControlPlane, StaticPodStatus{
+				Name:  component,
+				Node:  pod.Spec.NodeName,
+				Phase: string(pod.Status.Phase),
+				Ready: isPodReady(pod),
+			})
+		}
+	}
+	for _, master := range c.Masters {
+		status.Nodes = append(status.Nodes, nodeStatus(master, "master", nodes.Items, lvscareNodes))
+	}
+	for _, node := range c.Nodes {
+		status.Nodes = append(status.Nodes, nodeStatus(node, "node", nodes.Items, lvscareNodes))
+	}
+
+	e := &EtcdFlags{SealConfig: *c}
+	if e.CertFileExist() {
+		for _, h := range c.Masters {
+			e.Endpoints = append(e.Endpoints, fmt.Sprintf("%s:2379", reFormatHostToIP(h)))
+		}
+		status.Etcd = e.HealthCheck()
+	} else {
+		logger.Warn("ETCD CaCert or key file is not exist, skip etcd health check")
+	}
+	status.Certs = certsStatus(v1.CertPath, v1.CertEtcdPath)
+	return status, nil
+}
+
+func nodeStatus(host, role string, nodes []corev1.Node, lvscareNodes map[string]bool) NodeStatus {
+	ip := reFormatHostToIP(host)
+	ns := NodeStatus{IP: ip, Role: role}
+	for _, node := range nodes {
+		for _, addr := range node.Status.Addresses {
+			if addr.Type == corev1.NodeInternalIP && addr.Address == ip {
+				ns.Name = node.Name
+				ns.Ready = nodeclient.IsNodeReady(node)
+				ns.KubeletVersion = node.Status.NodeInfo.KubeletVersion
+				ns.Arch = node.Status.NodeInfo.Architecture
+				ns.Lvscare = lvscareNodes[node.Name]
+				return ns
+			}
+		}
+	}
+	ns.Error = "not found in kubernetes"
+	return ns
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+func certsStatus(certPath, certEtcdPath string) []CertStatus {
+	var list []CertStatus
+	for _, c := range append(cert.CaList(certPath, certEtcdPath), cert.List(certPath, certEtcdPath)...) {
+		certFile := filepath.Join(c.Path, c.BaseName+".crt")
+		name, _ := filepath.Rel(certPath, certFile)
+		cs := CertStatus{Name: name}
+		certs, err := certutil.CertsFromFile(certFile)
+		if err != nil {
+			cs.Error = err.Error()
+		} else {
+			cs.NotAfter = certs[0].NotAfter
+		}
+		list = append(list, cs)
+	}
+	return list
+}
+
+// Print write the cluster status to w by output format.
+func (s *ClusterStatus) Print(w io.Writer, output string) error {
+	switch output {
+	case StatusOutputJSON:
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case StatusOutputYAML:
+		data, err := yaml.Marshal(s)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, string(data))
+		return err
+	case StatusOutputTable, "":
+		return s.printTable(w)
+	default:
+		return fmt.Errorf("unsupported output format %s, only support table|json|yaml", output)
+	}
+}
+
+func (s *ClusterStatus) printTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "VERSION:\t%s\n", s.Version)
+	if s.Metadata != nil {
+		fmt.Fprintf(tw, "PACKAGE:\tk8s %s, %s %s\n", s.Metadata.K8sVersion, s.Metadata.CniName, s.Metadata.CniVersion)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "IP\tROLE\tNAME\tREADY\tKUBELET\tARCH\tLVSCARE\tERROR")
+	for _, n := range s.Nodes {
+		lvscare := "-"
+		if n.Role == "node" {
+			lvscare = fmt.Sprintf("%t", n.Lvscare)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\t%s\t%s\t%s\n", n.IP, n.Role, n.Name, n.Ready, n.KubeletVersion, n.Arch, lvscare, n.Error)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "COMPONENT\tNODE\tPHASE\tREADY")
+	for _, p := range s.ControlPlane {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\n", p.Name, p.Node, p.Phase, p.Ready)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "ETCD ENDPOINT\tHEALTH\tTOOK\tERROR")
+	for _, h := range s.Etcd {
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%s\n", h.Ep, h.Health, h.Took, h.Error)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "CERT\tEXPIRES\tERROR")
+	for _, c := range s.Certs {
+		expires := "-"
+		if !c.NotAfter.IsZero() {
+			expires = c.NotAfter.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, expires, c.Error)
+	}
+	return tw.Flush()
+}
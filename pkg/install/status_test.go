@@ -0,0 +1,122 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fanux/sealos/pkg/kubernetes/cert"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestNodeStatus(t *testing.T) {
+	nodes := []corev1.Node{{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Status: corev1.NodeStatus{
+			Addresses:  []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "192.168.0.3"}},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			NodeInfo:   corev1.NodeSystemInfo{KubeletVersion: "v1.19.0", Architecture: "amd64"},
+		},
+	}}
+	got := nodeStatus("192.168.0.3:22", "node", nodes, map[string]bool{"node-1": true})
+	want := NodeStatus{IP: "192.168.0.3", Role: "node", Name: "node-1", Ready: true, KubeletVersion: "v1.19.0", Arch: "amd64", Lvscare: true}
+	if got != want {
+		t.Errorf("nodeStatus() = %+v, want %+v", got, want)
+	}
+	got = nodeStatus("192.168.0.4", "node", nodes, nil)
+	if got.Name != "" || got.Error == "" {
+		t.Errorf("nodeStatus() of unknown host = %+v, want error", got)
+	}
+}
+
+func TestCertsStatus(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "sealos-status-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	certPath, certEtcdPath := tmpDir, filepath.Join(tmpDir, "etcd")
+	cfg := cert.CaList(certPath, certEtcdPath)[0]
+	cfg.Validity = time.Hour
+	cfg.KeyAlgorithm = x509.ECDSA
+	caCert, caKey, err := cert.NewCaCertAndKey(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cert.WriteCertAndKey(cfg.Path, cfg.BaseName, caCert, caKey); err != nil {
+		t.Fatal(err)
+	}
+
+	list := certsStatus(certPath, certEtcdPath)
+	if len(list) == 0 || list[0].Name != "ca.crt" {
+		t.Fatalf("certsStatus() = %+v, want ca.crt first", list)
+	}
+	if list[0].Error != "" || !list[0].NotAfter.Equal(caCert.NotAfter) {
+		t.Errorf("certsStatus() ca.crt = %+v, want NotAfter %s", list[0], caCert.NotAfter)
+	}
+	for _, c := range list[1:] {
+		if c.Error == "" {
+			t.Errorf("certsStatus() %s = %+v, want error of missing cert", c.Name, c)
+		}
+	}
+}
+
+func TestClusterStatusPrint(t *testing.T) {
+	status := &ClusterStatus{
+		Version: "v1.19.0",
+		Nodes:   []NodeStatus{{IP: "192.168.0.2", Role: "master", Name: "master-1", Ready: true}},
+		Certs:   []CertStatus{{Name: "ca.crt", NotAfter: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}},
+	}
+	for _, output := range []string{StatusOutputJSON, StatusOutputYAML} {
+		var buf bytes.Buffer
+		if err := status.Print(&buf, output); err != nil {
+			t.Fatal(err)
+		}
+		got := &ClusterStatus{}
+		var err error
+		if output == StatusOutputJSON {
+			err = json.Unmarshal(buf.Bytes(), got)
+		} else {
+			err = yaml.Unmarshal(buf.Bytes(), got)
+		}
+		if err != nil {
+			t.Fatalf("Print(%s) is not parsable: %s", output, err)
+		}
+		if got.Version != status.Version || len(got.Nodes) != 1 || got.Nodes[0] != status.Nodes[0] || !got.Certs[0].NotAfter.Equal(status.Certs[0].NotAfter) {
+			t.Errorf("Print(%s) = %+v, want %+v", output, got, status)
+		}
+	}
+	var buf bytes.Buffer
+	if err := status.Print(&buf, StatusOutputTable); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "master-1") || !strings.Contains(buf.String(), "2030-01-01T00:00:00Z") {
+		t.Errorf("Print(table) = %s", buf.String())
+	}
+	if err := status.Print(&buf, "xml"); err == nil {
+		t.Error("Print(xml) should return error")
+	}
+}
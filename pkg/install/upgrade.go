@@ -17,8 +17,10 @@ package install
 import (
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
+	"github.com/fanux/sealos/pkg/cni"
 	"github.com/fanux/sealos/pkg/logger"
 
 	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
@@ -26,6 +28,7 @@ import (
 
 	nodeclient2 "github.com/fanux/sealos/pkg/kubernetes/nodeclient"
 
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -35,10 +38,26 @@ type SealosUpgrade struct {
 	NewPkgURL    string
 	IPtoHostName map[string]string
 	Client       *kubernetes.Clientset
+
+	// MaxUnavailable is the number of worker nodes upgraded at the same time.
+	MaxUnavailable int
+	// DrainWorkers drain the worker nodes before upgrade, masters are always drained.
+	DrainWorkers bool
+	DrainTimeout time.Duration
+	ReadyTimeout time.Duration
 }
 
 var (
-	upgradeSealos = &SealosUpgrade{}
+	upgradeSealos = &SealosUpgrade{
+		MaxUnavailable: 1,
+		DrainTimeout:   DefaultDrainTimeout,
+		ReadyTimeout:   DefaultReadyTimeout,
+	}
+)
+
+const (
+	DefaultDrainTimeout = 5 * time.Minute
+	DefaultReadyTimeout = 5 * time.Minute
 )
 
 func NewUpgrade(version, pkgURL string) *SealosUpgrade {
@@ -56,12 +75,14 @@ func NewUpgrade(version, pkgURL string) *SealosUpgrade {
 	return u
 }
 
-func ExitUpgradeCase(version, pkgURL, cfgFile string) error {
-	if pkgURL == "" || version == "" {
+func ExitUpgradeCase(version string, pkgURLs []string, cfgFile string) error {
+	if len(pkgURLs) == 0 || version == "" {
 		return fmt.Errorf("version or pkg-url is required, Exit")
 	}
-	if utils.URLCheck(pkgURL) {
-		return fmt.Errorf("pkgurl %s check err, Exit", pkgURL)
+	for _, pkgURL := range pkgURLs {
+		if utils.URLCheck(pkgURL) {
+			return fmt.Errorf("pkgurl %s check err, Exit", pkgURL)
+		}
 	}
 	if !utils.FileExist(nodeclient2.KubeDefaultConfigPath) {
 		return fmt.Errorf("KubeDefaultConfigPath %s is not exist, Exit", nodeclient2.KubeDefaultConfigPath)
@@ -71,108 +92,215 @@ func ExitUpgradeCase(version, pkgURL, cfgFile string) error {
 		upgradeSealos.ShowDefaultConfig()
 		return err
 	}
+	// the cri is kept during upgrade, store the default cri of the current version.
+	v1.CRI = criName(v1.Version)
+	if err := CheckCRI(version); err != nil {
+		return err
+	}
+	// multi-hop upgrade check every hop by the plan, the target only must be newer.
+	if len(pkgURLs) > 1 {
+		return utils.CanUpgradeByHops(version, v1.Version)
+	}
 	return utils.CanUpgradeByNewVersion(version, v1.Version)
 }
 
+// GetRollbackUpgrade load the sealos config for sealos upgrade rollback, exit if failed.
+func GetRollbackUpgrade(cfgFile string) *SealosUpgrade {
+	u := upgradeSealos
+	if err := u.Load(cfgFile); err != nil {
+		logger.Error(err)
+		u.ShowDefaultConfig()
+		os.Exit(0)
+	}
+	return u
+}
+
 func (u *SealosUpgrade) SetUP() {
-	u.SendPackage()
-	u.UpgradeMaster0()
+	if err := u.Backup(); err != nil {
+		logger.Error("backup before upgrade err: ", err)
+		os.Exit(1)
+	}
+	if err := u.upgrade(); err != nil {
+		logger.Error("%s, the cluster is backed up before the upgrade, run sealos upgrade rollback to restore it", err)
+		os.Exit(1)
+	}
+}
+
+// upgrade the masters and nodes to the new version, it stops at the first failed node,
+// and the version is not updated until all nodes are upgraded.
+func (u *SealosUpgrade) upgrade() error {
+	if err := u.SendPackage(); err != nil {
+		return fmt.Errorf("upgrade to %s is stopped: %w", u.NewVersion, err)
+	}
+	if err := u.UpgradeMaster0(); err != nil {
+		return err
+	}
 	if len(u.Masters) > 1 {
-		u.UpgradeOtherMaster()
+		if err := u.UpgradeOtherMaster(); err != nil {
+			return err
+		}
 	}
 	if len(u.Nodes) >= 1 {
-		u.UpgradeNodes()
+		if err := u.UpgradeNodes(); err != nil {
+			return err
+		}
 	}
 	// store latest version and pkgUrl
 	v1.Version = u.NewVersion
 	v1.PkgURL = u.NewPkgURL
+	return nil
 }
 
 // UpgradeMaster0 is upgrade master first.
-func (u *SealosUpgrade) UpgradeMaster0() {
+func (u *SealosUpgrade) UpgradeMaster0() error {
 	logger.Info("UpgradeMaster0")
 	hostname := u.GetHostNamesFromIps(u.Masters[:1])
-	u.upgradeNodes(hostname, true)
+	return u.upgradeNodes(hostname, true)
 }
 
 // UpgradeNodes is upgrade nodes.
-func (u *SealosUpgrade) UpgradeNodes() {
+func (u *SealosUpgrade) UpgradeNodes() error {
 	logger.Info("UpgradeNodes")
 	hostnames := u.GetHostNamesFromIps(u.Nodes)
-	u.upgradeNodes(hostnames, false)
+	return u.upgradeNodes(hostnames, false)
 }
 
 // UpgradeOtherMaster is upgrade other master.
-func (u *SealosUpgrade) UpgradeOtherMaster() {
+func (u *SealosUpgrade) UpgradeOtherMaster() error {
 	logger.Info("UpgradeOtherMasters")
 	hostnames := u.GetHostNamesFromIps(u.Masters[1:])
-	u.upgradeNodes(hostnames, true)
+	return u.upgradeNodes(hostnames, true)
 }
 
-func (u *SealosUpgrade) upgradeNodes(hostnames []string, isMaster bool) {
-	wg := NewPool(2)
-	var err error
+// upgradeNodes upgrade the nodes, no more node is started after a node failed.
+func (u *SealosUpgrade) upgradeNodes(hostnames []string, isMaster bool) error {
+	// masters are upgraded one at a time to keep the etcd quorum.
+	wg := NewPool(1)
+	if !isMaster {
+		wg = NewPool(u.MaxUnavailable)
+	}
+	var mu sync.Mutex
+	var errs []error
 	for _, hostname := range hostnames {
 		wg.Add(1)
+		mu.Lock()
+		failed := len(errs) != 0
+		mu.Unlock()
+		if failed {
+			wg.Done()
+			break
+		}
 		go func(node string) {
 			defer wg.Done()
 			ip := u.GetIPByHostname(node)
-			// drain worker node is too danger for prod use; do not drain nodes if worker nodes~
-			if isMaster {
-				logger.Info("[%s] first: to drain master node %s", ip, node)
-				cmdDrain := fmt.Sprintf(`kubectl drain %s --ignore-daemonsets --delete-local-data`, node)
-				err := v1.SSHConfig.CmdAsync(u.Masters[0], cmdDrain)
-				if err != nil {
-					logger.Error("kubectl drain %s  err: %v", node, err)
-				}
-			} else {
-				logger.Info("first: to print upgrade node %s", node)
+			if err := u.upgradeNode(ip, node, isMaster); err != nil {
+				logger.Error("[%s] upgrade node %s err: %v", ip, node, err)
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("[%s] upgrade node %s err: %w", ip, node, err))
+				mu.Unlock()
 			}
+		}(hostname)
+	}
+	wg.Wait()
+	if len(errs) != 0 {
+		return fmt.Errorf("upgrade to %s is stopped: %w", u.NewVersion, utilerrors.NewAggregate(errs))
+	}
+	return nil
+}
 
-			// second to exec kubeadm upgrade node
-			logger.Info("[%s] second: to exec kubeadm upgrade node on %s", ip, node)
-			var cmdUpgrade string
-			if ip == u.Masters[0] {
-				cmdUpgrade = fmt.Sprintf("kubeadm upgrade apply --certificate-renewal=false  --yes %s", u.NewVersion)
-				err = v1.SSHConfig.CmdAsync(ip, cmdUpgrade)
-				if err != nil {
-					// master1 upgrade failed exit.
-					logger.Error("kubeadm upgrade err: ", err)
-					os.Exit(1)
-				}
-			} else {
-				cmdUpgrade = "kubeadm upgrade node --certificate-renewal=false"
-				err = v1.SSHConfig.CmdAsync(ip, cmdUpgrade)
-				if err != nil {
-					logger.Error("kubeadm upgrade err: ", err)
-				}
-			}
+// upgradeNode drain the node, upgrade it by kubeadm and restart kubelet,
+// the node is uncordoned only after it is Ready.
+func (u *SealosUpgrade) upgradeNode(ip, node string, isMaster bool) error {
+	if isMaster || u.DrainWorkers {
+		logger.Info("[%s] first: to drain node %s", ip, node)
+		if err := nodeclient2.EvictNodePods(node, u.Client, u.DrainTimeout); err != nil {
+			// the node is cordoned, uncordon it as it is not upgraded.
+			_ = nodeclient2.CordonUnCordon(u.Client, node, false)
+			return fmt.Errorf("drain node err: %w", err)
+		}
+	} else {
+		logger.Info("[%s] first: skip to drain worker node %s", ip, node)
+	}
 
-			// third to restart kubelet
-			logger.Info("[%s] third: to restart kubelet on %s", ip, node)
-			err = v1.SSHConfig.CmdAsync(ip, "systemctl daemon-reload && systemctl restart kubelet")
-			if err != nil {
-				logger.Error("systemctl daemon-reload && systemctl restart kubelet err: ", err)
-			}
+	// second to exec kubeadm upgrade node
+	logger.Info("[%s] second: to exec kubeadm upgrade node on %s", ip, node)
+	if err := v1.SSHConfig.CmdAsync(ip, u.kubeadmUpgradeCmd(ip == u.Masters[0])); err != nil {
+		return fmt.Errorf("kubeadm upgrade err: %w", err)
+	}
+	if ip == u.Masters[0] && u.reinstallKubeProxy() {
+		logger.Info("[%s] kubeadm upgrade apply %s installed kube-proxy, cleanup its rules on all the hosts", ip, u.NewVersion)
+		if err := u.cleanupKubeProxy(); err != nil {
+			return err
+		}
+	}
+
+	// third to restart kubelet
+	logger.Info("[%s] third: to restart kubelet on %s", ip, node)
+	since, err := remoteTime(ip)
+	if err != nil {
+		return err
+	}
+	if err := v1.SSHConfig.CmdAsync(ip, upgradeKubeletCmd); err != nil {
+		return fmt.Errorf("%s err: %w", upgradeKubeletCmd, err)
+	}
+
+	// fourth to judge nodes is ready
+	if err := waitNodeReady(u.Client, node, since, u.ReadyTimeout); err != nil {
+		return fmt.Errorf(`node is not ready in %s, please check the nodes logs to find out reason, and run "kubectl uncordon %s" after it is ready`, u.ReadyTimeout, node)
+	}
+	logger.Info("[%s] fourth: %s nodes is ready", ip, node)
 
-			// fourth to judge nodes is ready
-			time.Sleep(time.Second * 10)
-			k8sNode, _ := nodeclient2.GetNodeByName(u.Client, node)
-			if nodeclient2.IsNodeReady(*k8sNode) {
-				logger.Info("[%s] fourth:  %s nodes is ready", ip, node)
-
-				// fifth to uncordon node
-				err = nodeclient2.CordonUnCordon(u.Client, node, false)
-				if err != nil {
-					logger.Error(`k8s.CordonUnCordon err: %s, \n After upgrade,  please run "kubectl uncordon %s" to enable Scheduling`, err, node)
-				}
-				logger.Info("[%s] fifth: to uncordon node, 10 seconds to wait for %s uncordon", ip, node)
-			} else {
-				logger.Error("fourth:  %s nodes is not ready, please check the nodes logs to find out reason", node)
+	// fifth to uncordon node
+	logger.Info("[%s] fifth: to uncordon node %s", ip, node)
+	if err := nodeclient2.CordonUnCordon(u.Client, node, false); err != nil {
+		return fmt.Errorf(`uncordon err: %w, please run "kubectl uncordon %s" to enable Scheduling`, err, node)
+	}
+	return nil
+}
+
+const upgradeKubeletCmd = "systemctl daemon-reload && systemctl restart kubelet"
+
+const (
+	// kubeadm upgrade apply skip the kube-proxy addon if the kube-proxy ConfigMap is missing since 1.19.
+	kubeProxyAddonSkippedSince = 119
+	// deleteKubeProxyCmd remove the kube-proxy addon, cilium replaces kube-proxy.
+	deleteKubeProxyCmd = "kubectl -n kube-system delete ds,cm kube-proxy --ignore-not-found"
+	// waitKubeProxyDeletedCmd wait for the kube-proxy pods deleted, or they sync the rules again after the cleanup.
+	waitKubeProxyDeletedCmd = "kubectl -n kube-system wait --for=delete pod -l k8s-app=kube-proxy --timeout=5m"
+	// cleanupKubeProxyCmd remove the iptables rules and the ipvs dummy interface of kube-proxy as cilium documents,
+	// the ipvs virtual servers of the service ips are not reached without the dummy interface.
+	cleanupKubeProxyCmd = "ip link del kube-ipvs0 2>/dev/null; iptables-save | grep -v KUBE | iptables-restore"
+)
+
+// kubeadmUpgradeCmd is kubeadm upgrade apply on master0, and kubeadm upgrade node on the others.
+func (u *SealosUpgrade) kubeadmUpgradeCmd(isMaster0 bool) string {
+	if isMaster0 {
+		cmd := fmt.Sprintf("kubeadm upgrade apply --certificate-renewal=false  --yes %s", u.NewVersion)
+		// cilium replaces kube-proxy, the missing kube-proxy ConfigMap keep kubeadm from installing it again.
+		if networkName() == cni.CILIUM {
+			cmd = deleteKubeProxyCmd + " && " + cmd
+			if u.reinstallKubeProxy() {
+				cmd += " && " + deleteKubeProxyCmd + " && " + waitKubeProxyDeletedCmd
 			}
-		}(hostname)
+		}
+		return cmd
 	}
-	wg.Wait()
+	return "kubeadm upgrade node --certificate-renewal=false"
+}
+
+// reinstallKubeProxy return true if kubeadm upgrade apply install the kube-proxy addon of a cilium cluster.
+func (u *SealosUpgrade) reinstallKubeProxy() bool {
+	return networkName() == cni.CILIUM && utils.VersionToInt(u.NewVersion) < kubeProxyAddonSkippedSince
+}
+
+// cleanupKubeProxy remove the rules of the kube-proxy installed by kubeadm upgrade apply on all the hosts.
+func (u *SealosUpgrade) cleanupKubeProxy() error {
+	for _, host := range append(append([]string{}, u.Masters...), u.Nodes...) {
+		if err := v1.SSHConfig.CmdAsync(host, cleanupKubeProxyCmd); err != nil {
+			return fmt.Errorf("[%s] cleanup the kube-proxy rules err: %w", host, err)
+		}
+	}
+	return nil
 }
 
 func (u *SealosUpgrade) SetIPtoHostName() {
@@ -0,0 +1,230 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fanux/sealos/pkg/logger"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// UpgradeBackupDir is the dir on every node to keep the binaries, manifests and kubelet config before upgrade,
+	// in the sub dir of the version upgraded from.
+	UpgradeBackupDir = "/var/lib/sealos/upgrade-backup"
+)
+
+var (
+	// UpgradeBackupRecordDir keep the record of the upgrade backup of every version upgraded from, used by sealos upgrade rollback.
+	UpgradeBackupRecordDir = v1.DefaultConfigPath + "/upgrade-backups"
+
+	// upgradeBackupBinaries is the binaries replaced by the upgrade package.
+	upgradeBackupBinaries = []string{"/usr/bin/kubeadm", "/usr/bin/kubelet", "/usr/bin/kubectl"}
+	// upgradeBackupKubeletFiles is the kubelet config updated by kubeadm upgrade node.
+	upgradeBackupKubeletFiles = []string{"/var/lib/kubelet/config.yaml", "/var/lib/kubelet/kubeadm-flags.env", "/etc/systemd/system/kubelet.service.d"}
+)
+
+// UpgradeBackup is the cluster state before upgrade.
+type UpgradeBackup struct {
+	Version string    `json:"version"`
+	PkgURL  string    `json:"pkgURL"`
+	Time    time.Time `json:"time"`
+	// Snapshot is the local etcd snapshot file, empty if etcd is not backed up.
+	Snapshot string   `json:"snapshot,omitempty"`
+	Masters  []string `json:"masters"`
+	Nodes    []string `json:"nodes"`
+}
+
+// LoadUpgradeBackups load the records of the upgrade backups, the last backup is the last one.
+func LoadUpgradeBackups() ([]*UpgradeBackup, error) {
+	files, err := filepath.Glob(filepath.Join(UpgradeBackupRecordDir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	var backups []*UpgradeBackup
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("read upgrade backup %s err: %w", f, err)
+		}
+		b := &UpgradeBackup{}
+		if err = yaml.Unmarshal(data, b); err != nil {
+			return nil, fmt.Errorf("unmarshal upgrade backup %s err: %w", f, err)
+		}
+		backups = append(backups, b)
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Time.Before(backups[j].Time) })
+	return backups, nil
+}
+
+// LoadUpgradeBackup load the record of the upgrade backup from the version, the last backup if version is empty.
+func LoadUpgradeBackup(version string) (*UpgradeBackup, error) {
+	backups, err := LoadUpgradeBackups()
+	if err != nil {
+		return nil, err
+	}
+	if len(backups) == 0 {
+		return nil, fmt.Errorf("no upgrade backup is found in %s", UpgradeBackupRecordDir)
+	}
+	if version == "" {
+		return backups[len(backups)-1], nil
+	}
+	var versions []string
+	for _, b := range backups {
+		if b.Version == version {
+			return b, nil
+		}
+		versions = append(versions, b.Version)
+	}
+	return nil, fmt.Errorf("upgrade backup of %s is not found, the backups are %v", version, versions)
+}
+
+func (b *UpgradeBackup) recordFile() string {
+	return filepath.Join(UpgradeBackupRecordDir, b.Version+".yaml")
+}
+
+func (b *UpgradeBackup) save() error {
+	data, err := yaml.Marshal(b)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(UpgradeBackupRecordDir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(b.recordFile(), data, 0600)
+}
+
+// Backup save an etcd snapshot and back up the binaries, manifests and kubelet config of every node,
+// it must run before the new package is sent.
+func (u *SealosUpgrade) Backup() error {
+	b := &UpgradeBackup{
+		Version: v1.Version,
+		PkgURL:  v1.PkgURL,
+		Time:    time.Now(),
+		Masters: u.Masters,
+		Nodes:   u.Nodes,
+	}
+	e := &EtcdFlags{SealConfig: u.SealConfig}
+	if e.CertFileExist() {
+		e.BackDir = ETCDDEFAULTBACKUPDIR
+		e.Name = fmt.Sprintf("upgrade-%s-%d", v1.Version, b.Time.Unix())
+		e.LongName = filepath.Join(e.BackDir, e.Name)
+		e.Endpoints = []string{fmt.Sprintf("%s:2379", reFormatHostToIP(u.Masters[0]))}
+		// do not upload the upgrade snapshot to oss.
+		e.AccessKeyID = ""
+		if err := e.Save(false); err != nil {
+			return err
+		}
+		b.Snapshot = e.LongName
+	} else {
+		logger.Warn("ETCD CaCert or key file is not exist, skip etcd snapshot")
+	}
+
+	for _, host := range append(u.Masters, u.Nodes...) {
+		logger.Info("[%s] back up binaries, manifests and kubelet config to %s", host, upgradeBackupDir(b.Version))
+		if err := v1.SSHConfig.CmdAsync(host, upgradeBackupCmd(b.Version)); err != nil {
+			return fmt.Errorf("[%s] backup err: %w", host, err)
+		}
+	}
+	return b.save()
+}
+
+// Rollback restore the binaries, manifests and kubelet config host by host, workers first to respect the version skew,
+// and restore the etcd snapshot if restoreEtcd is true. The objects changed by kubeadm upgrade apply,
+// like the kubeadm-config ConfigMap and the addons, are only restored by the etcd snapshot.
+// The backups taken after b are removed, the cluster is back to the version of b.
+func (u *SealosUpgrade) Rollback(b *UpgradeBackup, restoreEtcd bool) error {
+	if restoreEtcd && b.Snapshot == "" {
+		return fmt.Errorf("etcd snapshot is not found in upgrade backup %s", b.recordFile())
+	}
+	hosts := append(append([]string{}, b.Nodes...), b.Masters[1:]...)
+	hosts = append(hosts, b.Masters[0])
+	for _, host := range hosts {
+		logger.Info("[%s] restore binaries, manifests and kubelet config from %s", host, upgradeBackupDir(b.Version))
+		if err := v1.SSHConfig.CmdAsync(host, upgradeRestoreCmd(b.Version)); err != nil {
+			return fmt.Errorf("[%s] rollback err: %w", host, err)
+		}
+	}
+	if restoreEtcd {
+		e := &EtcdFlags{
+			SealConfig: u.SealConfig,
+			Name:       filepath.Base(b.Snapshot),
+			BackDir:    filepath.Dir(b.Snapshot),
+			LongName:   b.Snapshot,
+			RestoreDir: ETCDDEFAULTRESTOREDIR,
+		}
+		for _, h := range b.Masters {
+			ip := reFormatHostToIP(h)
+			e.EtcdHosts = append(e.EtcdHosts, ip)
+			e.Endpoints = append(e.Endpoints, fmt.Sprintf("%s:2379", ip))
+		}
+		if err := e.RestoreCluster(); err != nil {
+			return err
+		}
+	}
+	backups, err := LoadUpgradeBackups()
+	if err != nil {
+		return err
+	}
+	for _, later := range backups {
+		if later.Time.After(b.Time) {
+			if err = os.Remove(later.recordFile()); err != nil {
+				return err
+			}
+		}
+	}
+	v1.Version = b.Version
+	v1.PkgURL = b.PkgURL
+	return nil
+}
+
+// upgradeBackupDir is the backup dir of the version upgraded from.
+func upgradeBackupDir(version string) string {
+	return filepath.Join(UpgradeBackupDir, version)
+}
+
+func upgradeBackupCmd(version string) string {
+	dir := upgradeBackupDir(version)
+	cmd := fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s/bin %[1]s/kubelet", dir)
+	for _, f := range upgradeBackupBinaries {
+		cmd += fmt.Sprintf(" && cp -a %s %s/bin/", f, dir)
+	}
+	for _, f := range upgradeBackupKubeletFiles {
+		cmd += fmt.Sprintf(" && (test ! -e %[1]s || cp -a %[1]s %[2]s/kubelet/)", f, dir)
+	}
+	// only masters have the static pod manifests.
+	cmd += fmt.Sprintf(" && (test ! -d /etc/kubernetes/manifests || cp -a /etc/kubernetes/manifests %s/)", dir)
+	return cmd
+}
+
+func upgradeRestoreCmd(version string) string {
+	dir := upgradeBackupDir(version)
+	cmd := fmt.Sprintf("test -d %[1]s/bin && cp -af %[1]s/bin/. /usr/bin/", dir)
+	for _, f := range upgradeBackupKubeletFiles {
+		backup := filepath.Join(dir, "kubelet", filepath.Base(f))
+		cmd += fmt.Sprintf(" && (test ! -e %[1]s || (rm -rf %[2]s && cp -a %[1]s %[2]s))", backup, f)
+	}
+	cmd += fmt.Sprintf(" && (test ! -d %[1]s/manifests || (rm -rf /etc/kubernetes/manifests && cp -a %[1]s/manifests /etc/kubernetes/manifests))", dir)
+	return cmd + " && " + upgradeKubeletCmd
+}
@@ -0,0 +1,80 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUpgradeBackupSaveLoad(t *testing.T) {
+	old := UpgradeBackupRecordDir
+	defer func() { UpgradeBackupRecordDir = old }()
+	UpgradeBackupRecordDir = t.TempDir()
+
+	if _, err := LoadUpgradeBackup(""); err == nil {
+		t.Errorf("LoadUpgradeBackup() should fail without backup")
+	}
+	b := &UpgradeBackup{
+		Version:  "v1.19.16",
+		PkgURL:   "/root/kube1.19.16.tar.gz",
+		Time:     time.Unix(1630000000, 0).UTC(),
+		Snapshot: "/opt/sealos/etcd-backup/upgrade-v1.19.16-1630000000",
+		Masters:  []string{"192.168.0.2", "192.168.0.3"},
+		Nodes:    []string{"192.168.0.4"},
+	}
+	// the backup of the second hop is kept with the first one.
+	b2 := &UpgradeBackup{
+		Version: "v1.20.15",
+		PkgURL:  "/root/kube1.20.15.tar.gz",
+		Time:    time.Unix(1630003600, 0).UTC(),
+		Masters: []string{"192.168.0.2", "192.168.0.3"},
+		Nodes:   []string{"192.168.0.4"},
+	}
+	for _, backup := range []*UpgradeBackup{b2, b} {
+		if err := backup.save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for version, want := range map[string]*UpgradeBackup{"": b2, "v1.20.15": b2, "v1.19.16": b} {
+		got, err := LoadUpgradeBackup(version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("LoadUpgradeBackup(%q) = %+v, want %+v", version, got, want)
+		}
+	}
+	if _, err := LoadUpgradeBackup("v1.18.20"); err == nil {
+		t.Errorf("LoadUpgradeBackup(v1.18.20) should fail")
+	}
+}
+
+func TestUpgradeBackupRestoreCmd(t *testing.T) {
+	dir := filepath.Join(UpgradeBackupDir, "v1.19.16")
+	backup := upgradeBackupCmd("v1.19.16")
+	for _, want := range []string{"rm -rf " + dir + " ", "cp -a /usr/bin/kubeadm " + dir + "/bin/", "/var/lib/kubelet/config.yaml", "/etc/kubernetes/manifests"} {
+		if !strings.Contains(backup, want) {
+			t.Errorf("backup cmd %q does not contain %q", backup, want)
+		}
+	}
+	restore := upgradeRestoreCmd("v1.19.16")
+	if !strings.HasPrefix(restore, "test -d "+dir+"/bin") || !strings.HasSuffix(restore, upgradeKubeletCmd) {
+		t.Errorf("restore cmd %q should check the backup first and restart kubelet last", restore)
+	}
+}
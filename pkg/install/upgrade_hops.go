@@ -0,0 +1,223 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fanux/sealos/pkg/logger"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	// UpgradeStateFile keep the progress of a multi-hop upgrade, rerun sealos upgrade to resume it.
+	UpgradeStateFile = v1.DefaultConfigPath + "/upgrade-state.yaml"
+
+	pkgVersionRegexp = regexp.MustCompile(`v?(\d+\.\d+\.\d+)`)
+)
+
+// UpgradeHop is a one minor version upgrade by the package.
+type UpgradeHop struct {
+	Version string `json:"version"`
+	PkgURL  string `json:"pkgURL"`
+}
+
+// UpgradeState is the progress of a multi-hop upgrade.
+type UpgradeState struct {
+	Target string       `json:"target"`
+	Hops   []UpgradeHop `json:"hops"`
+	// Current is the hop started but not finished, the backup is not taken again when resume it.
+	Current string `json:"current,omitempty"`
+}
+
+func loadUpgradeState() (*UpgradeState, error) {
+	state := &UpgradeState{}
+	data, err := ioutil.ReadFile(UpgradeStateFile)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, yaml.Unmarshal(data, state)
+}
+
+func (s *UpgradeState) save() error {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(UpgradeStateFile), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(UpgradeStateFile, data, 0600)
+}
+
+// UpgradePackages return the comma separated package urls followed by the tar.gz packages in pkgDir.
+func UpgradePackages(pkgURL, pkgDir string) ([]string, error) {
+	var pkgs []string
+	for _, p := range strings.Split(pkgURL, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			pkgs = append(pkgs, p)
+		}
+	}
+	if pkgDir != "" {
+		matches, err := filepath.Glob(filepath.Join(pkgDir, "*.tar.gz"))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no tar.gz package is found in %s", pkgDir)
+		}
+		pkgs = append(pkgs, matches...)
+	}
+	return pkgs, nil
+}
+
+// packageVersion return the k8s version of the package by the Metadata of the local package,
+// or by the file name like kube1.21.5.tar.gz of the remote package.
+func packageVersion(pkg string) (string, error) {
+	if _, ok := utils.IsURL(pkg); !ok {
+		metadata, err := readPackageMetadata(pkg)
+		if err == nil {
+			return "v" + strings.TrimPrefix(metadata.K8sVersion, "v"), nil
+		}
+		logger.Warn("read metadata of package %s err: %s, use the version in file name", pkg, err)
+	}
+	m := pkgVersionRegexp.FindStringSubmatch(filepath.Base(pkg))
+	if m == nil {
+		return "", fmt.Errorf("can not get the k8s version of package %s", pkg)
+	}
+	return "v" + m[1], nil
+}
+
+// ResolveUpgradeHops choose a package for every minor version from the current version to the target,
+// the newest patch version is used for the intermediate minor versions.
+func ResolveUpgradeHops(current, target string, pkgs []string) ([]UpgradeHop, error) {
+	if len(pkgs) == 1 {
+		return []UpgradeHop{{Version: target, PkgURL: pkgs[0]}}, nil
+	}
+	var available []UpgradeHop
+	for _, pkg := range pkgs {
+		version, err := packageVersion(pkg)
+		if err != nil {
+			return nil, err
+		}
+		available = append(available, UpgradeHop{Version: version, PkgURL: pkg})
+	}
+	return resolveUpgradeHops(current, target, available)
+}
+
+func resolveUpgradeHops(current, target string, available []UpgradeHop) ([]UpgradeHop, error) {
+	currentMinor, currentPatch := utils.GetMajorMinorInt(current)
+	targetMinor, targetPatch := utils.GetMajorMinorInt(target)
+	if targetMinor < currentMinor || (targetMinor == currentMinor && targetPatch <= currentPatch) {
+		return nil, fmt.Errorf("target version %s is lower/equal than current version %s", target, current)
+	}
+	byMinor := make(map[int][]UpgradeHop)
+	for _, hop := range available {
+		minor, _ := utils.GetMajorMinorInt(hop.Version)
+		byMinor[minor] = append(byMinor[minor], hop)
+	}
+	var hops []UpgradeHop
+	for minor := currentMinor + 1; minor <= targetMinor; minor++ {
+		candidates := byMinor[minor]
+		sort.Slice(candidates, func(i, j int) bool {
+			_, pi := utils.GetMajorMinorInt(candidates[i].Version)
+			_, pj := utils.GetMajorMinorInt(candidates[j].Version)
+			return pi > pj
+		})
+		if minor == targetMinor {
+			candidates = filterHops(candidates, target)
+		}
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("no package is found for v%d.%d, kubeadm only upgrade one minor version at a time", minor/100, minor%100)
+		}
+		hops = append(hops, candidates[0])
+	}
+	if targetMinor == currentMinor {
+		candidates := filterHops(byMinor[targetMinor], target)
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("no package is found for %s", target)
+		}
+		hops = append(hops, candidates[0])
+	}
+	return hops, nil
+}
+
+func filterHops(hops []UpgradeHop, version string) []UpgradeHop {
+	var filtered []UpgradeHop
+	for _, hop := range hops {
+		if strings.TrimPrefix(hop.Version, "v") == strings.TrimPrefix(version, "v") {
+			filtered = append(filtered, hop)
+		}
+	}
+	return filtered
+}
+
+// UpgradeHops upgrade the cluster hop by hop, the plan of every hop must pass unless ignorePlanErrors is true.
+// The config is dumped after every hop, rerun with the same target to resume from the unfinished hop.
+func (u *SealosUpgrade) UpgradeHops(cfgFile, target string, hops []UpgradeHop, ignorePlanErrors bool) error {
+	state, err := loadUpgradeState()
+	if err != nil {
+		return err
+	}
+	if state.Current != "" && (state.Target != target || len(hops) == 0 || hops[0].Version != state.Current) {
+		return fmt.Errorf("upgrade to %s is not finished at %s, rerun it to resume or remove %s", state.Target, state.Current, UpgradeStateFile)
+	}
+	state.Target, state.Hops = target, hops
+	for i, hop := range hops {
+		u.NewVersion, u.NewPkgURL = hop.Version, hop.PkgURL
+		logger.Info("upgrade hop %d/%d: %s -> %s", i+1, len(hops), v1.Version, hop.Version)
+		// health gate before every hop, the kubelets are upgraded by the previous hop.
+		if plan := u.Plan(); plan.Failed() {
+			_ = plan.Print(os.Stdout)
+			if !ignorePlanErrors {
+				return fmt.Errorf("upgrade plan to %s is failed, fix it and rerun to resume", hop.Version)
+			}
+			logger.Warn("upgrade plan to %s is failed, upgrade is forced", hop.Version)
+		}
+		if state.Current != hop.Version {
+			if err = u.Backup(); err != nil {
+				return fmt.Errorf("backup before upgrade to %s err: %w", hop.Version, err)
+			}
+			state.Current = hop.Version
+			if err = state.save(); err != nil {
+				return err
+			}
+		} else {
+			logger.Info("resume the unfinished upgrade to %s, the backup is kept", hop.Version)
+		}
+		if err = u.upgrade(); err != nil {
+			return fmt.Errorf("%w, fix it and rerun to resume", err)
+		}
+		u.Dump(cfgFile)
+		state.Current = ""
+		if err = state.save(); err != nil {
+			return err
+		}
+	}
+	return os.Remove(UpgradeStateFile)
+}
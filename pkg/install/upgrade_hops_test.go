@@ -0,0 +1,69 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveUpgradeHops(t *testing.T) {
+	available := []UpgradeHop{
+		{Version: "v1.21.5", PkgURL: "kube1.21.5.tar.gz"},
+		{Version: "v1.21.14", PkgURL: "kube1.21.14.tar.gz"},
+		{Version: "v1.22.9", PkgURL: "kube1.22.9.tar.gz"},
+		{Version: "v1.23.1", PkgURL: "kube1.23.1.tar.gz"},
+		{Version: "v1.23.8", PkgURL: "kube1.23.8.tar.gz"},
+	}
+	tests := []struct {
+		name, current, target string
+		want                  []string
+		wantErr               bool
+	}{
+		{"multi hops with newest patch", "v1.20.15", "v1.23.1", []string{"kube1.21.14.tar.gz", "kube1.22.9.tar.gz", "kube1.23.1.tar.gz"}, false},
+		{"patch upgrade", "v1.23.1", "v1.23.8", []string{"kube1.23.8.tar.gz"}, false},
+		{"missing minor", "v1.19.16", "v1.23.8", nil, true},
+		{"missing target", "v1.22.9", "v1.23.5", nil, true},
+		{"downgrade", "v1.23.8", "v1.22.9", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hops, err := resolveUpgradeHops(tt.current, tt.target, available)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveUpgradeHops() err = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, hop := range hops {
+				got = append(got, hop.PkgURL)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveUpgradeHops() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackageVersion(t *testing.T) {
+	got, err := packageVersion("https://sealyun.oss-cn-beijing.aliyuncs.com/kube1.22.9.tar.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "v1.22.9" {
+		t.Errorf("packageVersion() = %s, want v1.22.9", got)
+	}
+	if _, err = packageVersion("https://sealyun.oss-cn-beijing.aliyuncs.com/kube.tar.gz"); err == nil {
+		t.Errorf("packageVersion() of package without version should fail")
+	}
+}
@@ -0,0 +1,338 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+	"github.com/fanux/sealos/pkg/utils"
+
+	"github.com/fanux/sealos/pkg/kubernetes/nodeclient"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	UpgradeCheckPass = "pass"
+	UpgradeCheckFail = "fail"
+	UpgradeCheckSkip = "skip"
+
+	// packageMetadataFile is the Metadata file in the offline package.
+	packageMetadataFile = "kube/Metadata"
+)
+
+// UpgradeCheck is a preflight check of the upgrade plan.
+type UpgradeCheck struct {
+	Name    string
+	Status  string
+	Message string
+}
+
+// UpgradeStep is the commands run on a node by upgradeNodes, in order.
+type UpgradeStep struct {
+	IP       string
+	Node     string
+	Role     string
+	Commands []string
+}
+
+// UpgradePlan is the preflight checks and steps of the upgrade.
+type UpgradePlan struct {
+	Version    string
+	NewVersion string
+	// MaxUnavailable is the number of worker nodes upgraded at the same time.
+	MaxUnavailable int
+	Checks         []UpgradeCheck
+	Steps          []UpgradeStep
+}
+
+// deprecatedKubeadmField is a kubeadm ClusterConfiguration field not supported since the version.
+type deprecatedKubeadmField struct {
+	path    []string
+	value   string
+	since   string
+	message string
+}
+
+var deprecatedKubeadmFields = []deprecatedKubeadmField{
+	{path: []string{"useHyperKubeImage"}, value: "true", since: "v1.19.0", message: "hyperkube image is not released"},
+	{path: []string{"dns", "type"}, value: "kube-dns", since: "v1.21.0", message: "kube-dns is not supported, use CoreDNS"},
+	{path: []string{"apiServer", "extraArgs", "insecure-port"}, since: "v1.24.0", message: "apiserver flag insecure-port is removed"},
+	{path: []string{"controllerManager", "extraArgs", "experimental-cluster-signing-duration"}, since: "v1.25.0", message: "use cluster-signing-duration instead"},
+	{path: []string{"apiServer", "extraArgs", "feature-gates"}, value: "TTLAfterFinished", since: "v1.25.0", message: "feature gate TTLAfterFinished is removed"},
+	{path: []string{"controllerManager", "extraArgs", "feature-gates"}, value: "TTLAfterFinished", since: "v1.25.0", message: "feature gate TTLAfterFinished is removed"},
+}
+
+// Plan run the preflight checks and list the upgrade steps, the upgrade should not proceed if the plan is failed.
+func (u *SealosUpgrade) Plan() *UpgradePlan {
+	p := &UpgradePlan{Version: v1.Version, NewVersion: u.NewVersion, MaxUnavailable: u.MaxUnavailable}
+	p.check("version", utils.CanUpgradeByNewVersion(u.NewVersion, v1.Version))
+	p.check("cri "+criName(v1.Version), CheckCRI(u.NewVersion))
+	p.Checks = append(p.Checks, u.checkNodes()...)
+	p.Checks = append(p.Checks, u.checkEtcd())
+	p.Checks = append(p.Checks, u.checkMetadata())
+	p.Checks = append(p.Checks, u.checkKubeadmConfig())
+
+	for i, master := range u.Masters {
+		p.Steps = append(p.Steps, u.upgradeStep(master, i == 0, true))
+	}
+	for _, node := range u.Nodes {
+		p.Steps = append(p.Steps, u.upgradeStep(node, false, false))
+	}
+	return p
+}
+
+func (p *UpgradePlan) check(name string, err error) {
+	if err != nil {
+		p.Checks = append(p.Checks, UpgradeCheck{Name: name, Status: UpgradeCheckFail, Message: err.Error()})
+		return
+	}
+	p.Checks = append(p.Checks, UpgradeCheck{Name: name, Status: UpgradeCheckPass})
+}
+
+// Failed return true if any check is failed.
+func (p *UpgradePlan) Failed() bool {
+	for _, c := range p.Checks {
+		if c.Status == UpgradeCheckFail {
+			return true
+		}
+	}
+	return false
+}
+
+// checkNodes check every node is Ready and the kubelet version is within the skew policy of the new version.
+func (u *SealosUpgrade) checkNodes() []UpgradeCheck {
+	nodes, err := nodeclient.GetNodeList(u.Client)
+	if err != nil {
+		return []UpgradeCheck{{Name: "nodes", Status: UpgradeCheckFail, Message: err.Error()}}
+	}
+	var checks []UpgradeCheck
+	for _, host := range append(u.Masters, u.Nodes...) {
+		ns := nodeStatus(host, "", nodes.Items, nil)
+		c := UpgradeCheck{Name: "node " + ns.IP, Status: UpgradeCheckPass, Message: ns.KubeletVersion}
+		switch {
+		case ns.Error != "":
+			c.Status, c.Message = UpgradeCheckFail, ns.Error
+		case !ns.Ready:
+			c.Status, c.Message = UpgradeCheckFail, fmt.Sprintf("node %s is not Ready", ns.Name)
+		default:
+			if err = checkKubeletSkew(ns.KubeletVersion, u.NewVersion); err != nil {
+				c.Status, c.Message = UpgradeCheckFail, err.Error()
+			}
+		}
+		checks = append(checks, c)
+	}
+	return checks
+}
+
+// checkKubeletSkew check the kubelet is not newer than the new version and at most one minor version older,
+// kubeadm upgrade the control plane first and the kubelets are upgraded after.
+func checkKubeletSkew(kubeletVersion, newVersion string) error {
+	kubelet, _ := utils.GetMajorMinorInt(kubeletVersion)
+	target, _ := utils.GetMajorMinorInt(newVersion)
+	if kubelet == 0 || target == 0 {
+		return fmt.Errorf("invalid kubelet version %s or new version %s", kubeletVersion, newVersion)
+	}
+	if kubelet > target {
+		return fmt.Errorf("kubelet %s is newer than %s", kubeletVersion, newVersion)
+	}
+	if target-kubelet > 1 {
+		return fmt.Errorf("kubelet %s is more than one minor version older than %s", kubeletVersion, newVersion)
+	}
+	return nil
+}
+
+func (u *SealosUpgrade) checkEtcd() UpgradeCheck {
+	c := UpgradeCheck{Name: "etcd", Status: UpgradeCheckPass}
+	e := &EtcdFlags{SealConfig: u.SealConfig}
+	if !e.CertFileExist() {
+		c.Status, c.Message = UpgradeCheckSkip, "etcd cert is not exist"
+		return c
+	}
+	for _, h := range u.Masters {
+		e.Endpoints = append(e.Endpoints, fmt.Sprintf("%s:2379", reFormatHostToIP(h)))
+	}
+	var unhealthy []string
+	for _, h := range e.HealthCheck() {
+		if !h.Health {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s %s", h.Ep, h.Error))
+		}
+	}
+	if len(unhealthy) != 0 {
+		c.Status, c.Message = UpgradeCheckFail, "unhealthy: "+strings.Join(unhealthy, ", ")
+	}
+	return c
+}
+
+// checkMetadata check the k8s version in the Metadata of the new package, only the local package is checked.
+func (u *SealosUpgrade) checkMetadata() UpgradeCheck {
+	c := UpgradeCheck{Name: "package metadata", Status: UpgradeCheckPass}
+	if _, ok := utils.IsURL(u.NewPkgURL); ok {
+		c.Status, c.Message = UpgradeCheckSkip, "remote package is not checked"
+		return c
+	}
+	metadata, err := readPackageMetadata(u.NewPkgURL)
+	if err != nil {
+		c.Status, c.Message = UpgradeCheckFail, err.Error()
+		return c
+	}
+	if err = checkMetadataVersion(metadata, u.NewVersion); err != nil {
+		c.Status, c.Message = UpgradeCheckFail, err.Error()
+		return c
+	}
+	c.Message = fmt.Sprintf("k8s %s, %s %s", metadata.K8sVersion, metadata.CniName, metadata.CniVersion)
+	return c
+}
+
+func checkMetadataVersion(metadata *v1.Metadata, version string) error {
+	if strings.TrimPrefix(metadata.K8sVersion, "v") != strings.TrimPrefix(version, "v") {
+		return fmt.Errorf("package k8s version %s does not match %s", metadata.K8sVersion, version)
+	}
+	return nil
+}
+
+// readPackageMetadata read the Metadata file in the offline tar.gz package.
+func readPackageMetadata(pkg string) (*v1.Metadata, error) {
+	f, err := os.Open(pkg)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("read package %s err: %w", pkg, err)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("metadata file %s is not in package %s", packageMetadataFile, pkg)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read package %s err: %w", pkg, err)
+		}
+		if strings.TrimPrefix(h.Name, "./") != packageMetadataFile {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		metadata := &v1.Metadata{}
+		if err = json.Unmarshal(data, metadata); err != nil {
+			return nil, fmt.Errorf("decode metadata of package %s err: %w", pkg, err)
+		}
+		return metadata, nil
+	}
+}
+
+func (u *SealosUpgrade) checkKubeadmConfig() UpgradeCheck {
+	c := UpgradeCheck{Name: "kubeadm config", Status: UpgradeCheckPass}
+	cm, err := u.Client.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(context.TODO(), KubeadmConfigConfigMap, metav1.GetOptions{})
+	if err != nil {
+		c.Status, c.Message = UpgradeCheckFail, err.Error()
+		return c
+	}
+	deprecated, err := deprecatedKubeadmConfig(cm.Data[ClusterConfigurationKey], u.NewVersion)
+	if err != nil {
+		c.Status, c.Message = UpgradeCheckFail, err.Error()
+		return c
+	}
+	if len(deprecated) != 0 {
+		c.Status, c.Message = UpgradeCheckFail, strings.Join(deprecated, "; ")
+	}
+	return c
+}
+
+// deprecatedKubeadmConfig return the fields of the ClusterConfiguration not supported by the new version.
+func deprecatedKubeadmConfig(data, version string) ([]string, error) {
+	clusterConfiguration := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(data), &clusterConfiguration); err != nil {
+		return nil, fmt.Errorf("unmarshal %s err: %w", ClusterConfigurationKey, err)
+	}
+	var deprecated []string
+	for _, f := range deprecatedKubeadmFields {
+		if !utils.VersionCompare(version, f.since) {
+			continue
+		}
+		var value interface{} = clusterConfiguration
+		for _, key := range f.path {
+			m, ok := value.(map[string]interface{})
+			if !ok {
+				value = nil
+				break
+			}
+			value = m[key]
+		}
+		if value == nil || !strings.Contains(fmt.Sprint(value), f.value) {
+			continue
+		}
+		deprecated = append(deprecated, fmt.Sprintf("%s: %s since %s", strings.Join(f.path, "."), f.message, f.since))
+	}
+	return deprecated, nil
+}
+
+func (u *SealosUpgrade) upgradeStep(ip string, isMaster0, isMaster bool) UpgradeStep {
+	s := UpgradeStep{IP: ip, Node: u.IPtoHostName[ip], Role: "node"}
+	if isMaster {
+		s.Role = "master"
+	}
+	if isMaster || u.DrainWorkers {
+		s.Commands = append(s.Commands, fmt.Sprintf("drain %s by eviction API, timeout %s", s.Node, u.DrainTimeout))
+	}
+	s.Commands = append(s.Commands, u.kubeadmUpgradeCmd(isMaster0))
+	if isMaster0 && u.reinstallKubeProxy() {
+		s.Commands = append(s.Commands, cleanupKubeProxyCmd+" on all the hosts")
+	}
+	s.Commands = append(s.Commands, upgradeKubeletCmd,
+		fmt.Sprintf("wait for %s Ready, timeout %s", s.Node, u.ReadyTimeout), "uncordon "+s.Node)
+	return s
+}
+
+// Print write the checks and steps of the plan as table.
+func (p *UpgradePlan) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "UPGRADE:\t%s -> %s\n", p.Version, p.NewVersion)
+	fmt.Fprintf(tw, "MAX UNAVAILABLE:\t%d worker nodes, masters one at a time\n", p.MaxUnavailable)
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tMESSAGE")
+	for _, c := range p.Checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, c.Status, c.Message)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "ORDER\tIP\tNODE\tROLE\tCOMMANDS")
+	for i, s := range p.Steps {
+		for j, cmd := range s.Commands {
+			if j == 0 {
+				fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", i+1, s.IP, s.Node, s.Role, cmd)
+				continue
+			}
+			fmt.Fprintf(tw, "\t\t\t\t%s\n", cmd)
+		}
+	}
+	return tw.Flush()
+}
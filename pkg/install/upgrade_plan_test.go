@@ -0,0 +1,122 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fanux/sealos/pkg/cni"
+	v1 "github.com/fanux/sealos/pkg/types/v1alpha1"
+)
+
+func TestCheckKubeletSkew(t *testing.T) {
+	tests := []struct {
+		kubelet, target string
+		wantErr         bool
+	}{
+		{"v1.19.16", "v1.20.15", false},
+		{"v1.20.15", "v1.20.15", false},
+		{"v1.18.20", "v1.20.15", true},
+		{"v1.21.0", "v1.20.15", true},
+		{"", "v1.20.15", true},
+	}
+	for _, tt := range tests {
+		if err := checkKubeletSkew(tt.kubelet, tt.target); (err != nil) != tt.wantErr {
+			t.Errorf("checkKubeletSkew(%s, %s) err = %v, wantErr %v", tt.kubelet, tt.target, err, tt.wantErr)
+		}
+	}
+}
+
+func TestDeprecatedKubeadmConfig(t *testing.T) {
+	data := `apiServer:
+  extraArgs:
+    feature-gates: TTLAfterFinished=true
+controllerManager:
+  extraArgs:
+    experimental-cluster-signing-duration: 876000h
+dns:
+  type: CoreDNS
+kind: ClusterConfiguration
+`
+	got, err := deprecatedKubeadmConfig(data, "v1.20.15")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("v1.20.15 deprecated = %v, want none", got)
+	}
+	if got, _ = deprecatedKubeadmConfig(data, "v1.25.0"); len(got) != 2 {
+		t.Errorf("v1.25.0 deprecated = %v, want 2", got)
+	}
+}
+
+func TestReadPackageMetadata(t *testing.T) {
+	pkg := filepath.Join(t.TempDir(), "kube1.20.15.tar.gz")
+	f, err := os.Create(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	data := []byte(`{"k8sVersion":"v1.20.15","cniVersion":"v3.19.1","cniName":"calico"}`)
+	if err = tw.WriteHeader(&tar.Header{Name: "kube/Metadata", Mode: 0644, Size: int64(len(data))}); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = tw.Write(data)
+	tw.Close()
+	gw.Close()
+	f.Close()
+
+	metadata, err := readPackageMetadata(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = checkMetadataVersion(metadata, "v1.20.15"); err != nil {
+		t.Error(err)
+	}
+	if err = checkMetadataVersion(metadata, "v1.21.0"); err == nil {
+		t.Errorf("metadata %s should not match v1.21.0", metadata.K8sVersion)
+	}
+}
+
+func TestKubeadmUpgradeCmdCilium(t *testing.T) {
+	defer func(network string) { v1.Network = network }(v1.Network)
+	v1.Network = cni.CILIUM
+	tests := []struct {
+		version   string
+		reinstall bool
+	}{
+		{"v1.18.20", true},
+		{"v1.19.16", false},
+	}
+	for _, tt := range tests {
+		u := &SealosUpgrade{NewVersion: tt.version}
+		cmd := u.kubeadmUpgradeCmd(true)
+		if !strings.HasPrefix(cmd, deleteKubeProxyCmd+" && ") {
+			t.Errorf("kubeadmUpgradeCmd(%s) = %s, want the kube-proxy ConfigMap deleted first", tt.version, cmd)
+		}
+		if got := strings.HasSuffix(cmd, waitKubeProxyDeletedCmd); got != tt.reinstall {
+			t.Errorf("kubeadmUpgradeCmd(%s) delete the reinstalled kube-proxy = %v, want %v", tt.version, got, tt.reinstall)
+		}
+		if got := u.reinstallKubeProxy(); got != tt.reinstall {
+			t.Errorf("reinstallKubeProxy(%s) = %v, want %v", tt.version, got, tt.reinstall)
+		}
+	}
+}
@@ -15,12 +15,15 @@
 package ipvs
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/fanux/sealos/pkg/logger"
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -35,26 +38,126 @@ func (l *LvscareImage) toImageName() string {
 	return l.Image + ":" + l.Tag
 }
 
+// LvscareOptions is the health check, resources and image pull policy of the lvscare static pod, empty is the default.
+type LvscareOptions struct {
+	// HealthPath and HealthSchem of the apiserver health check, default /healthz and https
+	HealthPath  string `json:"healthpath,omitempty"`
+	HealthSchem string `json:"healthschem,omitempty"`
+	// Interval of the health check in seconds, lvscare checks every 5s if it is 0.
+	// lvscare has no timeout option of the health check, so there is no timeout here.
+	Interval int32 `json:"interval,omitempty"`
+	// PullPolicy is Always, IfNotPresent or Never, default IfNotPresent
+	PullPolicy string `json:"pullpolicy,omitempty"`
+	// Requests and Limits of cpu and memory, ex. cpu: 50m
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
+}
+
+// Validate check the options are able to render the static pod.
+func (o LvscareOptions) Validate() error {
+	if o.HealthSchem != "" && o.HealthSchem != "http" && o.HealthSchem != "https" {
+		return fmt.Errorf("lvscare health schem %s is invalid, must be http or https", o.HealthSchem)
+	}
+	if o.HealthPath != "" && !strings.HasPrefix(o.HealthPath, "/") {
+		return fmt.Errorf("lvscare health path %s must start with /", o.HealthPath)
+	}
+	if o.Interval < 0 {
+		return fmt.Errorf("lvscare interval %d must not be negative", o.Interval)
+	}
+	switch v1.PullPolicy(o.PullPolicy) {
+	case "", v1.PullAlways, v1.PullIfNotPresent, v1.PullNever:
+	default:
+		return fmt.Errorf("lvscare image pull policy %s is invalid, must be Always, IfNotPresent or Never", o.PullPolicy)
+	}
+	_, err := o.resources()
+	return err
+}
+
+func (o LvscareOptions) resources() (v1.ResourceRequirements, error) {
+	var r v1.ResourceRequirements
+	var err error
+	if r.Requests, err = resourceList(o.Requests); err != nil {
+		return r, fmt.Errorf("lvscare requests %w", err)
+	}
+	if r.Limits, err = resourceList(o.Limits); err != nil {
+		return r, fmt.Errorf("lvscare limits %w", err)
+	}
+	return r, nil
+}
+
+func resourceList(m map[string]string) (v1.ResourceList, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	list := v1.ResourceList{}
+	for name, value := range m {
+		if name != string(v1.ResourceCPU) && name != string(v1.ResourceMemory) {
+			return nil, fmt.Errorf("resource %s is not supported, must be cpu or memory", name)
+		}
+		q, err := resource.ParseQuantity(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s is invalid: %w", name, value, err)
+		}
+		list[v1.ResourceName(name)] = q
+	}
+	return list, nil
+}
+
+// args return the lvscare args, the vip and masters are ip:port.
+func (o LvscareOptions) args(vip string, masters []string) []string {
+	path, schem := o.HealthPath, o.HealthSchem
+	if path == "" {
+		path = "/healthz"
+	}
+	if schem == "" {
+		schem = "https"
+	}
+	args := []string{"care", "--vs", vip, "--health-path", path, "--health-schem", schem}
+	if o.Interval > 0 {
+		args = append(args, "--interval", strconv.Itoa(int(o.Interval)))
+	}
+	for _, m := range masters {
+		args = append(args, "--rs", m)
+	}
+	return args
+}
+
+// RunOnceCmd return the sealos ipvs command creating the ipvs rules once, it checks the masters as the static pod.
+func (o LvscareOptions) RunOnceCmd(vip string, masters []string) string {
+	// drop the care subcommand of lvscare.
+	return "sealos ipvs " + strings.Join(o.args(vip, masters)[1:], " ") + " --run-once"
+}
+
 // return lvscare static pod yaml
-func LvsStaticPodYaml(vip string, masters []string, image LvscareImage) string {
+func LvsStaticPodYaml(vip string, masters []string, port int, image LvscareImage, options LvscareOptions) string {
 	if vip == "" || len(masters) == 0 {
 		return ""
 	}
-	args := []string{"care", "--vs", vip + ":6443", "--health-path", "/healthz", "--health-schem", "https"}
+	p := strconv.Itoa(port)
+	var rs []string
 	for _, m := range masters {
 		if strings.Contains(m, ":") {
 			m = strings.Split(m, ":")[0]
 		}
-		args = append(args, "--rs")
-		args = append(args, m+":6443")
+		rs = append(rs, m+":"+p)
+	}
+	resources, err := options.resources()
+	if err != nil {
+		logger.Error("lvscare static pod resources invalid: %s", err)
+		return ""
+	}
+	pullPolicy := v1.PullIfNotPresent
+	if options.PullPolicy != "" {
+		pullPolicy = v1.PullPolicy(options.PullPolicy)
 	}
 	flag := true
 	pod := componentPod(v1.Container{
 		Name:            "kube-sealyun-lvscare",
 		Image:           image.toImageName(),
 		Command:         []string{"/usr/bin/lvscare"},
-		Args:            args,
-		ImagePullPolicy: v1.PullIfNotPresent,
+		Args:            options.args(vip+":"+p, rs),
+		Resources:       resources,
+		ImagePullPolicy: pullPolicy,
 		SecurityContext: &v1.SecurityContext{Privileged: &flag},
 	})
 	yaml, err := podToYaml(pod)
@@ -15,6 +15,7 @@
 package ipvs
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -71,6 +72,7 @@ func TestLvsStaticPodYaml(t *testing.T) {
 	type args struct {
 		vip     string
 		masters []string
+		port    int
 		image   LvscareImage
 	}
 	tests := []struct {
@@ -83,6 +85,7 @@ func TestLvsStaticPodYaml(t *testing.T) {
 			args{
 				"10.10.10.10",
 				[]string{"116.31.96.134:6443", "116.31.96.135:6443", "116.31.96.136:6443"},
+				6443,
 				LvscareImage{"fanux/lvscare", "latest"},
 			},
 			want[0],
@@ -90,9 +93,40 @@ func TestLvsStaticPodYaml(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := LvsStaticPodYaml(tt.args.vip, tt.args.masters, tt.args.image); got != tt.want {
+			if got := LvsStaticPodYaml(tt.args.vip, tt.args.masters, tt.args.port, tt.args.image, LvscareOptions{}); got != tt.want {
 				t.Errorf("LvsStaticPodYaml() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestLvscareOptions(t *testing.T) {
+	o := LvscareOptions{HealthPath: "/livez", Interval: 3, PullPolicy: "Always", Limits: map[string]string{"cpu": "200m", "memory": "128Mi"}}
+	if err := o.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"care", "--vs", "10.103.97.2:6443", "--health-path", "/livez", "--health-schem", "https", "--interval", "3", "--rs", "192.168.0.2:6443"}
+	if got := o.args("10.103.97.2:6443", []string{"192.168.0.2:6443"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("args() = %v, want %v", got, want)
+	}
+	wantCmd := "sealos ipvs --vs 10.103.97.2:6443 --health-path /livez --health-schem https --interval 3 --rs 192.168.0.2:6443 --run-once"
+	if got := o.RunOnceCmd("10.103.97.2:6443", []string{"192.168.0.2:6443"}); got != wantCmd {
+		t.Errorf("RunOnceCmd() = %s, want %s", got, wantCmd)
+	}
+	r, _ := o.resources()
+	if r.Limits.Cpu().String() != "200m" || r.Requests != nil {
+		t.Errorf("resources() = %v", r)
+	}
+	for _, o := range []LvscareOptions{
+		{HealthSchem: "tcp"},
+		{HealthPath: "healthz"},
+		{Interval: -1},
+		{PullPolicy: "always"},
+		{Requests: map[string]string{"cpu": "a lot"}},
+		{Limits: map[string]string{"gpu": "1"}},
+	} {
+		if err := o.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should fail", o)
+		}
+	}
+}
@@ -41,6 +41,8 @@ type Waiter interface {
 	WaitForPodsWithLabel(kvLabel string) error
 	// WaitForPodToDisappear waits for the given Pod in the kube-system namespace to be deleted
 	WaitForPodToDisappear(staticPodName string) error
+	// WaitForDaemonSetRollout waits for the given DaemonSet in the kube-system namespace to be updated and available on all nodes
+	WaitForDaemonSetRollout(name string) error
 	// WaitForStaticPodSingleHash fetches sha256 hash for the control plane static pod
 	WaitForStaticPodSingleHash(nodeName string, component string) (string, error)
 	// WaitForStaticPodHashChange waits for the given static pod component's static pod hash to get updated.
@@ -130,6 +132,27 @@ func (w *KubeWaiter) WaitForPodToDisappear(podName string) error {
 	})
 }
 
+// WaitForDaemonSetRollout blocks until the DaemonSet observes the latest spec, and the updated pods are scheduled and available on all nodes
+func (w *KubeWaiter) WaitForDaemonSetRollout(name string) error {
+	lastUpdated := int32(-1)
+	return wait.PollImmediate(APICallRetryInterval, w.timeout, func() (bool, error) {
+		ds, err := w.client.AppsV1().DaemonSets(metav1.NamespaceSystem).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			fmt.Fprintf(w.writer, "[apiclient] Error getting DaemonSet %q [%v]\n", name, err)
+			return false, nil
+		}
+		if ds.Status.ObservedGeneration < ds.Generation {
+			return false, nil
+		}
+		if lastUpdated != ds.Status.UpdatedNumberScheduled {
+			fmt.Fprintf(w.writer, "[apiclient] DaemonSet %s updated %d of %d pods\n", name, ds.Status.UpdatedNumberScheduled, ds.Status.DesiredNumberScheduled)
+			lastUpdated = ds.Status.UpdatedNumberScheduled
+		}
+		return ds.Status.UpdatedNumberScheduled == ds.Status.DesiredNumberScheduled &&
+			ds.Status.NumberAvailable == ds.Status.DesiredNumberScheduled, nil
+	})
+}
+
 // WaitForHealthyKubelet blocks until the kubelet /healthz endpoint returns 'ok'
 func (w *KubeWaiter) WaitForHealthyKubelet(initialTimeout time.Duration, healthzEndpoint string) error {
 	time.Sleep(initialTimeout)
@@ -47,6 +47,9 @@ const (
 	RSAPrivateKeyBlockType = "RSA PRIVATE KEY"
 	rsaKeySize             = 2048
 	duration365d           = time.Hour * 24 * 365
+
+	// DefaultValidity is the validity of the certs if not specified.
+	DefaultValidity = duration365d * 100
 )
 
 // Config contains the basic fields required for creating a certificate
@@ -57,11 +60,19 @@ type Config struct {
 	CAName       string // root ca map key
 	CommonName   string
 	Organization []string
-	Year         time.Duration
+	Validity     time.Duration
+	KeyAlgorithm x509.PublicKeyAlgorithm // RSA if not specified
 	AltNames     AltNames
 	Usages       []x509.ExtKeyUsage
 }
 
+func (cfg Config) validity() time.Duration {
+	if cfg.Validity <= 0 {
+		return DefaultValidity
+	}
+	return cfg.Validity
+}
+
 // AltNames contains the domain names and IP addresses that will be added
 // to the API Server's x509 certificate SubAltNames field. The values will
 // be passed directly to the x509.Certificate object.
@@ -70,7 +81,7 @@ type AltNames struct {
 	IPs      map[string]net.IP
 }
 
-// NewPrivateKey creates an RSA private key
+// NewPrivateKey creates an ECDSA P-256 private key if keyType is x509.ECDSA, otherwise an RSA private key
 func NewPrivateKey(keyType x509.PublicKeyAlgorithm) (crypto.Signer, error) {
 	if keyType == x509.ECDSA {
 		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
@@ -80,7 +91,7 @@ func NewPrivateKey(keyType x509.PublicKeyAlgorithm) (crypto.Signer, error) {
 }
 
 // NewSelfSignedCACert creates a CA certificate
-func NewSelfSignedCACert(key crypto.Signer, commonName string, organization []string, year time.Duration) (*x509.Certificate, error) {
+func NewSelfSignedCACert(key crypto.Signer, commonName string, organization []string, validity time.Duration) (*x509.Certificate, error) {
 	now := time.Now()
 	tmpl := x509.Certificate{
 		SerialNumber: new(big.Int).SetInt64(0),
@@ -89,7 +100,7 @@ func NewSelfSignedCACert(key crypto.Signer, commonName string, organization []st
 			Organization: organization,
 		},
 		NotBefore:             now.UTC(),
-		NotAfter:              now.Add(duration365d * year).UTC(),
+		NotAfter:              now.Add(validity).UTC(),
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
 		BasicConstraintsValid: true,
 		IsCA:                  true,
@@ -109,11 +120,11 @@ func NewCaCertAndKey(cfg Config) (*x509.Certificate, crypto.Signer, error) {
 		return LoadCaCertAndKeyFromDisk(cfg)
 	}
 
-	key, err := NewPrivateKey(x509.UnknownPublicKeyAlgorithm)
+	key, err := NewPrivateKey(cfg.KeyAlgorithm)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to create private key while generating CA certificate %s", err)
 	}
-	cert, err := NewSelfSignedCACert(key, cfg.CommonName, cfg.Organization, cfg.Year)
+	cert, err := NewSelfSignedCACert(key, cfg.CommonName, cfg.Organization, cfg.validity())
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to create ca cert %s", err)
 	}
@@ -157,9 +168,9 @@ func TryLoadKeyFromDisk(pkiPath string) (crypto.Signer, error) {
 	return key, nil
 }
 
-//  NewCaCertAndKeyFromRoot cmd/kubeadm/app/util/pkiutil/pki_helpers.go NewCertAndKey
+// NewCaCertAndKeyFromRoot cmd/kubeadm/app/util/pkiutil/pki_helpers.go NewCertAndKey
 func NewCaCertAndKeyFromRoot(cfg Config, caCert *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, crypto.Signer, error) {
-	key, err := NewPrivateKey(x509.UnknownPublicKeyAlgorithm)
+	key, err := NewPrivateKey(cfg.KeyAlgorithm)
 	if err != nil {
 		return nil, nil, fmt.Errorf("unable to create private key while generating CA certificate %s", err)
 	}
@@ -171,6 +182,45 @@ func NewCaCertAndKeyFromRoot(cfg Config, caCert *x509.Certificate, caKey crypto.
 	return cert, key, nil
 }
 
+// NewIntermediateCaCertAndKey creates a CA cert and key signed by the given root or intermediate CA.
+func NewIntermediateCaCertAndKey(cfg Config, parentCert *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer, error) {
+	key, err := NewPrivateKey(cfg.KeyAlgorithm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create private key while generating CA certificate %s", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	notAfter := now.Add(cfg.validity()).UTC()
+	if notAfter.After(parentCert.NotAfter) {
+		notAfter = parentCert.NotAfter
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   cfg.CommonName,
+			Organization: cfg.Organization,
+		},
+		NotBefore:             now.UTC(),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	certDERBytes, err := x509.CreateCertificate(rand.Reader, &tmpl, parentCert, key.Public(), parentKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create intermediate ca cert %s", err)
+	}
+	cert, err := x509.ParseCertificate(certDERBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
+
 // NewSignedCert creates a signed certificate using the given CA certificate and key
 func NewSignedCert(cfg Config, key crypto.Signer, caCert *x509.Certificate, caKey crypto.Signer) (*x509.Certificate, error) {
 	serial, err := rand.Int(rand.Reader, new(big.Int).SetInt64(math.MaxInt64))
@@ -193,6 +243,11 @@ func NewSignedCert(cfg Config, key crypto.Signer, caCert *x509.Certificate, caKe
 	for _, v := range cfg.AltNames.IPs {
 		ips = append(ips, v)
 	}
+	// the cert can not outlive the ca which signed it
+	notAfter := time.Now().Add(cfg.validity()).UTC()
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
 	certTmpl := x509.Certificate{
 		Subject: pkix.Name{
 			CommonName:   cfg.CommonName,
@@ -202,7 +257,7 @@ func NewSignedCert(cfg Config, key crypto.Signer, caCert *x509.Certificate, caKe
 		IPAddresses:  ips,
 		SerialNumber: serial,
 		NotBefore:    caCert.NotBefore,
-		NotAfter:     time.Now().Add(duration365d * cfg.Year).UTC(),
+		NotAfter:     notAfter,
 		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:  cfg.Usages,
 	}
@@ -223,6 +278,27 @@ func WriteCertAndKey(pkiPath string, name string, cert *x509.Certificate, key cr
 	return WriteCert(pkiPath, name, cert)
 }
 
+// WriteCertChainAndKey stores the certificate chain and key at the specified location, the first cert is the cert itself
+func WriteCertChainAndKey(pkiPath string, name string, certs []*x509.Certificate, key crypto.Signer) error {
+	if err := WriteKey(pkiPath, name, key); err != nil {
+		return err
+	}
+	if len(certs) == 0 {
+		return errors.New("certificate chain cannot be empty when writing to file")
+	}
+
+	var data []byte
+	for _, cert := range certs {
+		data = append(data, EncodeCertPEM(cert)...)
+	}
+	certificatePath := pathForCert(pkiPath, name)
+	if err := certutil.WriteCert(certificatePath, data); err != nil {
+		return fmt.Errorf("unable to write certificate to file %s %s", certificatePath, err)
+	}
+
+	return nil
+}
+
 // WriteCert stores the given certificate at the given location
 func WriteCert(pkiPath, name string, cert *x509.Certificate) error {
 	if cert == nil {
@@ -21,8 +21,12 @@ import (
 	"net"
 	"os"
 	"path"
+	"strings"
+	"time"
 
 	"github.com/fanux/sealos/pkg/logger"
+
+	certutil "k8s.io/client-go/util/cert"
 )
 
 var (
@@ -39,7 +43,6 @@ func CaList(CertPath, CertEtcdPath string) []Config {
 			BaseName:     "ca",
 			CommonName:   "kubernetes",
 			Organization: nil,
-			Year:         100,
 			AltNames:     AltNames{},
 			Usages:       nil,
 		},
@@ -49,7 +52,6 @@ func CaList(CertPath, CertEtcdPath string) []Config {
 			BaseName:     "front-proxy-ca",
 			CommonName:   "front-proxy-ca",
 			Organization: nil,
-			Year:         100,
 			AltNames:     AltNames{},
 			Usages:       nil,
 		},
@@ -59,7 +61,6 @@ func CaList(CertPath, CertEtcdPath string) []Config {
 			BaseName:     "ca",
 			CommonName:   "etcd-ca",
 			Organization: nil,
-			Year:         100,
 			AltNames:     AltNames{},
 			Usages:       nil,
 		},
@@ -75,7 +76,6 @@ func List(CertPath, CertEtcdPath string) []Config {
 			CAName:       "kubernetes",
 			CommonName:   "kube-apiserver",
 			Organization: nil,
-			Year:         100,
 			AltNames: AltNames{
 				DNSNames: map[string]string{
 					"localhost":              "localhost",
@@ -96,7 +96,6 @@ func List(CertPath, CertEtcdPath string) []Config {
 			CAName:       "kubernetes",
 			CommonName:   "kube-apiserver-kubelet-client",
 			Organization: []string{"system:masters"},
-			Year:         100,
 			AltNames:     AltNames{},
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		},
@@ -107,7 +106,6 @@ func List(CertPath, CertEtcdPath string) []Config {
 			CAName:       "front-proxy-ca",
 			CommonName:   "front-proxy-client",
 			Organization: nil,
-			Year:         100,
 			AltNames:     AltNames{},
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		},
@@ -118,7 +116,6 @@ func List(CertPath, CertEtcdPath string) []Config {
 			CAName:       "etcd-ca",
 			CommonName:   "kube-apiserver-etcd-client",
 			Organization: []string{"system:masters"},
-			Year:         100,
 			AltNames:     AltNames{},
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		},
@@ -129,7 +126,6 @@ func List(CertPath, CertEtcdPath string) []Config {
 			CAName:       "etcd-ca",
 			CommonName:   "etcd", // kubeadm using node name as common name cc.CommonName = mc.NodeRegistration.Name
 			Organization: nil,
-			Year:         100,
 			AltNames:     AltNames{}, // need set altNames
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		},
@@ -140,7 +136,6 @@ func List(CertPath, CertEtcdPath string) []Config {
 			CAName:       "etcd-ca",
 			CommonName:   "etcd-peer", // change this in filter
 			Organization: nil,
-			Year:         100,
 			AltNames:     AltNames{}, // change this in filter
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
 		},
@@ -151,13 +146,55 @@ func List(CertPath, CertEtcdPath string) []Config {
 			CAName:       "etcd-ca",
 			CommonName:   "kube-etcd-healthcheck-client",
 			Organization: []string{"system:masters"},
-			Year:         100,
 			AltNames:     AltNames{},
 			Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
 		},
 	}
 }
 
+// CertOptions is the validity and key algorithm of the generated certs, zero value means the default.
+type CertOptions struct {
+	CAValidity   time.Duration
+	CertValidity time.Duration
+	KeyAlgorithm x509.PublicKeyAlgorithm
+	// RootCA sign the CAs if not nil, otherwise the CAs are self-signed.
+	RootCA *RootCA
+}
+
+// ParseKeyAlgorithm parse the key algorithm name, rsa or ecdsa. empty means rsa.
+func ParseKeyAlgorithm(name string) (x509.PublicKeyAlgorithm, error) {
+	switch strings.ToLower(name) {
+	case "", "rsa":
+		return x509.RSA, nil
+	case "ecdsa":
+		return x509.ECDSA, nil
+	default:
+		return x509.UnknownPublicKeyAlgorithm, fmt.Errorf("unsupported key algorithm %s, only support rsa|ecdsa", name)
+	}
+}
+
+// Validate check the validity of leaf certs is not longer than CAs.
+func (o CertOptions) Validate() error {
+	if o.CAValidity < 0 || o.CertValidity < 0 {
+		return fmt.Errorf("cert validity can not be negative")
+	}
+	caValidity := o.CAValidity
+	if caValidity == 0 {
+		caValidity = DefaultValidity
+	}
+	if o.CertValidity > caValidity {
+		return fmt.Errorf("cert validity %s is longer than ca validity %s", o.CertValidity, caValidity)
+	}
+	return nil
+}
+
+func (o CertOptions) apply(certList []Config, validity time.Duration) {
+	for i := range certList {
+		certList[i].Validity = validity
+		certList[i].KeyAlgorithm = o.KeyAlgorithm
+	}
+}
+
 // 证书中需要用到的一些信息,传入的参数得提前验证
 type SealosCertMetaData struct {
 	APIServer AltNames
@@ -167,6 +204,7 @@ type SealosCertMetaData struct {
 	//证书生成的位置
 	CertPath     string
 	CertEtcdPath string
+	CertOptions
 }
 
 const (
@@ -180,8 +218,11 @@ const (
 )
 
 // apiServerIPAndDomains = MasterIP + VIP + CertSANS 暂时只有apiserver, 记得把cluster.local后缀加到apiServerIPAndDOmas里先
-func NewSealosCertMetaData(certPATH, certEtcdPATH string, apiServerIPAndDomains []string, SvcCIDR, nodeName, nodeIP, DNSDomain string) (*SealosCertMetaData, error) {
-	data := &SealosCertMetaData{}
+func NewSealosCertMetaData(certPATH, certEtcdPATH string, apiServerIPAndDomains []string, SvcCIDR, nodeName, nodeIP, DNSDomain string, options CertOptions) (*SealosCertMetaData, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	data := &SealosCertMetaData{CertOptions: options}
 	data.CertPath = certPATH
 	data.CertEtcdPath = certEtcdPATH
 	data.DNSDomain = DNSDomain
@@ -270,26 +311,113 @@ func (meta *SealosCertMetaData) generatorServiceAccountKeyPaire() error {
 }
 
 func (meta *SealosCertMetaData) GenerateAll() error {
-	cas := CaList(meta.CertPath, meta.CertEtcdPath)
 	certs := List(meta.CertPath, meta.CertEtcdPath)
+	meta.apply(certs, meta.CertValidity)
 	meta.apiServerAltName(&certs)
 	meta.etcdAltAndCommonName(&certs)
 	_ = meta.generatorServiceAccountKeyPaire()
 
+	CACerts, CAKeys, err := meta.generateCAs()
+	if err != nil {
+		return err
+	}
+
+	for _, cert := range certs {
+		caCert, ok := CACerts[cert.CAName]
+		if !ok {
+			return fmt.Errorf("root ca cert not found %s", cert.CAName)
+		}
+		caKey, ok := CAKeys[cert.CAName]
+		if !ok {
+			return fmt.Errorf("root ca key not found %s", cert.CAName)
+		}
+
+		Cert, Key, err := NewCaCertAndKeyFromRoot(cert, caCert, caKey)
+		if err != nil {
+			return err
+		}
+		err = WriteCertAndKey(cert.Path, cert.BaseName, Cert, Key)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GenerateCAs generate the kubernetes, front-proxy and etcd CAs only, the CAs on disk are kept.
+func (meta *SealosCertMetaData) GenerateCAs() error {
+	_, _, err := meta.generateCAs()
+	return err
+}
+
+func (meta *SealosCertMetaData) generateCAs() (map[string]*x509.Certificate, map[string]crypto.Signer, error) {
+	cas := CaList(meta.CertPath, meta.CertEtcdPath)
+	meta.apply(cas, meta.CAValidity)
+
 	CACerts := map[string]*x509.Certificate{}
 	CAKeys := map[string]crypto.Signer{}
 	for _, ca := range cas {
-		caCert, caKey, err := NewCaCertAndKey(ca)
+		caChain, caKey, err := meta.newCA(ca)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
-		CACerts[ca.CommonName] = caCert
+		CACerts[ca.CommonName] = caChain[0]
 		CAKeys[ca.CommonName] = caKey
 
-		err = WriteCertAndKey(ca.Path, ca.BaseName, caCert, caKey)
+		err = WriteCertChainAndKey(ca.Path, ca.BaseName, caChain, caKey)
 		if err != nil {
-			return err
+			return nil, nil, err
+		}
+	}
+	return CACerts, CAKeys, nil
+}
+
+// newCA return the CA chain and key, the chain is kept in ca.crt.
+// The CA on disk is used if exists, otherwise it is signed by the root CA or self-signed.
+func (meta *SealosCertMetaData) newCA(cfg Config) ([]*x509.Certificate, crypto.Signer, error) {
+	if _, err := os.Stat(pathForKey(cfg.Path, cfg.BaseName)); err == nil {
+		caKey, err := TryLoadKeyFromDisk(pathForKey(cfg.Path, cfg.BaseName))
+		if err != nil {
+			return nil, nil, err
+		}
+		caChain, err := certutil.CertsFromFile(pathForCert(cfg.Path, cfg.BaseName))
+		if err != nil {
+			return nil, nil, err
 		}
+		return caChain, caKey, nil
+	}
+	if meta.RootCA == nil {
+		caCert, caKey, err := NewCaCertAndKey(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []*x509.Certificate{caCert}, caKey, nil
+	}
+	caCert, caKey, err := NewIntermediateCaCertAndKey(cfg, meta.RootCA.Certs[0], meta.RootCA.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append([]*x509.Certificate{caCert}, meta.RootCA.Certs...), caKey, nil
+}
+
+// RenewAll regenerate all leaf certs signed by the existing CAs in caPath and caEtcdPath,
+// the new certs and keys are written to meta.CertPath and meta.CertEtcdPath.
+func (meta *SealosCertMetaData) RenewAll(caPath, caEtcdPath string) error {
+	cas := CaList(caPath, caEtcdPath)
+	certs := List(meta.CertPath, meta.CertEtcdPath)
+	meta.apply(certs, meta.CertValidity)
+	meta.apiServerAltName(&certs)
+	meta.etcdAltAndCommonName(&certs)
+
+	CACerts := map[string]*x509.Certificate{}
+	CAKeys := map[string]crypto.Signer{}
+	for _, ca := range cas {
+		caCert, caKey, err := LoadCaCertAndKeyFromDisk(ca)
+		if err != nil {
+			return fmt.Errorf("load ca %s from %s failed %s", ca.BaseName, ca.Path, err)
+		}
+		CACerts[ca.CommonName] = caCert
+		CAKeys[ca.CommonName] = caKey
 	}
 
 	for _, cert := range certs {
@@ -297,17 +425,15 @@ func (meta *SealosCertMetaData) GenerateAll() error {
 		if !ok {
 			return fmt.Errorf("root ca cert not found %s", cert.CAName)
 		}
-		caKey, ok := CAKeys[cert.CAName]
-		if !ok {
-			return fmt.Errorf("root ca key not found %s", cert.CAName)
-		}
-
-		Cert, Key, err := NewCaCertAndKeyFromRoot(cert, caCert, caKey)
+		key, err := NewPrivateKey(cert.KeyAlgorithm)
 		if err != nil {
-			return err
+			return fmt.Errorf("unable to create private key for %s %s", cert.BaseName, err)
 		}
-		err = WriteCertAndKey(cert.Path, cert.BaseName, Cert, Key)
+		Cert, err := NewSignedCert(cert, key, caCert, CAKeys[cert.CAName])
 		if err != nil {
+			return fmt.Errorf("new signed cert %s failed %s", cert.BaseName, err)
+		}
+		if err = WriteCertAndKey(cert.Path, cert.BaseName, Cert, key); err != nil {
 			return err
 		}
 	}
@@ -15,7 +15,9 @@
 package cert
 
 import (
+	"crypto/x509"
 	"testing"
+	"time"
 )
 
 func TestGenerateAll(t *testing.T) {
@@ -30,7 +32,7 @@ func TestGenerateAll(t *testing.T) {
 			false,
 		},
 	}
-	certMeta, err := NewSealosCertMetaData(BasePath, EtcdBasePath, []string{"test.com", "192.168.1.2", "kubernetes.default.svc.sealyun"}, "10.64.0.0/10", "master1", "172.27.139.11", "cluster.local")
+	certMeta, err := NewSealosCertMetaData(BasePath, EtcdBasePath, []string{"test.com", "192.168.1.2", "kubernetes.default.svc.sealyun"}, "10.64.0.0/10", "master1", "172.27.139.11", "cluster.local", CertOptions{})
 	if err != nil {
 		t.Error(err)
 	}
@@ -43,3 +45,94 @@ func TestGenerateAll(t *testing.T) {
 		})
 	}
 }
+
+func TestRenewAll(t *testing.T) {
+	BasePath := "/tmp/kubernetes/pki"
+	EtcdBasePath := "/tmp/kubernetes/pki/etcd"
+	RenewPath := "/tmp/kubernetes/pki-renew"
+	RenewEtcdPath := "/tmp/kubernetes/pki-renew/etcd"
+	certMeta, err := NewSealosCertMetaData(BasePath, EtcdBasePath, []string{"test.com", "192.168.1.2"}, "10.64.0.0/10", "master1", "172.27.139.11", "cluster.local", CertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := certMeta.GenerateAll(); err != nil {
+		t.Fatal(err)
+	}
+	renewMeta, err := NewSealosCertMetaData(RenewPath, RenewEtcdPath, []string{"test.com", "192.168.1.2"}, "10.64.0.0/10", "master1", "172.27.139.11", "cluster.local", CertOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := renewMeta.RenewAll(BasePath, EtcdBasePath); err != nil {
+		t.Errorf("RenewAll() error = %v", err)
+	}
+	caCert, _, err := LoadCaCertAndKeyFromDisk(Config{Path: BasePath, BaseName: "ca"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiserver, _, err := LoadCaCertAndKeyFromDisk(Config{Path: RenewPath, BaseName: "apiserver"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := apiserver.CheckSignatureFrom(caCert); err != nil {
+		t.Errorf("renewed apiserver cert is not signed by ca: %v", err)
+	}
+}
+
+func TestGenerateAllWithOptions(t *testing.T) {
+	BasePath := "/tmp/kubernetes/pki-ecdsa"
+	EtcdBasePath := "/tmp/kubernetes/pki-ecdsa/etcd"
+	options := CertOptions{
+		CAValidity:   10 * duration365d,
+		CertValidity: duration365d,
+		KeyAlgorithm: x509.ECDSA,
+	}
+	certMeta, err := NewSealosCertMetaData(BasePath, EtcdBasePath, []string{"test.com"}, "10.64.0.0/10", "master1", "172.27.139.11", "cluster.local", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := certMeta.GenerateAll(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path     string
+		name     string
+		validity time.Duration
+	}{
+		{BasePath, "ca", options.CAValidity},
+		{EtcdBasePath, "ca", options.CAValidity},
+		{BasePath, "apiserver", options.CertValidity},
+		{EtcdBasePath, "server", options.CertValidity},
+	}
+	for _, tt := range tests {
+		c, _, err := LoadCaCertAndKeyFromDisk(Config{Path: tt.path, BaseName: tt.name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.PublicKeyAlgorithm != x509.ECDSA {
+			t.Errorf("%s/%s key algorithm = %v, want ECDSA", tt.path, tt.name, c.PublicKeyAlgorithm)
+		}
+		if c.NotAfter.After(time.Now().Add(tt.validity)) {
+			t.Errorf("%s/%s expires at %s, longer than %s", tt.path, tt.name, c.NotAfter, tt.validity)
+		}
+	}
+}
+
+func TestCertOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options CertOptions
+		wantErr bool
+	}{
+		{"default", CertOptions{}, false},
+		{"leaf shorter than ca", CertOptions{CAValidity: 10 * duration365d, CertValidity: duration365d}, false},
+		{"leaf longer than ca", CertOptions{CAValidity: duration365d, CertValidity: 10 * duration365d}, true},
+		{"negative", CertOptions{CertValidity: -time.Hour}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/fanux/sealos/pkg/logger"
 
@@ -34,6 +35,8 @@ import (
 type clientCertAuth struct {
 	CAKey         crypto.Signer
 	Organizations []string
+	Validity      time.Duration
+	KeyAlgorithm  x509.PublicKeyAlgorithm
 }
 
 // tokenAuth struct holds info required to use a token to provide authentication info in a kubeconfig object
@@ -54,6 +57,7 @@ type kubeConfigSpec struct {
 // join --control-plane workflow, plus the admin kubeconfig file used by the administrator and kubeadm itself; the
 // kubelet.conf file must not be created because it will be created and signed by the kubelet TLS bootstrap process.
 // If any kubeconfig files already exists, it used only if evaluated equal; otherwise an error is returned.
+// cfg locates the CA, its Validity and KeyAlgorithm are used for the client certs.
 func CreateJoinControlPlaneKubeConfigFiles(outDir string, cfg Config, nodeName, controlPlaneEndpoint, clusterName string) error {
 	return createKubeConfigFiles(
 		outDir,
@@ -72,7 +76,7 @@ func CreateJoinControlPlaneKubeConfigFiles(outDir string, cfg Config, nodeName,
 // cmd/kubeadm/app/phases/kubeconfig/kubeconfig.go
 func CreateKubeConfigFile(kubeConfigFileName string, outDir string, cfg Config, nodeName, controlPlaneEndpoint, clusterName string) error {
 	logger.Info("creating kubeconfig file for %s", kubeConfigFileName)
-	return createKubeConfigFiles(outDir, cfg, kubeConfigFileName, nodeName, controlPlaneEndpoint, clusterName)
+	return createKubeConfigFiles(outDir, cfg, nodeName, controlPlaneEndpoint, clusterName, kubeConfigFileName)
 }
 
 // createKubeConfigFiles creates all the requested kubeconfig files.
@@ -157,6 +161,10 @@ func getKubeConfigSpecs(cfg Config, nodeName, controlPlaneEndpoint string) (map[
 			},
 		},
 	}
+	for _, spec := range kubeConfigSpec {
+		spec.ClientCertAuth.Validity = cfg.Validity
+		spec.ClientCertAuth.KeyAlgorithm = cfg.KeyAlgorithm
+	}
 
 	return kubeConfigSpec, nil
 }
@@ -180,7 +188,8 @@ func buildKubeConfigFromSpec(spec *kubeConfigSpec, clustername string) (*clientc
 		CommonName:   spec.ClientName,
 		Organization: spec.ClientCertAuth.Organizations,
 		Usages:       []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
-		Year:         100,
+		Validity:     spec.ClientCertAuth.Validity,
+		KeyAlgorithm: spec.ClientCertAuth.KeyAlgorithm,
 	}
 
 	clientCert, clientKey, err := NewCaCertAndKeyFromRoot(clientCertConfig, spec.CACert, spec.ClientCertAuth.CAKey)
@@ -0,0 +1,167 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	certutil "k8s.io/client-go/util/cert"
+)
+
+// RootCA is the root or intermediate CA used to sign the cluster CAs.
+type RootCA struct {
+	// Certs is the signing cert followed by its issuers.
+	Certs []*x509.Certificate
+	Key   crypto.Signer
+}
+
+// LoadRootCA load the root or intermediate CA and validate the chain, the cert file may contain the issuers of the cert.
+func LoadRootCA(certFile, keyFile string) (*RootCA, error) {
+	certs, err := certutil.CertsFromFile(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("load root ca cert %s failed %s", certFile, err)
+	}
+	key, err := TryLoadKeyFromDisk(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load root ca key %s failed %s", keyFile, err)
+	}
+	if err = ValidateCAChain(certs, key); err != nil {
+		return nil, fmt.Errorf("invalid root ca %s: %s", certFile, err)
+	}
+	return &RootCA{Certs: certs, Key: key}, nil
+}
+
+// ValidateCADir validate the pre-created kubernetes, front-proxy and etcd CAs in dir, the etcd CA is in dir/etcd.
+func ValidateCADir(dir string) error {
+	for _, ca := range CaList(dir, filepath.Join(dir, "etcd")) {
+		certFile := pathForCert(ca.Path, ca.BaseName)
+		certs, err := certutil.CertsFromFile(certFile)
+		if err != nil {
+			return fmt.Errorf("load ca cert %s failed %s", certFile, err)
+		}
+		key, err := TryLoadKeyFromDisk(pathForKey(ca.Path, ca.BaseName))
+		if err != nil {
+			return err
+		}
+		if err = ValidateCAChain(certs, key); err != nil {
+			return fmt.Errorf("invalid ca %s: %s", certFile, err)
+		}
+	}
+	return nil
+}
+
+// CopyCADir copy the pre-created CAs in dir to the sealos cert paths.
+func CopyCADir(dir, certPath, certEtcdPath string) error {
+	return CopyCAs(dir, filepath.Join(dir, "etcd"), certPath, certEtcdPath)
+}
+
+// CopyCAs copy the kubernetes, front-proxy and etcd CA files from the src cert paths to the dst cert paths.
+func CopyCAs(srcCertPath, srcCertEtcdPath, dstCertPath, dstCertEtcdPath string) error {
+	src := CaList(srcCertPath, srcCertEtcdPath)
+	dst := CaList(dstCertPath, dstCertEtcdPath)
+	for i := range src {
+		if err := os.MkdirAll(dst[i].Path, 0755); err != nil {
+			return err
+		}
+		for _, f := range []struct {
+			from, to string
+			perm     os.FileMode
+		}{
+			{pathForCert(src[i].Path, src[i].BaseName), pathForCert(dst[i].Path, dst[i].BaseName), 0644},
+			{pathForKey(src[i].Path, src[i].BaseName), pathForKey(dst[i].Path, dst[i].BaseName), 0600},
+		} {
+			data, err := ioutil.ReadFile(f.from)
+			if err != nil {
+				return err
+			}
+			if err = ioutil.WriteFile(f.to, data, f.perm); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateCAChain check the first cert is a valid CA of the key, and it is trusted by the rest certs of the chain.
+// If there is no self-signed cert in the chain, the last cert is used as the trust anchor.
+func ValidateCAChain(certs []*x509.Certificate, key crypto.Signer) error {
+	if len(certs) == 0 {
+		return errors.New("no cert found")
+	}
+	ca := certs[0]
+	if !ca.IsCA || ca.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("cert %s is not a CA", ca.Subject)
+	}
+	now := time.Now()
+	if now.Before(ca.NotBefore) || now.After(ca.NotAfter) {
+		return fmt.Errorf("cert %s is not valid from %s to %s", ca.Subject, ca.NotBefore, ca.NotAfter)
+	}
+	pub, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !pub.Equal(ca.PublicKey) {
+		return fmt.Errorf("key does not match cert %s", ca.Subject)
+	}
+
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	hasRoot := false
+	for _, c := range certs {
+		if isSelfSigned(c) {
+			roots.AddCert(c)
+			hasRoot = true
+			continue
+		}
+		intermediates.AddCert(c)
+	}
+	if !hasRoot {
+		roots.AddCert(certs[len(certs)-1])
+	}
+	_, err := ca.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+func isSelfSigned(c *x509.Certificate) bool {
+	return bytes.Equal(c.RawIssuer, c.RawSubject) && c.CheckSignatureFrom(c) == nil
+}
+
+// CheckExistingCAs check the CAs already in the cert paths are signed by the root CA,
+// they are used instead of the new CAs derived from the root CA.
+func (r *RootCA) CheckExistingCAs(certPath, certEtcdPath string) error {
+	for _, ca := range CaList(certPath, certEtcdPath) {
+		certFile := pathForCert(ca.Path, ca.BaseName)
+		if _, err := os.Stat(pathForKey(ca.Path, ca.BaseName)); err != nil {
+			continue
+		}
+		certs, err := certutil.CertsFromFile(certFile)
+		if err != nil {
+			return fmt.Errorf("load ca cert %s failed %s", certFile, err)
+		}
+		if err = certs[0].CheckSignatureFrom(r.Certs[0]); err != nil {
+			return fmt.Errorf("ca %s already exists and is not signed by the root ca, please remove it", certFile)
+		}
+	}
+	return nil
+}
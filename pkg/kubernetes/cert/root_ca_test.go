@@ -0,0 +1,96 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cert
+
+import (
+	"crypto"
+	"crypto/x509"
+	"os"
+	"testing"
+
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func newTestRootCA(t *testing.T, commonName string) *RootCA {
+	key, err := NewPrivateKey(x509.ECDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := NewSelfSignedCACert(key, commonName, nil, 20*duration365d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &RootCA{Certs: []*x509.Certificate{cert}, Key: key}
+}
+
+func TestGenerateAllWithRootCA(t *testing.T) {
+	BasePath := "/tmp/kubernetes/pki-root"
+	EtcdBasePath := "/tmp/kubernetes/pki-root/etcd"
+	_ = os.RemoveAll(BasePath)
+
+	rootCA := newTestRootCA(t, "corporate-root")
+	certMeta, err := NewSealosCertMetaData(BasePath, EtcdBasePath, []string{"test.com"}, "10.64.0.0/10", "master1", "172.27.139.11", "cluster.local", CertOptions{RootCA: rootCA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := certMeta.GenerateAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateCADir(BasePath); err != nil {
+		t.Errorf("ValidateCADir() error = %v", err)
+	}
+	certs, err := certutil.CertsFromFile(pathForCert(BasePath, "ca"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(certs) != 2 || !certs[1].Equal(rootCA.Certs[0]) {
+		t.Errorf("ca.crt should contain the cluster ca and the root ca, got %d certs", len(certs))
+	}
+	if err := rootCA.CheckExistingCAs(BasePath, EtcdBasePath); err != nil {
+		t.Errorf("CheckExistingCAs() error = %v", err)
+	}
+	if err := newTestRootCA(t, "other-root").CheckExistingCAs(BasePath, EtcdBasePath); err == nil {
+		t.Errorf("CheckExistingCAs() want error for the CAs signed by other root")
+	}
+}
+
+func TestValidateCAChain(t *testing.T) {
+	rootCA := newTestRootCA(t, "corporate-root")
+	otherCA := newTestRootCA(t, "other-root")
+	interCert, interKey, err := NewIntermediateCaCertAndKey(Config{CommonName: "intermediate"}, rootCA.Certs[0], rootCA.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		certs   []*x509.Certificate
+		key     interface{}
+		wantErr bool
+	}{
+		{"self-signed root", rootCA.Certs, rootCA.Key, false},
+		{"intermediate with chain", []*x509.Certificate{interCert, rootCA.Certs[0]}, interKey, false},
+		{"intermediate as trust anchor", []*x509.Certificate{interCert}, interKey, false},
+		{"intermediate with wrong chain", []*x509.Certificate{interCert, otherCA.Certs[0]}, interKey, true},
+		{"key mismatch", rootCA.Certs, otherCA.Key, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCAChain(tt.certs, tt.key.(crypto.Signer))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCAChain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -17,12 +17,17 @@ package nodeclient
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/fanux/sealos/pkg/logger"
 
 	v1 "k8s.io/api/core/v1"
 	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 )
 
@@ -31,23 +36,84 @@ const (
 	PolicyGroupVersion = "policy/v1beta1"
 )
 
-func EvictNodePods(nodeName string, k8sClient *kubernetes.Clientset) error {
+// EvictRetryInterval is the interval to retry the eviction blocked by PodDisruptionBudget.
+var EvictRetryInterval = 5 * time.Second
+
+// EvictNodePods cordon the node and evict the pods on it by the eviction API, the DaemonSet and mirror pods are skipped.
+// The evictions blocked by PodDisruptionBudget are retried until the timeout, then wait for the pods deleted.
+func EvictNodePods(nodeName string, k8sClient kubernetes.Interface, timeout time.Duration) error {
+	if err := CordonUnCordon(k8sClient, nodeName, true); err != nil {
+		return err
+	}
 	pods, err := k8sClient.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
 		FieldSelector: "spec.nodeName=" + nodeName,
 	})
 	if err != nil {
 		return err
 	}
-	for _, i := range pods.Items {
-		err := EvictPod(k8sClient, i, PolicyGroupVersion)
-		if err != nil {
-			return err
+	deadline := time.Now().Add(timeout)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, pod := range pods.Items {
+		if !IsPodEvictable(pod) {
+			continue
 		}
+		wg.Add(1)
+		go func(pod v1.Pod) {
+			defer wg.Done()
+			if err := evictPodAndWait(k8sClient, pod, deadline); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("evict pod %s/%s err: %w", pod.Namespace, pod.Name, err))
+				mu.Unlock()
+			}
+		}(pod)
 	}
-	return nil
+	wg.Wait()
+	return utilerrors.NewAggregate(errs)
+}
+
+// IsPodEvictable return false for the DaemonSet pods and the mirror pods of static pods, they can not be drained.
+func IsPodEvictable(pod v1.Pod) bool {
+	if _, ok := pod.Annotations[v1.MirrorPodAnnotationKey]; ok {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller != nil && *owner.Controller && owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+func evictPodAndWait(k8sClient kubernetes.Interface, pod v1.Pod, deadline time.Time) error {
+	err := wait.PollImmediate(EvictRetryInterval, time.Until(deadline), func() (bool, error) {
+		err := EvictPod(k8sClient, pod, PolicyGroupVersion)
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+			return true, nil
+		case apierrors.IsTooManyRequests(err):
+			logger.Warn("evict pod %s/%s is blocked by PodDisruptionBudget, retry after %s", pod.Namespace, pod.Name, EvictRetryInterval)
+			return false, nil
+		default:
+			return false, err
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return wait.PollImmediate(time.Second, time.Until(deadline), func() (bool, error) {
+		p, err := k8sClient.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && p.UID != pod.UID) {
+			return true, nil
+		}
+		return false, nil
+	})
 }
 
-func EvictPod(k8sClient *kubernetes.Clientset, pod v1.Pod, policyGroupVersion string) error {
+func EvictPod(k8sClient kubernetes.Interface, pod v1.Pod, policyGroupVersion string) error {
 	deleteOptions := &metav1.DeleteOptions{}
 	eviction := &policyv1beta1.Eviction{
 		TypeMeta: metav1.TypeMeta{
@@ -63,7 +129,7 @@ func EvictPod(k8sClient *kubernetes.Clientset, pod v1.Pod, policyGroupVersion st
 	return k8sClient.PolicyV1beta1().Evictions(eviction.Namespace).Evict(context.TODO(), eviction)
 }
 
-func CordonUnCordon(k8sClient *kubernetes.Clientset, nodeName string, cordoned bool) error {
+func CordonUnCordon(k8sClient kubernetes.Interface, nodeName string, cordoned bool) error {
 	node, err := GetNodeByName(k8sClient, nodeName)
 	if err != nil {
 		return err
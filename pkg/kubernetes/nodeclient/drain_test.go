@@ -0,0 +1,133 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package nodeclient
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1beta1 "k8s.io/api/policy/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testPod(name string, owner string, mirror bool) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+		Spec:       v1.PodSpec{NodeName: "node-1"},
+	}
+	if owner != "" {
+		controller := true
+		pod.OwnerReferences = []metav1.OwnerReference{{Kind: owner, Name: owner, Controller: &controller}}
+	}
+	if mirror {
+		pod.Annotations = map[string]string{v1.MirrorPodAnnotationKey: "mirror"}
+	}
+	return pod
+}
+
+func TestIsPodEvictable(t *testing.T) {
+	notController := false
+	dsNotController := testPod("ds-owned", "", false)
+	dsNotController.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds", Controller: &notController}}
+	tests := []struct {
+		pod  *v1.Pod
+		want bool
+	}{
+		{testPod("bare", "", false), true},
+		{testPod("rs", "ReplicaSet", false), true},
+		{testPod("ds", "DaemonSet", false), false},
+		{testPod("static", "", true), false},
+		{dsNotController, true},
+	}
+	for _, tt := range tests {
+		if got := IsPodEvictable(*tt.pod); got != tt.want {
+			t.Errorf("IsPodEvictable(%s) = %t, want %t", tt.pod.Name, got, tt.want)
+		}
+	}
+}
+
+// evictionReactor reject the evictions by PodDisruptionBudget for the blocked times, then delete the pod.
+func evictionReactor(client *fake.Clientset, blocked int, attempts map[string]int, mu *sync.Mutex) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1beta1.Eviction)
+		mu.Lock()
+		attempts[eviction.Name]++
+		n := attempts[eviction.Name]
+		mu.Unlock()
+		if n <= blocked {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+		return true, nil, client.Tracker().Delete(gvr, eviction.Namespace, eviction.Name)
+	}
+}
+
+func TestEvictNodePods(t *testing.T) {
+	defer func(interval time.Duration) { EvictRetryInterval = interval }(EvictRetryInterval)
+	EvictRetryInterval = 10 * time.Millisecond
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	client := fake.NewSimpleClientset(node, testPod("app", "ReplicaSet", false), testPod("ds", "DaemonSet", false), testPod("static", "", true))
+	attempts := map[string]int{}
+	client.PrependReactor("create", "pods", evictionReactor(client, 2, attempts, &sync.Mutex{}))
+
+	if err := EvictNodePods("node-1", client, 10*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if attempts["app"] != 3 {
+		t.Errorf("eviction attempts of app = %d, want 3 after 2 rejected by PodDisruptionBudget", attempts["app"])
+	}
+	if attempts["ds"] != 0 || attempts["static"] != 0 {
+		t.Errorf("DaemonSet and mirror pods should not be evicted, attempts %v", attempts)
+	}
+	if _, err := client.CoreV1().Pods("default").Get(context.TODO(), "app", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("pod app should be deleted, err %v", err)
+	}
+	if n, _ := client.CoreV1().Nodes().Get(context.TODO(), "node-1", metav1.GetOptions{}); !n.Spec.Unschedulable {
+		t.Errorf("node-1 should be cordoned")
+	}
+}
+
+func TestEvictNodePodsTimeout(t *testing.T) {
+	defer func(interval time.Duration) { EvictRetryInterval = interval }(EvictRetryInterval)
+	EvictRetryInterval = 10 * time.Millisecond
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}}
+	client := fake.NewSimpleClientset(node, testPod("app", "ReplicaSet", false))
+	attempts := map[string]int{}
+	client.PrependReactor("create", "pods", evictionReactor(client, 1<<30, attempts, &sync.Mutex{}))
+
+	if err := EvictNodePods("node-1", client, 100*time.Millisecond); err == nil {
+		t.Error("eviction always blocked by PodDisruptionBudget should fail after the timeout")
+	}
+	if attempts["app"] < 2 {
+		t.Errorf("eviction attempts of app = %d, want retried", attempts["app"])
+	}
+	if _, err := client.CoreV1().Pods("default").Get(context.TODO(), "app", metav1.GetOptions{}); err != nil {
+		t.Errorf("pod app should be kept, err %v", err)
+	}
+}
@@ -120,7 +120,7 @@ func GetNodeIPByLabel(k8sClient *kubernetes.Clientset, label string) ([]string,
 }
 
 // GetNodeByName is get node internalIp by nodeName
-func GetNodeByName(k8sClient *kubernetes.Clientset, nodeName string) (node *v1.Node, err error) {
+func GetNodeByName(k8sClient kubernetes.Interface, nodeName string) (node *v1.Node, err error) {
 	return k8sClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
 }
 
@@ -0,0 +1,136 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lb
+
+// KeepalivedConfig float the VIP on the masters by vrrp unicast, the multicast is dropped on most clouds.
+const KeepalivedConfig = `global_defs {
+    router_id sealos_{{ .Master }}
+}
+
+vrrp_instance sealos_vip {
+    state BACKUP
+    interface {{ .Interface }}
+    virtual_router_id {{ .RouterID }}
+    priority {{ .Priority }}
+    advert_int 1
+    unicast_src_ip {{ .Master }}
+    unicast_peer {
+{{- range .Peers }}
+        {{ . }}
+{{- end }}
+    }
+    virtual_ipaddress {
+        {{ .VIP }}/32 dev {{ .Interface }}
+    }
+}
+`
+
+// HAProxyConfig balance the apiservers of the masters, unhealthy apiservers are removed by the /healthz check.
+const HAProxyConfig = `global
+    log stdout format raw local0
+    maxconn 4000
+
+defaults
+    mode tcp
+    log global
+    option tcplog
+    retries 3
+    timeout connect 5s
+    timeout client 1h
+    timeout server 1h
+
+frontend kube-apiserver
+    bind *:{{ .Port }}
+    default_backend kube-apiserver
+
+backend kube-apiserver
+    option httpchk GET /healthz
+    http-check expect status 200
+    balance roundrobin
+    default-server inter 3s fall 3 rise 2
+{{- range $i, $master := .Masters }}
+    server master{{ $i }} {{ $master }}:{{ $.APIServerPort }} check check-ssl verify none
+{{- end }}
+`
+
+const KeepalivedPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: kube-sealyun-keepalived
+  namespace: kube-system
+  labels:
+    component: kube-sealyun-keepalived
+    tier: control-plane
+  annotations:
+    sealyun.com/config-checksum: "{{ .Checksum }}"
+spec:
+  hostNetwork: true
+  priorityClassName: system-cluster-critical
+  containers:
+  - name: kube-sealyun-keepalived
+    image: {{ .Image }}
+    imagePullPolicy: IfNotPresent
+    args:
+    - --copy-service
+    securityContext:
+      capabilities:
+        add:
+        - NET_ADMIN
+        - NET_BROADCAST
+        - NET_RAW
+    volumeMounts:
+    - name: config
+      mountPath: /container/service/keepalived/assets/keepalived.conf
+      readOnly: true
+  volumes:
+  - name: config
+    hostPath:
+      path: /etc/kubernetes/sealyun/keepalived.conf
+      type: File
+`
+
+const HAProxyPod = `apiVersion: v1
+kind: Pod
+metadata:
+  name: kube-sealyun-haproxy
+  namespace: kube-system
+  labels:
+    component: kube-sealyun-haproxy
+    tier: control-plane
+  annotations:
+    sealyun.com/config-checksum: "{{ .Checksum }}"
+spec:
+  hostNetwork: true
+  priorityClassName: system-cluster-critical
+  containers:
+  - name: kube-sealyun-haproxy
+    image: {{ .Image }}
+    imagePullPolicy: IfNotPresent
+    livenessProbe:
+      tcpSocket:
+        host: 127.0.0.1
+        port: {{ .Port }}
+      initialDelaySeconds: 10
+      periodSeconds: 10
+    volumeMounts:
+    - name: config
+      mountPath: /usr/local/etc/haproxy/haproxy.cfg
+      readOnly: true
+  volumes:
+  - name: config
+    hostPath:
+      path: /etc/kubernetes/sealyun/haproxy.cfg
+      type: File
+`
@@ -0,0 +1,118 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lb
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+	"text/template"
+
+	"github.com/fanux/sealos/pkg/logger"
+)
+
+const (
+	DefaultKeepalivedImage = "osixia/keepalived:2.0.20"
+	DefaultHAProxyImage    = "haproxy:2.1.4"
+	DefaultKubeVIPImage    = "ghcr.io/kube-vip/kube-vip:v0.3.8"
+	// DefaultHAProxyPort is the port of the VIP in keepalived mode, the apiserver port is taken by the apiserver on the masters.
+	DefaultHAProxyPort = 8443
+
+	// ManifestsDir is the static pod dir of the kubelet.
+	ManifestsDir = "/etc/kubernetes/manifests"
+	// ConfigDir is the dir of the keepalived and haproxy config on the masters.
+	ConfigDir = "/etc/kubernetes/sealyun"
+
+	KeepalivedPodName = "kube-sealyun-keepalived"
+	HAProxyPodName    = "kube-sealyun-haproxy"
+	KubeVIPPodName    = "kube-sealyun-kube-vip"
+)
+
+type MetaData struct {
+	VIP string
+	// Port is the port of the VIP, the haproxy listens on it in keepalived mode
+	Port          int
+	APIServerPort int
+	// Master is the master ip the manifests rendered for
+	Master  string
+	Masters []string
+	// Interface is the NIC of the master holding the VIP
+	Interface string
+	// Priority of the keepalived on the master, the master with the highest priority holds the VIP
+	Priority int
+	// RouterID is the virtual router id of the keepalived, the masters of a cluster must be the same
+	RouterID int
+	Image    string
+	// Checksum of the config files, the kubelet restarts the static pod when it changes
+	Checksum string
+}
+
+// Peers return the masters except the rendered one, they are the unicast peers of the keepalived.
+func (m MetaData) Peers() []string {
+	var peers []string
+	for _, master := range m.Masters {
+		if master != m.Master {
+			peers = append(peers, master)
+		}
+	}
+	return peers
+}
+
+// KeepalivedManifests return the keepalived and haproxy static pods and configs of the master, keyed by the path.
+func KeepalivedManifests(md MetaData, keepalivedImage, haproxyImage string) map[string]string {
+	keepalivedConf := render(md, KeepalivedConfig)
+	haproxyConf := render(md, HAProxyConfig)
+	files := map[string]string{
+		filepath.Join(ConfigDir, "keepalived.conf"): keepalivedConf,
+		filepath.Join(ConfigDir, "haproxy.cfg"):     haproxyConf,
+	}
+	md.Image, md.Checksum = keepalivedImage, checksum(keepalivedConf)
+	files[filepath.Join(ManifestsDir, KeepalivedPodName+".yaml")] = render(md, KeepalivedPod)
+	md.Image, md.Checksum = haproxyImage, checksum(haproxyConf)
+	files[filepath.Join(ManifestsDir, HAProxyPodName+".yaml")] = render(md, HAProxyPod)
+	return files
+}
+
+// KubeVIPManifests return the kube-vip static pod of the master, keyed by the path.
+func KubeVIPManifests(md MetaData, image string) map[string]string {
+	md.Image = image
+	return map[string]string{
+		filepath.Join(ManifestsDir, KubeVIPPodName+".yaml"): render(md, KubeVIPPod),
+	}
+}
+
+// RouterID return the virtual router id of the VIP, it is the last byte of the VIP to avoid the conflict with other keepalived in the subnet.
+func RouterID(vip string) int {
+	var a, b, c, d int
+	if _, err := fmt.Sscanf(vip, "%d.%d.%d.%d", &a, &b, &c, &d); err != nil || d == 0 {
+		return 51
+	}
+	return d
+}
+
+func checksum(s string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(s)))[:16]
+}
+
+func render(data MetaData, temp string) string {
+	var b bytes.Buffer
+	t := template.Must(template.New("lb").Parse(temp))
+	if err := t.Execute(&b, &data); err != nil {
+		logger.Error("render load balancer manifests failed: %s", err)
+		return ""
+	}
+	return b.String()
+}
@@ -0,0 +1,90 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lb
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+func TestKeepalivedManifests(t *testing.T) {
+	md := MetaData{
+		VIP:           "192.168.0.100",
+		Port:          DefaultHAProxyPort,
+		APIServerPort: 6443,
+		Master:        "192.168.0.3",
+		Masters:       []string{"192.168.0.2", "192.168.0.3", "192.168.0.4"},
+		Interface:     "eth0",
+		Priority:      99,
+		RouterID:      RouterID("192.168.0.100"),
+	}
+	files := KeepalivedManifests(md, DefaultKeepalivedImage, DefaultHAProxyImage)
+	if len(files) != 4 {
+		t.Fatalf("KeepalivedManifests() = %d files, want 4", len(files))
+	}
+	conf := files[filepath.Join(ConfigDir, "keepalived.conf")]
+	for _, want := range []string{"interface eth0", "virtual_router_id 100", "priority 99", "unicast_src_ip 192.168.0.3", "        192.168.0.2\n        192.168.0.4\n", "192.168.0.100/32 dev eth0"} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("keepalived.conf does not contain %q:\n%s", want, conf)
+		}
+	}
+	haproxy := files[filepath.Join(ConfigDir, "haproxy.cfg")]
+	for _, want := range []string{"bind *:8443", "server master0 192.168.0.2:6443 check", "server master2 192.168.0.4:6443 check"} {
+		if !strings.Contains(haproxy, want) {
+			t.Errorf("haproxy.cfg does not contain %q:\n%s", want, haproxy)
+		}
+	}
+	for _, name := range []string{KeepalivedPodName, HAProxyPodName} {
+		pod := files[filepath.Join(ManifestsDir, name+".yaml")]
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(pod), &obj); err != nil || obj["kind"] != "Pod" {
+			t.Errorf("%s is not a valid pod: %v\n%s", name, err, pod)
+		}
+	}
+
+	// the static pod changes with the config, so the kubelet restarts it
+	md.Masters = md.Masters[:2]
+	changed := KeepalivedManifests(md, DefaultKeepalivedImage, DefaultHAProxyImage)
+	haproxyPod := filepath.Join(ManifestsDir, HAProxyPodName+".yaml")
+	if changed[haproxyPod] == files[haproxyPod] {
+		t.Errorf("haproxy pod is not changed with the config")
+	}
+}
+
+func TestKubeVIPManifests(t *testing.T) {
+	md := MetaData{VIP: "192.168.0.100", Port: 6443, Interface: "ens192"}
+	files := KubeVIPManifests(md, DefaultKubeVIPImage)
+	pod := files[filepath.Join(ManifestsDir, KubeVIPPodName+".yaml")]
+	var obj map[string]interface{}
+	if err := yaml.Unmarshal([]byte(pod), &obj); err != nil || obj["kind"] != "Pod" {
+		t.Fatalf("kube-vip is not a valid pod: %v\n%s", err, pod)
+	}
+	for _, want := range []string{DefaultKubeVIPImage, `value: "192.168.0.100"`, `value: "6443"`, `value: "ens192"`} {
+		if !strings.Contains(pod, want) {
+			t.Errorf("kube-vip pod does not contain %q", want)
+		}
+	}
+}
+
+func TestRouterID(t *testing.T) {
+	for vip, want := range map[string]int{"192.168.0.100": 100, "10.0.0.0": 51, "lb.example.com": 51} {
+		if got := RouterID(vip); got != want {
+			t.Errorf("RouterID(%s) = %d, want %d", vip, got, want)
+		}
+	}
+}
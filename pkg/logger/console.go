@@ -49,6 +49,7 @@ type consoleLogger struct {
 	stdErrMux sync.Mutex
 	Level     string `json:"level"`
 	Colorful  bool   `json:"color"`
+	Stderr    bool   `json:"stderr"` // write all levels to stderr, stdout is left to the output of the command
 	LogLevel  logLevel
 }
 
@@ -81,6 +82,10 @@ func (c *consoleLogger) LogWrite(when time.Time, msgText interface{}, level logL
 	if c.Colorful {
 		msg = colors[level](msg)
 	}
+	if c.Stderr {
+		c.printlnToStdErr(msg)
+		return nil
+	}
 	switch level {
 	case LevelEmergency, LevelAlert, LevelCritical, LevelError:
 		c.printlnToStdErr(msg)
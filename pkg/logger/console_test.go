@@ -15,6 +15,8 @@
 package logger
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 )
 
@@ -46,3 +48,26 @@ func TestNoColorConsole(t *testing.T) {
 	log.SetLogger("console", `{"color":false}`)
 	testConsoleCalls(log)
 }
+
+func TestConsoleStderr(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	log := NewLogger()
+	log.SetLogger("console", `{"stderr":true}`)
+	// the console adapter is shared by the loggers.
+	defer log.SetLogger("console", `{"stderr":false}`)
+	testConsoleCalls(log)
+	os.Stdout = stdout
+	_ = w.Close()
+	out, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 0 {
+		t.Errorf("console with stderr writes %q to stdout", out)
+	}
+}
@@ -385,6 +385,17 @@ func SetLogPath(show bool) {
 	defaultLogger.SetLogPath(show)
 }
 
+// SetConsoleStderr write the console logs of all levels to stderr, so the json or yaml on stdout is parsable.
+func SetConsoleStderr() {
+	defaultLogger.lock.Lock()
+	defer defaultLogger.lock.Unlock()
+	for _, l := range defaultLogger.outputs {
+		if c, ok := l.Logger.(*consoleLogger); ok {
+			c.Stderr = true
+		}
+	}
+}
+
 // param 可以是log配置文件名，也可以是log配置内容,默认DEBUG输出到控制台
 func SetLogger(param ...string) {
 	if len(param) == 0 {
@@ -0,0 +1,54 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+// the load balancer modes of the VIP which the nodes reach the apiservers of the masters through.
+const (
+	// LBModeIPVS is the local ipvs VIP of every node maintained by the lvscare static pod.
+	LBModeIPVS = "ipvs"
+	// LBModeExternal is an external load balancer like F5 or a cloud SLB, the VIP is the address of it.
+	LBModeExternal = "external"
+	// LBModeKeepalived is the VIP floating on the masters by keepalived, haproxy on the masters balances the apiservers.
+	LBModeKeepalived = "keepalived"
+	// LBModeKubeVIP is the VIP floating on the masters by the kube-vip static pod.
+	LBModeKubeVIP = "kube-vip"
+)
+
+// LBModes is the supported load balancer modes.
+var LBModes = []string{LBModeIPVS, LBModeExternal, LBModeKeepalived, LBModeKubeVIP}
+
+// LoadBalancerConfig is the load balancer of the control plane endpoint.
+type LoadBalancerConfig struct {
+	// Mode is ipvs, external, keepalived or kube-vip, ipvs if empty.
+	Mode string `json:"mode,omitempty"`
+	// Port is the port of the VIP, it is the apiserver port except the haproxy port in keepalived mode.
+	Port int `json:"port,omitempty"`
+	// Interface is the NIC of the masters holding the VIP, default the NIC of the master ip.
+	Interface string `json:"interface,omitempty"`
+	// images of the static pods on the masters
+	KeepalivedImage string `json:"keepalivedimage,omitempty"`
+	HAProxyImage    string `json:"haproxyimage,omitempty"`
+	KubeVIPImage    string `json:"kubevipimage,omitempty"`
+}
+
+// IsIPVS return true if the nodes reach the VIP by the local ipvs rules, it is the default mode.
+func (l LoadBalancerConfig) IsIPVS() bool {
+	return l.Mode == "" || l.Mode == LBModeIPVS
+}
+
+// OnMasters return true if the VIP floats on the masters by the static pods.
+func (l LoadBalancerConfig) OnMasters() bool {
+	return l.Mode == LBModeKeepalived || l.Mode == LBModeKubeVIP
+}
@@ -0,0 +1,96 @@
+// Copyright © 2021 sealos.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RegistryConfig is the registry config rendered to the container runtime of every host.
+// The registries are host[:port], docker hub is docker.io.
+type RegistryConfig struct {
+	// Mirrors is the mirror endpoints of the upstream registry, ex. docker.io: [https://mirror.example.com]
+	Mirrors map[string][]string `json:"mirrors,omitempty"`
+	// Insecure is the registries skip tls verify, or fall back to plain http.
+	Insecure []string `json:"insecure,omitempty"`
+	// CAFiles is the local ca file of the registry, it is copied to every host.
+	CAFiles map[string]string `json:"cafiles,omitempty"`
+	// Auths is the pull credential of the registry.
+	Auths map[string]RegistryAuth `json:"auths,omitempty"`
+}
+
+type RegistryAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// IsEmpty return true if nothing is configured.
+func (r RegistryConfig) IsEmpty() bool {
+	return len(r.Mirrors) == 0 && len(r.Insecure) == 0 && len(r.CAFiles) == 0 && len(r.Auths) == 0
+}
+
+// Registries return all the configured registries sorted.
+func (r RegistryConfig) Registries() []string {
+	set := make(map[string]bool)
+	for registry := range r.Mirrors {
+		set[registry] = true
+	}
+	for _, registry := range r.Insecure {
+		set[registry] = true
+	}
+	for registry := range r.CAFiles {
+		set[registry] = true
+	}
+	for registry := range r.Auths {
+		set[registry] = true
+	}
+	var registries []string
+	for registry := range set {
+		registries = append(registries, registry)
+	}
+	sort.Strings(registries)
+	return registries
+}
+
+// ParseRegistryFlags parse --registry-mirror and --registry-auth to Registry.
+func ParseRegistryFlags() error {
+	for _, m := range RegistryMirrors {
+		kv := strings.SplitN(m, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return fmt.Errorf("registry mirror %s is invalid, must be like docker.io=https://mirror.example.com", m)
+		}
+		if Registry.Mirrors == nil {
+			Registry.Mirrors = make(map[string][]string)
+		}
+		Registry.Mirrors[kv[0]] = append(Registry.Mirrors[kv[0]], kv[1])
+	}
+	for _, a := range RegistryAuths {
+		kv := strings.SplitN(a, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("registry auth %s is invalid, must be like harbor.example.com=user:password", a)
+		}
+		up := strings.SplitN(kv[1], ":", 2)
+		if len(up) != 2 || up[0] == "" {
+			return fmt.Errorf("registry auth %s is invalid, must be like harbor.example.com=user:password", a)
+		}
+		if Registry.Auths == nil {
+			Registry.Auths = make(map[string]RegistryAuth)
+		}
+		Registry.Auths[kv[0]] = RegistryAuth{Username: up[0], Password: up[1]}
+	}
+	return nil
+}
@@ -18,7 +18,10 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/fanux/sealos/pkg/ipvs"
 	"github.com/fanux/sealos/pkg/logger"
 
 	"sigs.k8s.io/yaml"
@@ -29,6 +32,7 @@ import (
 const (
 	DefaultConfigFile      = "/config.yaml"
 	DefaultAPIServerDomain = "apiserver.cluster.local"
+	DefaultAPIServerPort   = 6443
 )
 
 var (
@@ -62,12 +66,48 @@ type SealConfig struct {
 	Repo            string `json:"repo"`
 	PodCIDR         string `json:"podcidr"`
 	SvcCIDR         string `json:"svccidr"`
+	//apiserver port, 6443 if empty
+	APIServerPort int `json:"apiserverport,omitempty"`
+	//cni plugin, calico|flannel|cilium
+	Network string `json:"network,omitempty"`
+	//cni version and options, used to render the cni manifests again by sealos cni upgrade
+	CniVersion     string            `json:"cniversion,omitempty"`
+	WithoutCNI     bool              `json:"withoutcni,omitempty"`
+	Interface      string            `json:"interface,omitempty"`
+	MTU            string            `json:"mtu,omitempty"`
+	BGP            bool              `json:"bgp,omitempty"`
+	CalicoEncap    string            `json:"calicoencapsulation,omitempty"`
+	CalicoASNumber string            `json:"calicoasnumber,omitempty"`
+	CalicoBGPPeers map[string]string `json:"calicobgppeers,omitempty"`
+	FlannelBackend string            `json:"flannelbackend,omitempty"`
+	CiliumTunnel   string            `json:"ciliumtunnel,omitempty"`
+	//custom cni template file or dir and its user variables file
+	CNIManifest string `json:"cnimanifest,omitempty"`
+	CNIValues   string `json:"cnivalues,omitempty"`
 	//certs location
 	CertPath     string `json:"certpath"`
 	CertEtcdPath string `json:"certetcdpath"`
+	//certs validity and private key algorithm, ex. 87600h 8760h ecdsa
+	CAValidity   string `json:"cavalidity,omitempty"`
+	CertValidity string `json:"certvalidity,omitempty"`
+	KeyAlgorithm string `json:"keyalgorithm,omitempty"`
+	//root or intermediate ca to sign the cluster CAs
+	RootCACert string `json:"rootcacert,omitempty"`
+	RootCAKey  string `json:"rootcakey,omitempty"`
+	//container runtime, docker|containerd|cri-o
+	CRI string `json:"cri,omitempty"`
+	//registry mirrors, insecure registries, CAs and auths of the container runtime
+	Registry RegistryConfig `json:"registry,omitempty"`
+	//local registry address and image, ex. 192.168.0.2:5000, empty if disabled
+	LocalRegistry      string `json:"localregistry,omitempty"`
+	LocalRegistryImage string `json:"localregistryimage,omitempty"`
+	//load balancer of the control plane endpoint, ipvs|external|keepalived|kube-vip
+	LoadBalancer LoadBalancerConfig `json:"loadbalancer,omitempty"`
 	//lvscare images
 	LvscareName string `json:"lvscarename"`
 	LvscareTag  string `json:"lvscaretag"`
+	//lvscare health check, resources and image pull policy
+	Lvscare ipvs.LvscareOptions `json:"lvscare,omitempty"`
 	AliOss      `json:"alioss"`
 }
 type AliOss struct {
@@ -92,20 +132,45 @@ func (c *SealConfig) Dump(path string) {
 	c.PrivateKey = SSHConfig.PkFile
 	c.PkPassword = SSHConfig.PkPassword
 	c.APIServerDomain = APIServer
+	c.APIServerPort = APIServerPort
 	c.VIP = VIP
 	c.PkgURL = PkgURL
 	c.Version = Version
 	c.Repo = Repo
 	c.SvcCIDR = SvcCIDR
 	c.PodCIDR = PodCIDR
+	c.Network = Network
+	c.CniVersion = CniVersion
+	c.WithoutCNI = WithoutCNI
+	c.Interface = Interface
+	c.MTU = MTU
+	c.BGP = BGP
+	c.CalicoEncap = CalicoEncap
+	c.CalicoASNumber = CalicoASNumber
+	c.CalicoBGPPeers = CalicoBGPPeers
+	c.FlannelBackend = FlannelBackend
+	c.CiliumTunnel = CiliumTunnel
+	c.CNIManifest = absPath(CNIManifest)
+	c.CNIValues = absPath(CNIValues)
 
 	c.DNSDomain = DNSDomain
 	c.APIServerCertSANs = APIServerCertSANs
 	c.CertPath = CertPath
 	c.CertEtcdPath = CertEtcdPath
+	c.CAValidity = durationString(CAValidity)
+	c.CertValidity = durationString(CertValidity)
+	c.KeyAlgorithm = KeyAlgorithm
+	c.RootCACert = RootCACert
+	c.RootCAKey = RootCAKey
+	c.CRI = CRI
+	c.Registry = Registry
+	c.LocalRegistry = LocalRegistry
+	c.LocalRegistryImage = LocalRegistryImage
+	c.LoadBalancer = LoadBalancer
 	//lvscare
 	c.LvscareName = LvscareImage.Image
 	c.LvscareTag = LvscareImage.Tag
+	c.Lvscare = Lvscare
 	// oss
 	c.AliOss.AccessKeyID = AccessKeyID
 	c.AliOss.AccessKeySecrets = AccessKeySecrets
@@ -166,19 +231,60 @@ func (c *SealConfig) Load(path string) (err error) {
 	SSHConfig.PkFile = c.PrivateKey
 	SSHConfig.PkPassword = c.PkPassword
 	APIServer = c.APIServerDomain
+	if c.APIServerPort == 0 {
+		c.APIServerPort = DefaultAPIServerPort
+	}
+	APIServerPort = c.APIServerPort
 	VIP = c.VIP
 	PkgURL = c.PkgURL
 	Version = c.Version
 	Repo = c.Repo
 	PodCIDR = c.PodCIDR
 	SvcCIDR = c.SvcCIDR
+	Network = c.Network
+	CniVersion = c.CniVersion
+	WithoutCNI = c.WithoutCNI
+	Interface = c.Interface
+	MTU = c.MTU
+	BGP = c.BGP
+	CalicoEncap = c.CalicoEncap
+	CalicoASNumber = c.CalicoASNumber
+	CalicoBGPPeers = c.CalicoBGPPeers
+	FlannelBackend = c.FlannelBackend
+	CiliumTunnel = c.CiliumTunnel
+	//the cni template of the command line overrides the config file
+	if CNIManifest == "" {
+		CNIManifest = c.CNIManifest
+	}
+	if CNIValues == "" {
+		CNIValues = c.CNIValues
+	}
 	DNSDomain = c.DNSDomain
 	APIServerCertSANs = c.APIServerCertSANs
 	CertPath = c.CertPath
 	CertEtcdPath = c.CertEtcdPath
+	if CAValidity, err = parseDuration(c.CAValidity); err != nil {
+		return fmt.Errorf("parse cavalidity failed: %w", err)
+	}
+	if CertValidity, err = parseDuration(c.CertValidity); err != nil {
+		return fmt.Errorf("parse certvalidity failed: %w", err)
+	}
+	KeyAlgorithm = c.KeyAlgorithm
+	RootCACert = c.RootCACert
+	RootCAKey = c.RootCAKey
+	CRI = c.CRI
+	Registry = c.Registry
+	LocalRegistry = c.LocalRegistry
+	LocalRegistryImage = c.LocalRegistryImage
+	//the haproxy port of keepalived mode is defaulted by the check of init
+	if c.LoadBalancer.Port == 0 && c.LoadBalancer.Mode != LBModeKeepalived {
+		c.LoadBalancer.Port = c.APIServerPort
+	}
+	LoadBalancer = c.LoadBalancer
 	//lvscare
 	LvscareImage.Image = c.LvscareName
 	LvscareImage.Tag = c.LvscareTag
+	Lvscare = c.Lvscare
 
 	// 优先使用使用命令行， 再使用配置文件
 	if AccessKeyID == "" || AccessKeySecrets == "" ||
@@ -206,6 +312,31 @@ func Load(path string, content interface{}) error {
 	return nil
 }
 
+// absPath return the absolute path, the config file is loaded from other working dirs.
+func absPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
 func (c *SealConfig) ShowDefaultConfig() {
 	home, _ := os.UserHomeDir()
 	c.Masters = []string{"192.168.0.2", "192.168.0.2", "192.168.0.2"}
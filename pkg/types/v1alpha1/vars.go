@@ -16,6 +16,7 @@ package v1alpha1
 
 import (
 	"strconv"
+	"time"
 
 	"github.com/fanux/sealos/pkg/ipvs"
 	"github.com/fanux/sealos/pkg/utils/ssh"
@@ -31,15 +32,36 @@ var (
 	APIServerCertSANs []string
 	SSHConfig         ssh.SSH
 	APIServer         string
+	APIServerPort     = DefaultAPIServerPort
 	CertPath          = DefaultConfigPath + "/pki"
 	CertEtcdPath      = DefaultConfigPath + "/pki/etcd"
 	EtcdCacart        = DefaultConfigPath + "/pki/etcd/ca.crt"
 	EtcdCert          = DefaultConfigPath + "/pki/etcd/healthcheck-client.crt"
 	EtcdKey           = DefaultConfigPath + "/pki/etcd/healthcheck-client.key"
 
+	CAValidity   time.Duration // validity of the CAs, default 100 years
+	CertValidity time.Duration // validity of the leaf certs, default 100 years
+	KeyAlgorithm string        // private key algorithm of the certs, rsa or ecdsa
+	RootCACert   string        // root or intermediate ca cert file to sign the cluster CAs
+	RootCAKey    string        // root or intermediate ca key file
+	CADir        string        // dir of the pre-created kubernetes, front-proxy and etcd CAs
+
+	CRI          string // container runtime, docker|containerd|cri-o, default by the kubernetes version
 	CriSocket    string
 	CgroupDriver string
-	KubeadmAPI   string
+
+	Registry        RegistryConfig // registry config of the container runtime
+	RegistryMirrors []string       // registry mirrors from --registry-mirror, ex. docker.io=https://mirror.example.com
+	RegistryAuths   []string       // registry auths from --registry-auth, ex. harbor.example.com=admin:password
+
+	LocalRegistry          string // address of the local registry, ex. 192.168.0.2:5000, empty if disabled
+	LocalRegistryImage     string // image of the local registry
+	LocalRegistryImageFile string // tarball of the local registry image, the image is taken from the package if empty
+	LocalRegistryEnabled   bool   // start the local registry during init
+	LocalRegistryHost      string // host of the local registry, default master0
+	KubeadmAPI             string
+
+	LoadBalancer LoadBalancerConfig // load balancer of the control plane endpoint
 
 	VIP     string
 	PkgURL  string
@@ -55,10 +77,21 @@ var (
 
 	Ipvs         care.LvsCare
 	LvscareImage ipvs.LvscareImage
+	Lvscare      ipvs.LvscareOptions // health check, resources and image pull policy of the lvscare static pod
 	KubeadmFile  string
 
 	WithoutCNI bool // if true don't install cni plugin
 
+	Network        string            // cni plugin, calico|flannel|cilium
+	CniVersion     string            // version of the installed cni plugin
+	FlannelBackend string            // backend of the flannel, vxlan|host-gw
+	CalicoEncap    string            // encapsulation of the calico, ipip|vxlan|ipip-crosssubnet|vxlan-crosssubnet|none
+	CalicoASNumber string            // AS number of the calico nodes
+	CalicoBGPPeers map[string]string // bgp peers of the calico, ip to AS number
+	CiliumTunnel   string            // tunnel of the cilium, vxlan|geneve|disabled
+	CNIManifest    string            // custom template file or dir of the cni manifests
+	CNIValues      string            // user variables file of the custom cni template
+
 	Interface string //network interface name, like "eth.*|en.*"
 
 	BGP bool // the ipip mode of the calico
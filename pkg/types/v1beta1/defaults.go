@@ -17,6 +17,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 
@@ -28,6 +29,9 @@ const (
 	SourceCidrIP  = "0.0.0.0/0"
 	SSHPort       = "22/22"
 	APIServerPort = "6443/6443"
+	// DefaultAPIServerPort is the apiserver port if the apiserver port of Default is 0,
+	// APIServerPort is its port range.
+	DefaultAPIServerPort = 6443
 )
 
 var (
@@ -36,21 +40,25 @@ var (
 		CidrIP:    SourceCidrIP,
 		PortRange: SSHPort,
 	}
-	apiserverExportPort = ExportPort{
+)
+
+func apiserverExportPort(port int) ExportPort {
+	return ExportPort{
 		Protocol:  ProtocolTCP,
 		CidrIP:    SourceCidrIP,
-		PortRange: APIServerPort,
+		PortRange: fmt.Sprintf("%d/%d", port, port),
 	}
-)
+}
 
-func Default(infra *Infra, fn func(infra *Infra) error) error {
-	defaultCluster(infra)
+// Default set the defaults of the infra, apiServerPort is the apiserverPort of the sealos config exported by the security group.
+func Default(infra *Infra, apiServerPort int, fn func(infra *Infra) error) error {
+	defaultCluster(infra, apiServerPort)
 	defaultHosts(infra)
 	defaultToStatus(infra)
 	return fn(infra)
 }
 
-func defaultCluster(infra *Infra) {
+func defaultCluster(infra *Infra, apiServerPort int) {
 	infra.Spec.Cluster.AccessChannels.SSH.Port = 22
 	if infra.Spec.Cluster.AccessChannels.SSH.Passwd == "" {
 		infra.Spec.Cluster.AccessChannels.SSH.Passwd = createPassword()
@@ -68,10 +76,14 @@ func defaultCluster(infra *Infra) {
 	if infra.Spec.Cluster.Metadata.Network.Bandwidth == "" {
 		infra.Spec.Cluster.Metadata.Network.Bandwidth = "100"
 	}
+	if apiServerPort == 0 {
+		apiServerPort = DefaultAPIServerPort
+	}
+	apiserverPort := apiserverExportPort(apiServerPort)
 	if len(infra.Spec.Cluster.Metadata.Network.ExportPorts) == 0 {
 		infra.Spec.Cluster.Metadata.Network.ExportPorts = []ExportPort{
 			sshExportPort,
-			apiserverExportPort,
+			apiserverPort,
 		}
 	} else {
 		ports := sets.NewString()
@@ -81,8 +93,8 @@ func defaultCluster(infra *Infra) {
 		if !ports.Has(SSHPort) {
 			infra.Spec.Cluster.Metadata.Network.ExportPorts = append(infra.Spec.Cluster.Metadata.Network.ExportPorts, sshExportPort)
 		}
-		if !ports.Has(APIServerPort) {
-			infra.Spec.Cluster.Metadata.Network.ExportPorts = append(infra.Spec.Cluster.Metadata.Network.ExportPorts, apiserverExportPort)
+		if !ports.Has(apiserverPort.PortRange) {
+			infra.Spec.Cluster.Metadata.Network.ExportPorts = append(infra.Spec.Cluster.Metadata.Network.ExportPorts, apiserverPort)
 		}
 	}
 	if infra.Spec.Cluster.Metadata.Network.PrivateCidrIP == "" {
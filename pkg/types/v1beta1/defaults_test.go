@@ -0,0 +1,43 @@
+/*
+Copyright 2021 cuisongliu@qq.com.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import "testing"
+
+func TestDefaultClusterAPIServerPort(t *testing.T) {
+	tests := []struct {
+		port int
+		want string
+	}{
+		{0, APIServerPort},
+		{7443, "7443/7443"},
+	}
+	for _, tt := range tests {
+		infra := &Infra{}
+		defaultCluster(infra, tt.port)
+		ports := infra.Spec.Cluster.Metadata.Network.ExportPorts
+		if len(ports) != 2 || ports[1].PortRange != tt.want {
+			t.Errorf("defaultCluster(%d) export ports = %v, want %s", tt.port, ports, tt.want)
+		}
+	}
+	infra := &Infra{}
+	infra.Spec.Cluster.Metadata.Network.ExportPorts = []ExportPort{sshExportPort}
+	defaultCluster(infra, 7443)
+	if ports := infra.Spec.Cluster.Metadata.Network.ExportPorts; len(ports) != 2 || ports[1].PortRange != "7443/7443" {
+		t.Errorf("defaultCluster(7443) should append the apiserver port, export ports = %v", ports)
+	}
+}
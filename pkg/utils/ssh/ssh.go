@@ -16,6 +16,8 @@ package ssh
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"io"
 	"strings"
 
@@ -105,6 +107,23 @@ func (ss *SSH) CmdAsync(host string, cmd string) error {
 	return session.Wait()
 }
 
+// CmdOutput is in host exec cmd and return the stdout, the error contains the stderr if the command fails.
+func (ss *SSH) CmdOutput(host, cmd string) ([]byte, error) {
+	logger.Debug("[ssh][%s] %s", host, cmd)
+	session, err := ss.Connect(host)
+	if err != nil {
+		return nil, err
+	}
+	defer session.Close()
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	out, err := session.Output(cmd)
+	if err != nil {
+		return out, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
 //CmdToString is in host exec cmd and replace to spilt str
 func (ss *SSH) CmdToString(host, cmd, spilt string) string {
 	if data := ss.Cmd(host, cmd); data != nil {
@@ -45,30 +45,38 @@ func GetMajorMinorInt(version string) (major, minor int) {
 	return 0, 0
 }
 
+// CanUpgradeByNewVersion check the new version is newer than the old version, and at most one minor version newer,
+// kubeadm only upgrades one minor version at a time.
 func CanUpgradeByNewVersion(new, old string) error {
+	if err := CanUpgradeByHops(new, old); err != nil {
+		return err
+	}
+	newMajor, _ := GetMajorMinorInt(new)
+	major, _ := GetMajorMinorInt(old)
+	// case three : new major version > old major version +1;
+	// 1.18.2    1.16.10
+	if newMajor > major+1 {
+		return fmt.Errorf("kubernetes is upgraded one minor version at a time, current version %s can not be upgraded to %s directly, "+
+			"upgrade to v%d.%d.x first, or pass the packages of every minor version by --pkg-dir or comma separated --pkg-url to upgrade in hops", old, new, (major+1)/100, (major+1)%100)
+	}
+	return nil
+}
+
+// CanUpgradeByHops check the new version is newer than the old version, the minor versions between them are upgraded in hops.
+func CanUpgradeByHops(new, old string) error {
 	newMajor, newMinor := GetMajorMinorInt(new)
 	major, minor := GetMajorMinorInt(old)
 
-	// sealos change cri to containerd when version more than 1.20.0
-	if newMajor == 120 && major == 119 {
-		return fmt.Errorf("sealos change cri to containerd when Version greater than 1.20! New version: %s, current version: %s", new, old)
-	}
 	// case one:  new major version <  old major version
 	// 1.18.8     1.19.1
 	if newMajor < major {
-		return fmt.Errorf("kubernetes new version is lower than current version! New version: %s, current version: %s", new, old)
+		return fmt.Errorf("kubernetes new version is lower than current version, downgrade is not supported! New version: %s, current version: %s", new, old)
 	}
 	// case two:  new major version = old major version ; new minor version <= old minor version
 	// 1.18.0   1.18.1
 	if newMajor == major && newMinor <= minor {
 		return fmt.Errorf("kubernetes new version is lower/equal than current version! New version: %s, current version: %s", new, old)
 	}
-
-	// case three : new major version > old major version +1;
-	// 1.18.2    1.16.10
-	if newMajor > major+1 {
-		return fmt.Errorf("kubernetes new version is bigger than current version, more than one major version is not allowed! New version: %s, current version: %s", new, old)
-	}
 	return nil
 }
 